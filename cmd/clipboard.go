@@ -0,0 +1,67 @@
+/*
+Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
+*/
+package cmd
+
+import (
+	"AirBridge/internal/clipboard"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	clearAfter time.Duration
+	clearHash  string
+)
+
+// clipboardClearCmd clears the clipboard after the TUI has exited.
+// It is started in the background by handOffClipboardClear.
+var clipboardClearCmd = &cobra.Command{
+	Use:    "clipboard-clear",
+	Short:  "Clear copied secrets from the clipboard after a delay.",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		time.Sleep(clearAfter)
+		if _, err := clipboard.ClearIfMatches(clipboard.Default.Backend, clearHash); err != nil {
+			fmt.Fprintf(os.Stderr, "Error clearing clipboard: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// handOffClipboardClear starts a background process that clears the clipboard
+// when the TUI exits before a copied secret has expired.
+func handOffClipboardClear() {
+	remaining, ok := clipboard.Default.Pending()
+	if !ok {
+		return
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	c := exec.Command(executable, "clipboard-clear",
+		"--clipboard", clipboard.Default.Backend.Name(),
+		"--after", remaining.String(),
+		"--hash", clipboard.Default.Hash(),
+	)
+	// The OSC 52 backend writes to the terminal through stderr
+	c.Stderr = os.Stderr
+	if err := c.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not schedule clipboard clearing: %v\n", err)
+		return
+	}
+	_ = c.Process.Release()
+}
+
+func init() {
+	rootCmd.AddCommand(clipboardClearCmd)
+	clipboardClearCmd.Flags().DurationVar(&clearAfter, "after", 0, "Delay before clearing")
+	clipboardClearCmd.Flags().StringVar(&clearHash, "hash", "", "SHA256 of the text to clear")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
+*/
+package cmd
+
+import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/config"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change settings.",
+	Long: `Reads and changes the settings in the config file
+($XDG_CONFIG_HOME/airbridge/config.toml).
+
+Settings: ` + strings.Join(config.Names, ", ") + `
+
+Each setting can also be set with an AIRBRIDGE_* environment variable,
+e.g. AIRBRIDGE_OUTPUT_DIR for output_dir. Flags take precedence over
+environment variables, which take precedence over the selected profile
+(--profile) and the config file.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <setting>",
+	Short: "Print the value of a setting in effect.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := cfg.Get(args[0])
+		if err != nil {
+			return cli.Errorf(cli.ClassUsage, "%w", err)
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print all settings in effect.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, name := range config.Names {
+			value, _ := cfg.Get(name)
+			fmt.Printf("%s = %s\n", name, value)
+		}
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <setting> <value>",
+	Short: "Write a setting to the config file.",
+	Long: `Writes a setting to the config file, or to the profile given with --profile.
+Comments in the config file are not kept.`,
+	Args: cobra.ExactArgs(2),
+	// The config file is not loaded first, so a broken file can still be fixed
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.Path()
+		if err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+		if err := config.SetFile(path, profileName, args[0], args[1]); err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd, configListCmd, configSetCmd)
+}
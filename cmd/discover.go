@@ -0,0 +1,47 @@
+/*
+Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
+*/
+package cmd
+
+import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/config"
+	"AirBridge/internal/discovery"
+	"AirBridge/internal/keystore"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lookupRecipient fetches the public key published for target (see send --to).
+// Keys seen for the first time are confirmed on the terminal, or must match
+// expected when there is no terminal to ask on.
+func lookupRecipient(target, expected string, refresh bool) (discovery.Result, error) {
+	configDir, err := config.Dir()
+	if err != nil {
+		return discovery.Result{}, cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		return discovery.Result{}, cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+
+	d := discovery.New(discovery.NewClient(), filepath.Join(cacheDir, "keys"), filepath.Join(configDir, keystore.PinsFile))
+	d.Refresh = refresh
+	if stdinIsTerminal() {
+		d.Confirm = func(target, fingerprint string) (bool, error) {
+			p := cli.NewPrompter(os.Stdin, os.Stderr)
+			p.Say("First time sending to %s. Its key has the fingerprint", target)
+			p.Say("  %s", fingerprint)
+			p.Say("Check it with the recipient over another channel before you trust it.")
+			return p.Confirm("Trust this key?")
+		}
+	}
+
+	result, err := d.Lookup(context.Background(), target, expected)
+	if err != nil {
+		return discovery.Result{}, fmt.Errorf("error looking up key for %s: %w", target, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,433 @@
+/*
+Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
+*/
+package cmd
+
+import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/config"
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/identity"
+	"AirBridge/internal/keystore"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var certifyDays int
+
+// identityCmd represents the identity command
+var identityCmd = &cobra.Command{
+	Use:   "identity",
+	Short: "Manage identities and their device keys.",
+	Long: `An identity is a long-term Ed25519 key that certifies the short-lived
+encryption keys of your devices (laptop, workstation, bastion host, ...).
+People import your identity bundle once and "send -k <name>" then encrypts
+for every device key that is currently valid, so you can decrypt on any of
+them. Device keys are checked against the identity's signature before they
+are used, and the identity key itself is pinned like a contact's key.
+
+  airbridge identity create me
+  airbridge keygen --name laptop
+  airbridge identity certify me laptop laptop --days 30
+  airbridge identity export me -o me.bundle
+
+Bundles are kept in $XDG_CONFIG_HOME/airbridge/identities.`,
+}
+
+var identityCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an identity key.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		name := args[0]
+		if err := keystore.CheckName(name); err != nil {
+			return cli.Errorf(cli.ClassUsage, "%w", err)
+		}
+		keys, err := keyStore()
+		if err != nil {
+			return err
+		}
+		identities, err := identityStore()
+		if err != nil {
+			return err
+		}
+		if !forceKey && (keys.Has(name) || identities.Has(name)) {
+			return fmt.Errorf("error creating identity: %w: %s", keystore.ErrExists, name)
+		}
+
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("error generating identity key: %w", err)
+		}
+		if err := identities.PutKey(name, privateKey); err != nil {
+			return cli.Errorf(cli.ClassIO, "error creating identity: %w", err)
+		}
+		b, err := identity.New(name, publicKey)
+		if err != nil {
+			return err
+		}
+		if err := identities.Put(b); err != nil {
+			return cli.Errorf(cli.ClassIO, "%w", err)
+		}
+		fingerprint, err := b.Fingerprint()
+		if err != nil {
+			return err
+		}
+		if err := contactPins().Set(identityPin(name), fingerprint); err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+
+		result, err := identityResult(b)
+		if err != nil {
+			return err
+		}
+		result.Status = "created"
+		return cli.WriteResult(os.Stdout, outputFormat, result)
+	},
+}
+
+var identityCertifyCmd = &cobra.Command{
+	Use:   "certify <identity> <device> <key>",
+	Short: "Certify a device key for one of your identities.",
+	Long: `Signs the public key <key> (a file or a stored key name) as the key of
+<device> with the identity key, valid for --days days from now. A device that
+was certified before gets the new certificate instead. Export the bundle again
+afterwards to hand out the new device key.`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		name, device := args[0], args[1]
+		if err := keystore.CheckName(device); err != nil {
+			return cli.Errorf(cli.ClassUsage, "invalid device name: %w", err)
+		}
+		if certifyDays <= 0 {
+			return cli.Errorf(cli.ClassUsage, "--days must be at least 1")
+		}
+		identities, err := identityStore()
+		if err != nil {
+			return err
+		}
+		b, err := identities.Get(name)
+		if err != nil {
+			return err
+		}
+		identityKey, err := loadIdentityKey(name)
+		if err != nil {
+			return err
+		}
+
+		keyPath, err := resolveKeyPath(args[2], false)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(keyPath)
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "error reading device key: %w", err)
+		}
+		deviceKey, err := crypto.DecodePublicKey(string(content))
+		if err != nil {
+			return fmt.Errorf("error decoding device key: %w", err)
+		}
+
+		validity := time.Duration(certifyDays) * 24 * time.Hour
+		if _, err := b.Certify(identityKey, device, deviceKey, time.Now(), validity); err != nil {
+			return fmt.Errorf("error certifying device key: %w", err)
+		}
+		if err := identities.Put(b); err != nil {
+			return cli.Errorf(cli.ClassIO, "%w", err)
+		}
+
+		result, err := identityResult(b)
+		if err != nil {
+			return err
+		}
+		result.Status = "updated"
+		return cli.WriteResult(os.Stdout, outputFormat, result)
+	},
+}
+
+var identityShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show an identity and its device keys.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		identities, err := identityStore()
+		if err != nil {
+			return err
+		}
+		b, err := identities.Get(args[0])
+		if err != nil {
+			return err
+		}
+		result, err := identityResult(b)
+		if err != nil {
+			return err
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, result)
+	},
+}
+
+var identityListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your identities and the ones you imported.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		identities, err := identityStore()
+		if err != nil {
+			return err
+		}
+		bundles, err := identities.List()
+		if err != nil {
+			return err
+		}
+		list := cli.IdentityListResult{Identities: []cli.IdentityResult{}}
+		for _, b := range bundles {
+			result, err := identityResult(b)
+			if err != nil {
+				return err
+			}
+			list.Identities = append(list.Identities, result)
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, list)
+	},
+}
+
+var identityExportCmd = &cobra.Command{
+	Use:   "export <name>",
+	Short: "Print the bundle of an identity, to share with people who send to you.",
+	Long: `Prints the bundle of an identity: the identity public key and the
+certificates of its device keys. Expired certificates are left out.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		identities, err := identityStore()
+		if err != nil {
+			return err
+		}
+		b, err := identities.Get(args[0])
+		if err != nil {
+			return err
+		}
+		data, err := b.Current(time.Now()).Marshal()
+		if err != nil {
+			return err
+		}
+
+		if exportOutput == "" {
+			fmt.Print(string(data))
+			return nil
+		}
+		if err := os.WriteFile(exportOutput, data, 0644); err != nil {
+			return cli.Errorf(cli.ClassIO, "error writing identity bundle: %w", err)
+		}
+		return nil
+	},
+}
+
+var identityImportCmd = &cobra.Command{
+	Use:   "import <name> <file>",
+	Short: "Import someone's identity bundle.",
+	Long: `Stores the identity bundle in <file> under <name>, after checking that
+every device key in it was certified by the identity key. An imported bundle
+replaces the earlier one, so people can hand out new device keys.
+
+The fingerprint of the identity key is pinned the first time. A bundle with a
+different identity key is refused until you check the new fingerprint with its
+owner and pass it with --fingerprint.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		name, path := args[0], args[1]
+		if err := keystore.CheckName(name); err != nil {
+			return cli.Errorf(cli.ClassUsage, "%w", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "error reading identity bundle: %w", err)
+		}
+		b, err := identity.Parse(data)
+		if err != nil {
+			return fmt.Errorf("error importing identity: %w", err)
+		}
+		b.Name = name
+		fingerprint, err := b.Fingerprint()
+		if err != nil {
+			return fmt.Errorf("error importing identity: %w", err)
+		}
+		if _, _, err := b.DeviceKeys(time.Now()); err != nil {
+			return fmt.Errorf("error importing identity: %w", err)
+		}
+
+		if trustFingerprint != "" {
+			if trustFingerprint != fingerprint {
+				return cli.Errorf(cli.ClassKey, "the identity key of %s has the fingerprint %s, not %s", name, fingerprint, trustFingerprint)
+			}
+			if err := contactPins().Set(identityPin(name), fingerprint); err != nil {
+				return cli.Errorf(cli.ClassConfig, "%w", err)
+			}
+		} else if err := checkPin(identityPin(name), name, fingerprint, "airbridge identity import "+name+" "+path+" --fingerprint <new fingerprint>"); err != nil {
+			return fmt.Errorf("error importing identity: %w", err)
+		}
+
+		identities, err := identityStore()
+		if err != nil {
+			return err
+		}
+		if err := identities.Put(b); err != nil {
+			return cli.Errorf(cli.ClassIO, "%w", err)
+		}
+		result, err := identityResult(b)
+		if err != nil {
+			return err
+		}
+		result.Status = "imported"
+		return cli.WriteResult(os.Stdout, outputFormat, result)
+	},
+}
+
+// identityStore returns the identity store in the config directory.
+func identityStore() (*identity.Store, error) {
+	dir, err := config.IdentitiesDir()
+	if err != nil {
+		return nil, cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+	return identity.NewStore(dir), nil
+}
+
+// isIdentity reports whether a -k value names an identity rather than a file or stored key.
+func isIdentity(value string) bool {
+	if value == "" {
+		return false
+	}
+	// Send can't use a directory, so only a file of the same name wins
+	if info, err := os.Stat(value); err == nil && !info.IsDir() {
+		return false
+	}
+	identities, err := identityStore()
+	return err == nil && identities.Has(value)
+}
+
+// identityPin returns the name the identity key of name is pinned under, kept
+// apart from contacts of the same name.
+func identityPin(name string) string {
+	return "identity:" + name
+}
+
+// loadIdentityKey reads the identity private key of name from the identity store.
+func loadIdentityKey(name string) (ed25519.PrivateKey, error) {
+	identities, err := identityStore()
+	if err != nil {
+		return nil, err
+	}
+	identityKey, err := identities.Key(name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading identity key: %w", err)
+	}
+	return identityKey, nil
+}
+
+// identityRecipient returns the keys of the devices of identity name that are
+// valid now, as PEM blocks for send, with warnings about the devices left out.
+func identityRecipient(name string) (string, []string, error) {
+	identities, err := identityStore()
+	if err != nil {
+		return "", nil, err
+	}
+	b, err := identities.Get(name)
+	if err != nil {
+		return "", nil, err
+	}
+	fingerprint, err := b.Fingerprint()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := checkPin(identityPin(name), name, fingerprint, "airbridge identity import "+name+" <bundle> --fingerprint <new fingerprint>"); err != nil {
+		return "", nil, fmt.Errorf("error using identity %s: %w", name, err)
+	}
+
+	deviceKeys, skipped, err := b.DeviceKeys(time.Now())
+	if err != nil {
+		return "", nil, fmt.Errorf("error using identity %s: %w", name, err)
+	}
+	var text strings.Builder
+	for _, key := range deviceKeys {
+		pubPEM, err := crypto.ExportPublicKeyAsPEM(key)
+		if err != nil {
+			return "", nil, err
+		}
+		text.Write(pubPEM)
+	}
+	var warnings []string
+	for _, device := range skipped {
+		warnings = append(warnings, fmt.Sprintf("Warning: left out device %s of %s, its certificate is not valid now", device, name))
+	}
+	return text.String(), warnings, nil
+}
+
+// identityResult describes a bundle for output.
+func identityResult(b identity.Bundle) (cli.IdentityResult, error) {
+	fingerprint, err := b.Fingerprint()
+	if err != nil {
+		return cli.IdentityResult{}, err
+	}
+	result := cli.IdentityResult{Name: b.Name, Fingerprint: fingerprint, Devices: []cli.DeviceResult{}}
+	if identities, err := identityStore(); err == nil {
+		if identityKey, err := identities.Key(b.Name); err == nil {
+			own, _ := crypto.FingerprintPublicKey(identityKey.Public())
+			result.Own = own == fingerprint
+		}
+	}
+
+	now := time.Now()
+	for _, c := range b.Devices {
+		key, err := c.PublicKey()
+		if err != nil {
+			return cli.IdentityResult{}, err
+		}
+		keyFingerprint, err := crypto.FingerprintPublicKey(key)
+		if err != nil {
+			return cli.IdentityResult{}, err
+		}
+		result.Devices = append(result.Devices, cli.DeviceResult{
+			Device:      c.Device,
+			Type:        crypto.KeyType(key),
+			Bits:        crypto.KeyBits(key),
+			Fingerprint: keyFingerprint,
+			NotBefore:   c.NotBefore,
+			NotAfter:    c.NotAfter,
+			Valid:       c.ValidAt(now),
+		})
+	}
+	return result, nil
+}
+
+func init() {
+	rootCmd.AddCommand(identityCmd)
+	identityCmd.AddCommand(identityCreateCmd, identityCertifyCmd, identityShowCmd, identityListCmd, identityExportCmd, identityImportCmd)
+
+	for _, c := range []*cobra.Command{identityCreateCmd, identityCertifyCmd, identityShowCmd, identityListCmd, identityImportCmd} {
+		c.Flags().StringVar(&outputFormat, "output-format", cli.FormatText, "Result format: text or json")
+	}
+	identityCreateCmd.Flags().BoolVarP(&forceKey, "force", "f", false, "Replace an existing key or identity with the same name")
+	identityCertifyCmd.Flags().IntVar(&certifyDays, "days", int(identity.DefaultValidity/(24*time.Hour)), "Days the device certificate is valid")
+	identityExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write the bundle to (default: stdout)")
+	identityImportCmd.Flags().StringVar(&trustFingerprint, "fingerprint", "", "Identity fingerprint you checked with its owner; trusts a changed identity")
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
+*/
+package cmd
+
+import (
+	"AirBridge/internal/cli"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var inspectKeyPath string
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <payload>",
+	Short: "Show what a payload contains without decrypting it.",
+	Long: `Reports the format, version and encoding of a payload, the keys it is
+encrypted to, whether it is signed, the ciphertext length and any structural
+problems such as bad base64, truncation or a wrong nonce length.
+
+Use "-" to read the payload from stdin. With -k (a private key file or a
+directory of private keys), it also tells whether each key can decrypt the
+payload. Nothing is written to disk.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+
+		var content []byte
+		var err error
+		if args[0] == "-" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "error reading payload: %w", err)
+		}
+
+		keyPath, err := resolveKeyPath(cfg.PrivateKey, true)
+		if err != nil {
+			return err
+		}
+		var keys []cli.KeyFile
+		if keyPath != "" {
+			keys, err = cli.LoadPrivateKeys(keyPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		return cli.WriteResult(os.Stdout, outputFormat, cli.InspectPayload(string(content), keys))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.Flags().StringVarP(&inspectKeyPath, "privkey", "k", "", "Private key file, stored key name or directory of keys to try (default from config)")
+	inspectCmd.Flags().StringVar(&outputFormat, "output-format", cli.FormatText, "Result format: text or json")
+}
@@ -0,0 +1,713 @@
+/*
+Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
+*/
+package cmd
+
+import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/config"
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/statement"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Public key export formats.
+const (
+	exportPEM    = "pem"
+	exportBase64 = "base64"
+)
+
+var (
+	forceKey         bool
+	exportFormat     string
+	exportOutput     string
+	trustFingerprint string
+	revokeReason     string
+	allowExpired     bool
+)
+
+// keyCmd represents the key command
+var keyCmd = &cobra.Command{
+	Use:     "key",
+	Aliases: []string{"keys"},
+	Short:   "Manage stored keys.",
+	Long: `Manages the key store ($XDG_CONFIG_HOME/airbridge/keys), which holds your
+own key pairs and the public keys of people you send to.
+
+Stored keys can be used by name wherever a key file is expected, e.g.
+"send -k alice" or "receive -k work". The store directory itself can be
+given to "receive -k" to pick the matching key automatically.
+
+The fingerprint of each contact's key is pinned the first time it is seen.
+If the key stored under that name changes later, send refuses it until the
+new key is verified with "airbridge key trust <name>".
+
+A key can carry statements it signed about itself: when it expires (see
+"keygen --expires") and that it was revoked (see "key revoke"). They are kept
+after the public key, so exporting the key hands them on, and importing them
+makes send refuse the key.`,
+}
+
+var keyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the stored keys.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		store, err := keyStore()
+		if err != nil {
+			return err
+		}
+		identities, err := store.List()
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "%w", err)
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, cli.KeyListResult{Keys: identities})
+	},
+}
+
+var keyShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the details of a stored key.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		store, err := keyStore()
+		if err != nil {
+			return err
+		}
+		identity, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, cli.KeyResult{Identity: identity})
+	},
+}
+
+var keyExportCmd = &cobra.Command{
+	Use:   "export <name>",
+	Short: "Print the public key of a stored key.",
+	Long: `Prints the public key of a stored key, to share with people who send to you.
+
+--format pem prints the PEM block, --format base64 prints it as the single
+line the receive screen shows. Statements kept with the key, such as its
+expiry or revocation, are included in both. -o doesn't replace an existing
+file unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutput(exportOutput); err != nil {
+			return err
+		}
+		store, err := keyStore()
+		if err != nil {
+			return err
+		}
+		pubPEM, err := store.PublicKeyPEM(args[0])
+		if err != nil {
+			return err
+		}
+
+		var exported string
+		switch exportFormat {
+		case exportPEM:
+			exported = string(pubPEM)
+		case exportBase64:
+			exported = base64.StdEncoding.EncodeToString(pubPEM) + "\n"
+		default:
+			return cli.Errorf(cli.ClassUsage, "unknown export format %q (expected %s or %s)", exportFormat, exportPEM, exportBase64)
+		}
+
+		if exportOutput == "" {
+			fmt.Print(exported)
+			return nil
+		}
+		return writeOutput(exportOutput, []byte(exported), "public key")
+	},
+}
+
+var keyImportCmd = &cobra.Command{
+	Use:   "import <name> <file>",
+	Short: "Store a private key, or someone's public key, under a name.",
+	Long: `Stores the key in <file> under <name>. A private key (PKCS#1, PKCS#8 or an
+OpenSSH key file) is stored as your own key pair, a public key (PEM, the base64
+form or an authorized_keys line such as ~/.ssh/id_ed25519.pub) as a recipient.
+RSA and Ed25519 keys are supported.
+
+Key statements in the file, such as an expiry or a revocation, are checked
+against the key and kept with it. A file holding only statements, like the one
+"airbridge key revoke" prints, updates the key already stored under <name>.
+
+An existing key with the same name is only replaced with --force. A public key
+never replaces one of your key pairs, as its private key would be lost: delete
+the key pair first if you mean to.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		name, path := args[0], args[1]
+		if err := keystore.CheckName(name); err != nil {
+			return cli.Errorf(cli.ClassUsage, "%w", err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "error reading key file: %w", err)
+		}
+		store, err := keyStore()
+		if err != nil {
+			return err
+		}
+
+		statements, err := statement.Parse(string(content))
+		if err != nil {
+			return err
+		}
+		kind := cli.DetectContent(string(content))
+		if len(statements) > 0 && kind != cli.ContentPrivateKey && kind != cli.ContentPublicKey {
+			return importStatements(store, name, statements)
+		}
+
+		pinned, err := contactPins().Get(name)
+		if err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+
+		var identity keystore.Identity
+		if kind == cli.ContentPrivateKey {
+			privateKey, err := crypto.DecodePrivateKey(content)
+			if err != nil {
+				return fmt.Errorf("error decoding private key: %w", err)
+			}
+			identity, err = store.AddPrivate(name, privateKey, forceKey)
+			if err != nil {
+				return fmt.Errorf("error importing key: %w", err)
+			}
+		} else {
+			publicKey, err := crypto.DecodePublicKey(string(content))
+			if err != nil {
+				return fmt.Errorf("error decoding public key: %w", err)
+			}
+			identity, err = store.AddPublic(name, publicKey, forceKey)
+			if err != nil {
+				return fmt.Errorf("error importing key: %w", err)
+			}
+		}
+		for _, st := range statements {
+			if st.Key != identity.Fingerprint {
+				continue
+			}
+			if identity, err = store.AddStatement(name, st); err != nil {
+				return fmt.Errorf("error importing key: %w", err)
+			}
+		}
+		result := cli.KeyResult{Identity: identity, Status: "imported", Warnings: statusWarnings(identity)}
+		switch pinned {
+		case "":
+			if err := contactPins().Set(name, identity.Fingerprint); err != nil {
+				return cli.Errorf(cli.ClassConfig, "%w", err)
+			}
+		case identity.Fingerprint:
+		default:
+			result.Warnings = append(keyChangedWarning(name, pinned, identity.Fingerprint, "airbridge key trust "+name), result.Warnings...)
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, result)
+	},
+}
+
+var keyRevokeCmd = &cobra.Command{
+	Use:   "revoke <name>",
+	Short: "Revoke one of your keys and print the revocation statement.",
+	Long: `Marks your key <name> as revoked, e.g. because it was lost or stolen, and
+prints a revocation statement signed by the key. Hand the statement to the
+people who send to you ("airbridge key import <name> <file>"), or publish it
+with your key (the "revocations" list of the well-known document): send then
+refuses the key.
+
+The statement is also kept with the public key, so "airbridge key export"
+includes it. Revoking a key again prints the same statement. -o doesn't replace
+an existing file unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Checked first, so the key isn't revoked when the statement can't be written
+		if err := checkOutput(exportOutput); err != nil {
+			return err
+		}
+		store, err := keyStore()
+		if err != nil {
+			return err
+		}
+		path, err := store.PrivateKeyPath(args[0])
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "error reading private key: %w", err)
+		}
+		privateKey, err := crypto.DecodePrivateKey(content)
+		if err != nil {
+			return fmt.Errorf("error decoding private key: %w", err)
+		}
+
+		revocation, err := storedRevocation(store, args[0])
+		if err != nil {
+			return err
+		}
+		if revocation == nil {
+			s, err := statement.Revocation(privateKey, time.Now(), revokeReason)
+			if err != nil {
+				return fmt.Errorf("error revoking key: %w", err)
+			}
+			if _, err := store.AddStatement(args[0], s); err != nil {
+				return fmt.Errorf("error revoking key: %w", err)
+			}
+			revocation = &s
+		}
+		block, err := revocation.Encode()
+		if err != nil {
+			return err
+		}
+
+		if exportOutput == "" {
+			fmt.Print(string(block))
+			return nil
+		}
+		return writeOutput(exportOutput, block, "revocation statement")
+	},
+}
+
+// checkOutput refuses an -o file that already exists unless --force is given,
+// like keygen does
+func checkOutput(path string) error {
+	if path == "" || forceKey {
+		return nil
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", keystore.ErrExists, path)
+	}
+	return nil
+}
+
+// writeOutput writes data to the -o file, which is only replaced with --force
+func writeOutput(path string, data []byte, what string) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !forceKey {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", keystore.ErrExists, path)
+	} else if err != nil {
+		return cli.Errorf(cli.ClassIO, "error writing %s: %w", what, err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return cli.Errorf(cli.ClassIO, "error writing %s: %w", what, err)
+	}
+	if err := file.Close(); err != nil {
+		return cli.Errorf(cli.ClassIO, "error writing %s: %w", what, err)
+	}
+	return nil
+}
+
+var keyDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a stored key.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		store, err := keyStore()
+		if err != nil {
+			return err
+		}
+		identity, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(identity.Name); err != nil {
+			return cli.Errorf(cli.ClassIO, "%w", err)
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, cli.KeyResult{Identity: identity, Status: "deleted"})
+	},
+}
+
+var keyRenameCmd = &cobra.Command{
+	Use:   "rename <name> <new-name>",
+	Short: "Rename a stored key.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		if err := keystore.CheckName(args[1]); err != nil {
+			return cli.Errorf(cli.ClassUsage, "%w", err)
+		}
+		store, err := keyStore()
+		if err != nil {
+			return err
+		}
+		identity, err := store.Rename(args[0], args[1], forceKey)
+		if err != nil {
+			return fmt.Errorf("error renaming key: %w", err)
+		}
+		if err := movePin(args[0], args[1]); err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, cli.KeyResult{Identity: identity, Status: "renamed"})
+	},
+}
+
+var keyTrustCmd = &cobra.Command{
+	Use:   "trust <name>",
+	Short: "Trust the key now stored under a name after it changed.",
+	Long: `Pins the key stored under <name> as the trusted one, after checking its
+fingerprint with its owner over another channel. Send refuses a contact whose
+key changed since it was first seen until this is done.
+
+Without a terminal to confirm on, pass the fingerprint you checked with
+--fingerprint.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		store, err := keyStore()
+		if err != nil {
+			return err
+		}
+		identity, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fingerprint, err := contactFingerprint(store, identity.Name)
+		if err != nil {
+			return err
+		}
+		pins := contactPins()
+		pinned, err := pins.Get(identity.Name)
+		if err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+
+		switch {
+		case trustFingerprint != "":
+			if trustFingerprint != fingerprint {
+				return cli.Errorf(cli.ClassKey, "the key of %s has the fingerprint %s, not %s", identity.Name, fingerprint, trustFingerprint)
+			}
+		case pinned == fingerprint:
+		case stdinIsTerminal():
+			p := cli.NewPrompter(os.Stdin, os.Stderr)
+			if pinned != "" {
+				p.Say("Trusted before: %s", pinned)
+			}
+			p.Say("Key now:        %s", fingerprint)
+			trusted, err := p.Confirm(fmt.Sprintf("Have you checked this fingerprint with %s?", identity.Name))
+			if err != nil {
+				return err
+			}
+			if !trusted {
+				return cli.Errorf(cli.ClassKey, "key of %s was not trusted", identity.Name)
+			}
+		default:
+			return cli.Errorf(cli.ClassUsage, "--fingerprint is required when not running interactively")
+		}
+
+		if err := pins.Set(identity.Name, fingerprint); err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, cli.KeyResult{Identity: identity, Status: "trusted"})
+	},
+}
+
+// importStatements adds statements to the key already stored under name, as
+// key import does for a file without a key
+func importStatements(store *keystore.Store, name string, statements []statement.Statement) error {
+	identity, err := store.Get(name)
+	if err != nil {
+		return err
+	}
+	for _, st := range statements {
+		if identity, err = store.AddStatement(name, st); err != nil {
+			return fmt.Errorf("error importing statement for %s: %w", name, err)
+		}
+	}
+	status := "updated"
+	if !identity.Revoked.IsZero() {
+		status = "revoked"
+	}
+	return cli.WriteResult(os.Stdout, outputFormat, cli.KeyResult{Identity: identity, Status: status, Warnings: statusWarnings(identity)})
+}
+
+// storedRevocation returns the revocation stored with the key called name, or nil
+func storedRevocation(store *keystore.Store, name string) (*statement.Statement, error) {
+	pubPEM, err := store.PublicKeyPEM(name)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := statement.Parse(string(pubPEM))
+	if err != nil {
+		return nil, fmt.Errorf("error reading statements of %s: %w", name, err)
+	}
+	for _, st := range statements {
+		if st.Kind == statement.KindRevocation {
+			return &st, nil
+		}
+	}
+	return nil, nil
+}
+
+// statusWarnings warns about a stored key that is revoked or outside its validity
+func statusWarnings(identity keystore.Identity) []string {
+	if err := identity.Status().Check(time.Now()); err != nil {
+		return []string{fmt.Sprintf("Warning: the %s %v", identity.Name, err)}
+	}
+	return nil
+}
+
+// checkKeyStatus checks the statements kept with the recipient keys in keyText,
+// and any extra ones. Revoked keys are refused. Keys outside their validity are
+// only used with --allow-expired, or if the user agrees on the terminal.
+func checkKeyStatus(recipient, keyText string, extra []statement.Statement) error {
+	keys, err := crypto.DecodePublicKeys(keyText)
+	if err != nil {
+		// Bad keys are reported when they are used
+		return nil
+	}
+	statements, err := statement.Parse(keyText)
+	if err != nil {
+		return fmt.Errorf("error using key %s: %w", recipient, err)
+	}
+	statements = append(statements, extra...)
+
+	for _, key := range keys {
+		status, err := statement.StatusOf(key, statements)
+		if err != nil {
+			return fmt.Errorf("error using key %s: %w", recipient, err)
+		}
+		err = status.Check(time.Now())
+		switch {
+		case err == nil:
+		case errors.Is(err, statement.ErrRevoked):
+			return fmt.Errorf("error using key %s: %w", recipient, err)
+		case allowExpired:
+		case stdinIsTerminal() && outputFormat != cli.FormatJSON:
+			p := cli.NewPrompter(os.Stdin, os.Stderr)
+			send, err := p.Confirm(fmt.Sprintf("The %s %v. Send anyway?", recipient, err))
+			if err != nil {
+				return err
+			}
+			if !send {
+				return cli.Errorf(cli.ClassKey, "not sending to the key of %s", recipient)
+			}
+		default:
+			return fmt.Errorf("error using key %s: %w", recipient, err)
+		}
+	}
+	return nil
+}
+
+// usedKeyWarnings warns when the private key to decrypt with is a stored key
+// that was revoked or is outside its validity
+func usedKeyWarnings(privKeyPEM []byte) []string {
+	privateKey, err := crypto.DecodePrivateKey(privKeyPEM)
+	if err != nil {
+		return nil
+	}
+	fingerprint, err := crypto.FingerprintPublicKey(crypto.PublicKeyOf(privateKey))
+	if err != nil {
+		return nil
+	}
+	store, err := keyStore()
+	if err != nil {
+		return nil
+	}
+	identity, err := store.Find(fingerprint)
+	if err != nil {
+		return nil
+	}
+	return statusWarnings(identity)
+}
+
+// keyStore returns the key store in the config directory.
+func keyStore() (*keystore.Store, error) {
+	dir, err := config.KeysDir()
+	if err != nil {
+		return nil, cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+	return keystore.New(dir), nil
+}
+
+// resolveKeyPath returns the key file for a -k value. A leading ~/ is the home
+// directory, and values that aren't files or directories are looked up by name
+// in the key store.
+func resolveKeyPath(value string, private bool) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if rest, ok := strings.CutPrefix(value, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			value = filepath.Join(home, rest)
+		}
+	}
+	if _, err := os.Stat(value); err == nil || keystore.CheckName(value) != nil {
+		return value, nil
+	}
+	store, err := keyStore()
+	if err != nil {
+		return "", err
+	}
+	if !store.Has(value) {
+		return value, nil
+	}
+	var path string
+	if private {
+		path, err = store.PrivateKeyPath(value)
+	} else {
+		path, err = store.PublicKeyPath(value)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error using key %s: %w", value, err)
+	}
+	return path, nil
+}
+
+// isStoredKey reports whether a -k value names a key in the store rather than a file.
+func isStoredKey(value string) bool {
+	if _, err := os.Stat(value); err == nil || keystore.CheckName(value) != nil {
+		return false
+	}
+	store, err := keyStore()
+	return err == nil && store.Has(value)
+}
+
+// signingKey loads the private key payloads are signed with, from a stored key
+// name or a private key file. It returns nil if value is empty.
+func signingKey(value string) (crypto.PrivateKey, error) {
+	path, err := resolveKeyPath(value, true)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, cli.Errorf(cli.ClassIO, "error reading signing key: %w", err)
+	}
+	privateKey, err := crypto.DecodePrivateKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signing key: %w", err)
+	}
+	return privateKey, nil
+}
+
+// contactPins returns the fingerprints pinned in the config directory. Without a
+// config directory nothing can be pinned, so every key is new.
+func contactPins() *keystore.Pins {
+	dir, err := config.Dir()
+	if err != nil {
+		return &keystore.Pins{Path: os.DevNull}
+	}
+	return &keystore.Pins{Path: filepath.Join(dir, keystore.PinsFile)}
+}
+
+// contactFingerprint returns the fingerprint of the public key stored under name,
+// computed from the key itself rather than taken from its metadata.
+func contactFingerprint(store *keystore.Store, name string) (string, error) {
+	pubPEM, err := store.PublicKeyPEM(name)
+	if err != nil {
+		return "", err
+	}
+	publicKey, err := crypto.DecodePublicKey(string(pubPEM))
+	if err != nil {
+		return "", fmt.Errorf("error decoding public key %s: %w", name, err)
+	}
+	return crypto.FingerprintPublicKey(publicKey)
+}
+
+// checkContact compares the key stored under name with the one first seen for it,
+// pinning it if it is new.
+func checkContact(name string) error {
+	store, err := keyStore()
+	if err != nil {
+		return err
+	}
+	fingerprint, err := contactFingerprint(store, name)
+	if err != nil {
+		return err
+	}
+	if err := checkPin(name, name, fingerprint, "airbridge key trust "+name); err != nil {
+		return fmt.Errorf("error using key %s: %w", name, err)
+	}
+	return nil
+}
+
+// checkPin compares fingerprint with the one pinned under pin, pinning it if it is
+// new and warning with both fingerprints if it changed. retry is the command that
+// trusts the new key.
+func checkPin(pin, name, fingerprint, retry string) error {
+	pins := contactPins()
+	pinned, err := pins.Get(pin)
+	if err != nil {
+		return cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+	if pinned != "" && pinned != fingerprint && outputFormat != cli.FormatJSON {
+		fmt.Fprintln(os.Stderr, strings.Join(keyChangedWarning(name, pinned, fingerprint, retry), "\n"))
+	}
+	_, err = pins.Check(pin, fingerprint)
+	return err
+}
+
+// keyChangedWarning returns the warning shown when the key of name is no longer the pinned one.
+func keyChangedWarning(name, pinned, fingerprint, retry string) []string {
+	return []string{
+		fmt.Sprintf("WARNING: THE KEY OF %s HAS CHANGED!", strings.ToUpper(name)),
+		"  Trusted before: " + pinned,
+		"  Key now:        " + fingerprint,
+		"Someone may have swapped it. Check the new fingerprint with " + name + " over another channel,",
+		"then run '" + retry + "'. Until then send refuses this key.",
+	}
+}
+
+// movePin moves the fingerprint pinned for a renamed contact to its new name.
+func movePin(oldName, newName string) error {
+	pins := contactPins()
+	pinned, err := pins.Get(oldName)
+	if err != nil || pinned == "" || oldName == newName {
+		return err
+	}
+	if err := pins.Set(newName, pinned); err != nil {
+		return err
+	}
+	return pins.Delete(oldName)
+}
+
+func init() {
+	rootCmd.AddCommand(keyCmd)
+	keyCmd.AddCommand(keyListCmd, keyShowCmd, keyExportCmd, keyImportCmd, keyDeleteCmd, keyRenameCmd, keyTrustCmd, keyRevokeCmd)
+
+	for _, c := range []*cobra.Command{keyListCmd, keyShowCmd, keyImportCmd, keyDeleteCmd, keyRenameCmd, keyTrustCmd} {
+		c.Flags().StringVar(&outputFormat, "output-format", cli.FormatText, "Result format: text or json")
+	}
+	keyImportCmd.Flags().BoolVarP(&forceKey, "force", "f", false, "Replace an existing key with the same name")
+	keyRenameCmd.Flags().BoolVarP(&forceKey, "force", "f", false, "Replace an existing key with the new name")
+	keyTrustCmd.Flags().StringVar(&trustFingerprint, "fingerprint", "", "Fingerprint you checked with the key's owner")
+	keyExportCmd.Flags().StringVar(&exportFormat, "format", exportPEM, "Export format: pem or base64")
+	keyExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write the public key to (default: stdout)")
+	keyExportCmd.Flags().BoolVarP(&forceKey, "force", "f", false, "Overwrite an existing -o file")
+	keyRevokeCmd.Flags().StringVar(&revokeReason, "reason", "", "Why the key is revoked, e.g. \"laptop stolen\"")
+	keyRevokeCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write the revocation statement to (default: stdout)")
+	keyRevokeCmd.Flags().BoolVarP(&forceKey, "force", "f", false, "Overwrite an existing -o file")
+}
@@ -4,16 +4,25 @@ Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
 package cmd
 
 import (
+	"AirBridge/internal/cli"
 	"AirBridge/internal/crypto"
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/statement"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	outDir string
+	outDir        string
+	keyName       string
+	keyType       string
+	keyBits       int
+	keyExpiryDays int
 )
 
 // keygenCmd represents the keygen command
@@ -21,45 +30,126 @@ var keygenCmd = &cobra.Command{
 	Use:   "keygen",
 	Short: "Generate a new RSA key pair.",
 	Long: `Generates a new RSA key pair (private.pem and public.pem)
-in the specified directory (defaults to current directory).
+in the specified directory (defaults to current directory), or with --name
+in the key store (see "airbridge key").
 
+--bits picks the key size: 2048, 3072 or 4096 (default from the key_bits
+setting, else 2048). --type only accepts rsa for now.
+
+--expires gives the key a validity of that many days, signed by the key
+itself and kept after the public key, so people sending to you see when it
+expires. Without it the key doesn't expire.
+
+Existing keys are only overwritten with --force.
 These keys can be used for the send and receive commands.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Generating RSA key pair...")
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		if keyExpiryDays < 0 {
+			return cli.Errorf(cli.ClassUsage, "--expires must be a number of days")
+		}
+
+		privateKeyPath := filepath.Join(outDir, "private.pem")
+		publicKeyPath := filepath.Join(outDir, "public.pem")
+		var store *keystore.Store
+		if keyName != "" {
+			if err := keystore.CheckName(keyName); err != nil {
+				return cli.Errorf(cli.ClassUsage, "%w", err)
+			}
+			var err error
+			if store, err = keyStore(); err != nil {
+				return err
+			}
+			if !forceKey && store.Has(keyName) {
+				return fmt.Errorf("%w: %s", keystore.ErrExists, keyName)
+			}
+		} else if !forceKey {
+			for _, path := range []string{privateKeyPath, publicKeyPath} {
+				if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("%w: %s", keystore.ErrExists, path)
+				}
+			}
+		}
+
+		if outputFormat == cli.FormatText {
+			fmt.Printf("Generating %d-bit RSA key pair...\n", cfg.KeyBits)
+		}
 
-		privateKey, publicKey, err := crypto.GenerateRSAKeyPair()
+		privateKey, publicKey, err := crypto.GenerateRSAKeyPairBits(cfg.KeyBits)
 		if err != nil {
-			fmt.Printf("Error generating keys: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("error generating keys: %w", err)
+		}
+		var validity *statement.Statement
+		if keyExpiryDays > 0 {
+			s, err := statement.Validity(privateKey, time.Now(), time.Duration(keyExpiryDays)*24*time.Hour)
+			if err != nil {
+				return fmt.Errorf("error signing key validity: %w", err)
+			}
+			validity = &s
+		}
+
+		if store != nil {
+			identity, err := store.AddPrivate(keyName, privateKey, forceKey)
+			if err != nil {
+				return fmt.Errorf("error storing key: %w", err)
+			}
+			if validity != nil {
+				if identity, err = store.AddStatement(keyName, *validity); err != nil {
+					return fmt.Errorf("error storing key validity: %w", err)
+				}
+			}
+			privateKeyPath, _ = store.PrivateKeyPath(keyName)
+			publicKeyPath, _ = store.PublicKeyPath(keyName)
+			return cli.WriteResult(os.Stdout, outputFormat, cli.KeygenResult{
+				Name:        keyName,
+				Type:        identity.Type,
+				Bits:        identity.Bits,
+				PrivateKey:  privateKeyPath,
+				PublicKey:   publicKeyPath,
+				Fingerprint: identity.Fingerprint,
+				NotAfter:    identity.NotAfter,
+			})
 		}
 
 		// Private Key
 		privPEM, err := crypto.ExportRSAPrivateKeyAsPEM(privateKey)
 		if err != nil {
-			fmt.Printf("Error exporting private key: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("error exporting private key: %w", err)
 		}
 
-		privateKeyPath := filepath.Join(outDir, "private.pem")
 		if err := os.WriteFile(privateKeyPath, privPEM, 0600); err != nil {
-			fmt.Printf("Error writing private key to file: %v\n", err)
-			os.Exit(1)
+			return cli.Errorf(cli.ClassIO, "error writing private key to file: %w", err)
 		}
-		fmt.Printf("Private key saved to: %s\n", privateKeyPath)
 
 		// Public Key
 		pubPEM, err := crypto.ExportRSAPublicKeyAsPEM(publicKey)
 		if err != nil {
-			fmt.Printf("Error exporting public key: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("error exporting public key: %w", err)
+		}
+		result := cli.KeygenResult{
+			Type:       keystore.TypeRSA,
+			Bits:       publicKey.N.BitLen(),
+			PrivateKey: privateKeyPath,
+			PublicKey:  publicKeyPath,
+		}
+		if validity != nil {
+			block, err := validity.Encode()
+			if err != nil {
+				return err
+			}
+			pubPEM = append(pubPEM, block...)
+			result.NotAfter = validity.NotAfter
 		}
 
-		publicKeyPath := filepath.Join(outDir, "public.pem")
 		if err := os.WriteFile(publicKeyPath, pubPEM, 0644); err != nil {
-			fmt.Printf("Error writing public key to file: %v\n", err)
-			os.Exit(1)
+			return cli.Errorf(cli.ClassIO, "error writing public key to file: %w", err)
+		}
+
+		if result.Fingerprint, err = crypto.FingerprintRSAPublicKey(publicKey); err != nil {
+			return err
 		}
-		fmt.Printf("Public key saved to: %s\n", publicKeyPath)
+		return cli.WriteResult(os.Stdout, outputFormat, result)
 	},
 }
 
@@ -67,4 +157,10 @@ func init() {
 	rootCmd.AddCommand(keygenCmd)
 
 	keygenCmd.Flags().StringVarP(&outDir, "output", "o", ".", "Directory to save the generated keys")
+	keygenCmd.Flags().StringVarP(&keyName, "name", "n", "", "Save the keys in the key store under this name instead")
+	keygenCmd.Flags().BoolVarP(&forceKey, "force", "f", false, "Overwrite existing keys")
+	keygenCmd.Flags().StringVar(&keyType, "type", "", "Key algorithm: rsa (default from config, else rsa)")
+	keygenCmd.Flags().IntVar(&keyBits, "bits", 0, "Key size: 2048, 3072 or 4096 (default from config, else 2048)")
+	keygenCmd.Flags().IntVar(&keyExpiryDays, "expires", 0, "Days until the key expires (default: never)")
+	keygenCmd.Flags().StringVar(&outputFormat, "output-format", cli.FormatText, "Result format: text or json")
 }
@@ -0,0 +1,175 @@
+/*
+Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
+*/
+package cmd
+
+import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/config"
+	"AirBridge/internal/keypool"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var poolSize int
+
+var keyPoolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage the pool of pre-generated temporary keys.",
+	Long: `Manages the key pool: temporary key pairs generated ahead of time, so a
+receive without -k starts at once instead of waiting for a new RSA key.
+
+Each key is taken from the pool once and then deleted. If the pool is empty,
+receive generates a key as before. Pooled keys are kept encrypted in
+$XDG_CACHE_HOME/airbridge/keypool, with the key they are encrypted with in the
+config directory.
+
+This encryption only obscures the pooled keys. The pool key is not protected by
+a passphrase, so anyone who can read your config and cache directories (any
+program running as you, for instance) can decrypt payloads sent to a pooled
+key. It only keeps the keys safe from a copy of the cache directory alone, such
+as a backup. Leave key_pool at 0 if that isn't enough.
+
+Set key_pool to the number of keys to keep (e.g. "airbridge config set
+key_pool 3"), and the pool is refilled in the background after each receive.`,
+}
+
+var keyPoolRefillCmd = &cobra.Command{
+	Use:   "refill",
+	Short: "Generate keys until the pool is full.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		pool, err := keyPool()
+		if err != nil {
+			return err
+		}
+		size := poolSize
+		if size == 0 {
+			size = cfg.KeyPool
+		}
+		if size == 0 {
+			size = keypool.DefaultSize
+		}
+		if size < 0 || size > config.MaxKeyPool {
+			return cli.Errorf(cli.ClassUsage, "--size must be between 1 and %d", config.MaxKeyPool)
+		}
+
+		added, err := pool.Refill(cfg.KeyBits, size)
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "error refilling key pool: %w", err)
+		}
+		return writeKeyPool(pool, cli.KeyPoolResult{Added: added})
+	},
+}
+
+// keyRefillCmd is "key pool refill" as "keys refill", the name it was first asked for.
+var keyRefillCmd = &cobra.Command{
+	Use:   "refill",
+	Short: `Generate keys until the pool is full (same as "key pool refill").`,
+	Args:  cobra.NoArgs,
+	RunE:  keyPoolRefillCmd.RunE,
+}
+
+var keyPoolStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show how many keys the pool holds.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		pool, err := keyPool()
+		if err != nil {
+			return err
+		}
+		return writeKeyPool(pool, cli.KeyPoolResult{})
+	},
+}
+
+var keyPoolClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete every key in the pool.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		pool, err := keyPool()
+		if err != nil {
+			return err
+		}
+		removed, err := pool.Clear()
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "error clearing key pool: %w", err)
+		}
+		return writeKeyPool(pool, cli.KeyPoolResult{Removed: removed})
+	},
+}
+
+// writeKeyPool completes result with what the pool holds now and writes it
+func writeKeyPool(pool *keypool.Pool, result cli.KeyPoolResult) error {
+	count, err := pool.Count(cfg.KeyBits)
+	if err != nil {
+		return cli.Errorf(cli.ClassIO, "%w", err)
+	}
+	result.Keys, result.Bits, result.Size = count, cfg.KeyBits, cfg.KeyPool
+	return cli.WriteResult(os.Stdout, outputFormat, result)
+}
+
+// keyPool returns the key pool in the cache directory, encrypted with the pool
+// key in the config directory.
+func keyPool() (*keypool.Pool, error) {
+	dir, err := config.KeyPoolDir()
+	if err != nil {
+		return nil, cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+	configDir, err := config.Dir()
+	if err != nil {
+		return nil, cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+	return keypool.New(dir, filepath.Join(configDir, keypool.KeyFileName)), nil
+}
+
+// refillKeyPool starts "key pool refill" in the background if the pool holds fewer
+// keys than the key_pool setting, so the next receive finds a key ready. It
+// doesn't wait for it, and the refill goes on after AirBridge exits.
+func refillKeyPool() {
+	if cfg.KeyPool == 0 {
+		return
+	}
+	pool, err := keyPool()
+	if err != nil {
+		return
+	}
+	if count, err := pool.Count(cfg.KeyBits); err != nil || count >= cfg.KeyPool {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	refill := exec.Command(exe, "key", "pool", "refill", "--size", strconv.Itoa(cfg.KeyPool), "--bits", strconv.Itoa(cfg.KeyBits))
+	if err := refill.Start(); err == nil {
+		_ = refill.Process.Release()
+	}
+}
+
+func init() {
+	keyCmd.AddCommand(keyPoolCmd, keyRefillCmd)
+	keyPoolCmd.AddCommand(keyPoolRefillCmd, keyPoolStatusCmd, keyPoolClearCmd)
+
+	for _, c := range []*cobra.Command{keyPoolRefillCmd, keyRefillCmd, keyPoolStatusCmd, keyPoolClearCmd} {
+		c.Flags().StringVar(&outputFormat, "output-format", cli.FormatText, "Result format: text or json")
+		c.Flags().IntVar(&keyBits, "bits", 0, "Size of the pooled keys: 2048, 3072 or 4096 (default from config, else 2048)")
+	}
+	for _, c := range []*cobra.Command{keyPoolRefillCmd, keyRefillCmd} {
+		c.Flags().IntVar(&poolSize, "size", 0, "Number of keys to keep (default from config, else 3)")
+	}
+}
@@ -6,8 +6,12 @@ package cmd
 import (
 	"AirBridge/internal/cli"
 	"AirBridge/internal/tui/receive"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
@@ -18,6 +22,13 @@ var privKeyPath string
 var inputPayloadPath string
 var deletePayload bool
 var headlessReceive bool
+var outputDir string
+var conflictPolicy string
+var resumeSession string
+var receiveInbox bool
+var watchDir string
+var watchInterval time.Duration
+var expectSender string
 
 var receiveCmd = &cobra.Command{
 	Use:   "receive",
@@ -26,64 +37,250 @@ var receiveCmd = &cobra.Command{
 It generates a temporary key pair for this session and displays the public key.
 You can then provide the encrypted text block.
 
+With sessions turned on (session_ttl, see "airbridge session"), the temporary
+key is saved as a receive session. If AirBridge is closed before the payload
+arrives, resume with --resume <id>. Ctrl+X quits and deletes the session instead.
+Expired sessions are purged whenever receive starts.
+
+With --inbox, receive keeps going after each payload, decrypting every one sent
+to the same key until you quit.
+
+With --watch <dir> and -k, receive runs until interrupted, decrypting every
+payload (*.abp) dropped into the directory and moving it into done/ or failed/.
+
+A signed payload shows which contact signed it, or warns that its key is
+unverified if no contact is pinned to it (see "airbridge key import"). With
+--from <name>, the payload must be signed, by the key pinned for that contact;
+the first key seen for a new name is pinned, and a different one later is refused.
+
 Use --headless with -k and -i for headless mode.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		var initialPrivKeyPEM []byte
-		if privKeyPath != "" {
-			var err error
-			initialPrivKeyPEM, err = os.ReadFile(privKeyPath)
-			if err != nil {
-				fmt.Printf("Error reading private key file: %v\n", err)
-				os.Exit(1)
-			}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		if watchDir != "" {
+			return watchPayloads(watchDir)
 		}
 
 		var initialPayload string
 		if inputPayloadPath != "" {
 			content, err := os.ReadFile(inputPayloadPath)
 			if err != nil {
-				fmt.Printf("Error reading payload file: %v\n", err)
-				os.Exit(1)
+				return cli.Errorf(cli.ClassIO, "error reading payload file: %w", err)
 			}
 			initialPayload = string(content)
 		}
 
-		var appMode = ModeTUI
-		if headlessReceive {
+		var initialPrivKeyPEM []byte
+		// keyDir holds the keys of a directory given without a payload, to pick from once it is pasted
+		var keyDir []cli.KeyFile
+		if resumeSession != "" {
+			if privKeyPath != "" {
+				return cli.Errorf(cli.ClassUsage, "--resume can't be used with -k")
+			}
+			var err error
+			initialPrivKeyPEM, err = sessionKey(resumeSession)
+			if err != nil {
+				return fmt.Errorf("error resuming session: %w", err)
+			}
+		} else {
+			keyPath, err := resolveKeyPath(cfg.PrivateKey, true)
+			if err != nil {
+				return err
+			}
+			if info, statErr := os.Stat(keyPath); statErr == nil && info.IsDir() && initialPayload == "" {
+				keyDir, err = cli.LoadPrivateKeys(keyPath)
+				if err != nil {
+					return err
+				}
+				keyPath = ""
+			}
+			keyPath, err = selectKeyPath(keyPath, initialPayload)
+			if err != nil {
+				return err
+			}
+			if keyPath != "" {
+				initialPrivKeyPEM, err = os.ReadFile(keyPath)
+				if err != nil {
+					return cli.Errorf(cli.ClassIO, "error reading private key file: %w", err)
+				}
+			}
+		}
+
+		warnings := usedKeyWarnings(initialPrivKeyPEM)
+		for _, key := range keyDir {
+			if pemBytes, err := os.ReadFile(key.Path); err == nil {
+				warnings = append(warnings, usedKeyWarnings(pemBytes)...)
+			}
+		}
+		// Purged after --resume opened its session, so an expired one is reported as such
+		if err := cli.PurgeSessions(); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Warning: %v", err))
+		}
+
+		var appMode = interactiveMode()
+		if headlessReceive || outputFormat == cli.FormatJSON {
 			appMode = ModeCLI
 		}
 
+		if appMode != ModeCLI {
+			for _, warning := range warnings {
+				fmt.Fprintln(os.Stderr, warning)
+			}
+			if len(initialPrivKeyPEM) == 0 && len(keyDir) == 0 {
+				// The temporary key may come from the pool, so top it up on the way out
+				defer refillKeyPool()
+			}
+		}
+
+		if receiveInbox && appMode != ModeTUI {
+			return cli.Errorf(cli.ClassUsage, "--inbox needs the TUI, it can't be used in headless or plain mode")
+		}
+
 		switch appMode {
 		case ModeCLI:
-			if len(initialPrivKeyPEM) == 0 {
-				fmt.Println("Error: Private key (-k) required in headless mode")
-				os.Exit(1)
+			if len(initialPrivKeyPEM) == 0 && len(keyDir) == 0 {
+				return cli.Errorf(cli.ClassUsage, "private key (-k) required in headless mode")
 			}
 			if initialPayload == "" {
-				fmt.Println("Error: Input payload (-i) required in headless mode")
-				os.Exit(1)
+				return cli.Errorf(cli.ClassUsage, "input payload (-i) required in headless mode")
 			}
 
 			// Headless Execution
-			if err := cli.RunReceive(initialPayload, initialPrivKeyPEM, inputPayloadPath, deletePayload); err != nil {
-				fmt.Printf("Error running headless receive: %v\n", err)
-				os.Exit(1)
+			result, err := cli.RunReceive(initialPayload, initialPrivKeyPEM, inputPayloadPath, deletePayload, progressFunc())
+			if err != nil {
+				return fmt.Errorf("error running headless receive: %w", err)
+			}
+			result.Warnings = append(warnings, result.Warnings...)
+			if err := cli.EndSession(resumeSession); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to end session: %v", err))
+			}
+			return cli.WriteResult(os.Stdout, outputFormat, result)
+
+		case ModePlain:
+			if len(keyDir) > 0 {
+				return cli.Errorf(cli.ClassUsage, "input payload (-i) required to pick a key from %s in plain mode", cfg.PrivateKey)
+			}
+			p := cli.NewPrompter(os.Stdin, os.Stdout)
+			if err := cli.RunPlainReceive(p, initialPrivKeyPEM, initialPayload, inputPayloadPath, deletePayload, progressFunc()); err != nil {
+				return fmt.Errorf("error running receive: %w", err)
+			}
+			if err := cli.EndSession(resumeSession); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to end session: %v\n", err)
 			}
 
 		case ModeTUI:
-			p := tea.NewProgram(receive.InitialModel(initialPrivKeyPEM, initialPayload, inputPayloadPath, deletePayload))
+			model := receive.InitialModel(initialPrivKeyPEM, initialPayload, inputPayloadPath, deletePayload).Resume(resumeSession).Keys(keyDir)
+			if receiveInbox {
+				model.Inbox()
+			}
+			p := tea.NewProgram(model)
 			if _, err := p.Run(); err != nil {
-				fmt.Printf("Alas, there's been an error: %v", err)
-				os.Exit(1)
+				return fmt.Errorf("alas, there's been an error: %w", err)
 			}
+			handOffClipboardClear()
 		}
+		return nil
 	},
 }
 
+// watchPayloads decrypts the payloads dropped into dir until interrupted,
+// printing a line for each one
+func watchPayloads(dir string) error {
+	switch {
+	case inputPayloadPath != "":
+		return cli.Errorf(cli.ClassUsage, "--watch can't be used with -i")
+	case deletePayload:
+		return cli.Errorf(cli.ClassUsage, "--watch can't be used with -d, handled payloads are moved into %s/ or %s/", cli.WatchDone, cli.WatchFailed)
+	case resumeSession != "":
+		return cli.Errorf(cli.ClassUsage, "--watch can't be used with --resume")
+	case receiveInbox:
+		return cli.Errorf(cli.ClassUsage, "--watch can't be used with --inbox")
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return cli.Errorf(cli.ClassIO, "error reading watched directory: %w", err)
+	} else if !info.IsDir() {
+		return cli.Errorf(cli.ClassUsage, "%s is not a directory", dir)
+	}
+
+	keyPath, err := resolveKeyPath(cfg.PrivateKey, true)
+	if err != nil {
+		return err
+	}
+	if keyPath == "" {
+		return cli.Errorf(cli.ClassUsage, "private key (-k) required with --watch")
+	}
+	keys, err := cli.LoadPrivateKeys(keyPath)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if pemBytes, err := os.ReadFile(key.Path); err == nil {
+			for _, warning := range usedKeyWarnings(pemBytes) {
+				fmt.Fprintln(os.Stderr, warning)
+			}
+		}
+	}
+
+	if err := cli.PurgeSessions(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher := &cli.Watcher{
+		Dir:        dir,
+		Keys:       keys,
+		Interval:   watchInterval,
+		OnProgress: progressFunc(),
+		Log: func(event cli.WatchEvent) {
+			cli.WriteEvent(os.Stdout, outputFormat, event)
+		},
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Watching %s for payloads, press Ctrl+C to stop.\n", dir)
+	}
+	return watcher.Run(ctx)
+}
+
+// selectKeyPath returns the private key file to decrypt the payload with.
+// If path is a directory, the key is picked from it by the payload's recipient.
+func selectKeyPath(path string, payload string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return path, nil
+	}
+	if payload == "" {
+		return "", cli.Errorf(cli.ClassUsage, "input payload (-i) required to pick a key from %s", path)
+	}
+	keys, err := cli.LoadPrivateKeys(path)
+	if err != nil {
+		return "", err
+	}
+	key, err := cli.SelectKey(payload, keys)
+	if err != nil {
+		return "", fmt.Errorf("error selecting private key: %w", err)
+	}
+	return key.Path, nil
+}
+
 func init() {
 	rootCmd.AddCommand(receiveCmd)
-	receiveCmd.Flags().StringVarP(&privKeyPath, "privkey", "k", "", "Path to private key")
+	receiveCmd.Flags().StringVarP(&privKeyPath, "privkey", "k", "", "Private key file, stored key name, or a directory of keys to pick from (default from config)")
+	receiveCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "Directory to save the received file (default: current directory)")
+	receiveCmd.Flags().StringVar(&conflictPolicy, "on-conflict", "", "When the file exists: overwrite, rename or fail (default from config, else overwrite)")
 	receiveCmd.Flags().StringVarP(&inputPayloadPath, "input", "i", "", "Path to input payload file")
+	receiveCmd.Flags().StringVar(&expectSender, "from", "", "Only accept payloads signed by this contact's key, pinning it on first use")
+	receiveCmd.Flags().StringVar(&resumeSession, "resume", "", "Resume the receive session with this ID, decrypting with its saved key")
+	receiveCmd.Flags().BoolVar(&receiveInbox, "inbox", false, "Keep receiving payloads for the same key until you quit")
+	receiveCmd.Flags().StringVar(&watchDir, "watch", "", "Decrypt every payload dropped into this directory until interrupted (requires -k)")
+	receiveCmd.Flags().DurationVar(&watchInterval, "interval", cli.DefaultWatchInterval, "How often --watch scans the directory")
+	receiveCmd.Flags().IntVar(&keyBits, "bits", 0, "Size of the temporary key pair: 2048, 3072 or 4096 (default from config, else 2048)")
 	receiveCmd.Flags().BoolVarP(&deletePayload, "delete", "d", false, "Delete payload file after successful decryption")
 	receiveCmd.Flags().BoolVarP(&headlessReceive, "headless", "H", false, "Run in headless mode (requires -k and -i)")
+	receiveCmd.Flags().StringVar(&outputFormat, "output-format", cli.FormatText, "Result format: text or json (json implies --headless)")
+	receiveCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Don't print progress in headless mode")
 }
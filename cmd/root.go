@@ -4,8 +4,17 @@ Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
 package cmd
 
 import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/clipboard"
+	"AirBridge/internal/config"
+	"AirBridge/internal/progress"
+	"AirBridge/internal/tui"
+	"AirBridge/internal/tui/home"
+	"fmt"
 	"os"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
@@ -14,8 +23,33 @@ type AppMode int
 const (
 	ModeTUI AppMode = iota
 	ModeCLI
+	ModePlain
 )
 
+var (
+	cfg              = config.Default()
+	clipboardBackend string
+	themeName        string
+	quiet            bool
+	plain            bool
+	profileName      string
+	outputFormat     = cli.FormatText
+)
+
+// settingFlags maps flags to the config settings they override.
+var settingFlags = map[string]string{
+	"clipboard":   "clipboard.backend",
+	"pubkey":      "recipient",
+	"privkey":     "private_key",
+	"output-dir":  "output_dir",
+	"encoding":    "payload_encoding",
+	"on-conflict": "conflict_policy",
+	"anonymous":   "anonymous",
+	"type":        "key_type",
+	"bits":        "key_bits",
+	"min-bits":    "min_key_bits",
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "airbridge",
@@ -24,8 +58,169 @@ var rootCmd = &cobra.Command{
 It features a TUI (Terminal User Interface) for easy interaction, allowing you to:
 - Generate and share public keys.
 - Encrypt files with a recipient's public key.
-- Decrypt received payloads using your private key.`,
+- Decrypt received payloads using your private key.
+
+Run it without a command to open the interactive home menu.`,
 	Version: "v0.2.0",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Flags were parsed fine, so errors from here on aren't usage errors
+		cmd.SilenceUsage = true
+
+		if err := loadConfig(cmd); err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+
+		if err := applyTheme(cmd); err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+		if err := tui.Keys.Apply(cfg.Keys); err != nil {
+			return cli.Errorf(cli.ClassConfig, "invalid [keys] in config: %w", err)
+		}
+
+		signKey, err := signingKey(signWith)
+		if err != nil {
+			return err
+		}
+		// Without a cache directory every temporary key is generated, and receives can't be resumed
+		pool, _ := keyPool()
+		sessions, _ := sessionStore()
+		err = cli.Configure(cli.Settings{
+			OutputDir:       cfg.OutputDir,
+			PayloadEncoding: cfg.PayloadEncoding,
+			ConflictPolicy:  cfg.ConflictPolicy,
+			Anonymous:       cfg.Anonymous,
+			KeyBits:         cfg.KeyBits,
+			KeyPool:         pool,
+			Sessions:        sessions,
+			SessionTTL:      cfg.SessionTTL.Duration,
+			MinKeyBits:      cfg.MinKeyBits,
+			SignKey:         signKey,
+			Pins:            contactPins(),
+			Sender:          expectSender,
+		})
+		if err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+		if err := clipboard.Configure(cfg.Clipboard.Backend, cfg.Clipboard.ClearAfter.Duration); err != nil {
+			return cli.Errorf(cli.ClassConfig, "%w", err)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		defer refillKeyPool()
+		if interactiveMode() == ModePlain {
+			if err := cli.RunPlainMenu(cli.NewPrompter(os.Stdin, os.Stdout), progressFunc()); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		// Without a config directory the Keys screen just lists nothing
+		store, _ := keyStore()
+		p := tea.NewProgram(home.InitialModel(store), tea.WithAltScreen())
+		finalModel, err := p.Run()
+		if err != nil {
+			fmt.Printf("Alas, there's been an error: %v", err)
+			os.Exit(1)
+		}
+		handOffClipboardClear()
+		printOutput(finalModel)
+	},
+}
+
+// loadConfig reads the config file and applies the profile, the AIRBRIDGE_*
+// environment variables and the flags on top, in that order.
+func loadConfig(cmd *cobra.Command) error {
+	var err error
+	cfg, err = config.Load()
+	if err != nil {
+		return err
+	}
+
+	profile := os.Getenv(config.EnvPrefix + "PROFILE")
+	if cmd.Flags().Changed("profile") {
+		profile = profileName
+	}
+	if profile != "" {
+		if err := cfg.UseProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.ApplyEnv(); err != nil {
+		return err
+	}
+
+	for flag, name := range settingFlags {
+		if f := cmd.Flags().Lookup(flag); f != nil && f.Changed {
+			if err := cfg.Set(name, f.Value.String()); err != nil {
+				return fmt.Errorf("invalid --%s: %w", flag, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTheme picks the theme from --theme, NO_COLOR or the config file, in that order.
+func applyTheme(cmd *cobra.Command) error {
+	name := cfg.Theme
+	if os.Getenv("NO_COLOR") != "" {
+		name = tui.MonochromeTheme.Name
+	}
+	if cmd.Flags().Changed("theme") {
+		name = themeName
+	}
+
+	themesDir, err := config.ThemesDir()
+	if err != nil {
+		return err
+	}
+	theme, err := tui.LoadTheme(name, themesDir)
+	if err != nil {
+		return err
+	}
+	tui.ApplyTheme(theme)
+	return nil
+}
+
+// interactiveMode returns ModePlain with --plain or when stdout is not a terminal, else ModeTUI.
+func interactiveMode() AppMode {
+	if plain {
+		return ModePlain
+	}
+	fd := os.Stdout.Fd()
+	if !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd) {
+		return ModePlain
+	}
+	return ModeTUI
+}
+
+// stdinIsTerminal reports whether questions can be asked on stdin.
+func stdinIsTerminal() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// progressFunc returns where headless commands report progress, or nil with --quiet.
+// Plain mode gets whole lines, without redrawing them in place.
+func progressFunc() progress.Func {
+	if quiet {
+		return nil
+	}
+	if interactiveMode() == ModePlain {
+		return progress.NewLinePrinter(os.Stderr).Func()
+	}
+	return progress.NewPrinter(os.Stderr).Func()
+}
+
+// printOutput writes whatever the final TUI model asked to print on exit.
+func printOutput(model tea.Model) {
+	if m, ok := model.(interface{ Output() string }); ok {
+		if out := m.Output(); out != "" {
+			fmt.Println(out)
+		}
+	}
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -33,6 +228,25 @@ It features a TUI (Terminal User Interface) for easy interaction, allowing you t
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		// Errors go to stdout as part of the result in JSON format
+		out := os.Stderr
+		if outputFormat == cli.FormatJSON {
+			out = os.Stdout
+		}
+		_ = cli.WriteError(out, outputFormat, err)
+		os.Exit(cli.ClassOf(err).ExitCode())
 	}
 }
+
+func init() {
+	rootCmd.SilenceErrors = true
+	// Screens show the hint for known errors, like the headless commands do
+	tui.DescribeError = cli.Describe
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return cli.Errorf(cli.ClassUsage, "%w", err)
+	})
+	rootCmd.PersistentFlags().StringVar(&clipboardBackend, "clipboard", "", "Clipboard backend: auto, system or osc52 (default from config, else auto)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Config profile to use (default from AIRBRIDGE_PROFILE)")
+	rootCmd.PersistentFlags().BoolVar(&plain, "plain", false, "Use line based prompts instead of the TUI (default when stdout is not a terminal)")
+	rootCmd.PersistentFlags().StringVar(&themeName, "theme", "", "Color theme: dark, light, high-contrast, monochrome or a user theme")
+}
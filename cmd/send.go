@@ -5,6 +5,8 @@ package cmd
 
 import (
 	"AirBridge/internal/cli"
+	"AirBridge/internal/discovery"
+	"AirBridge/internal/statement"
 	"AirBridge/internal/tui/send"
 	"fmt"
 	"os"
@@ -17,6 +19,15 @@ import (
 var pubKeyPath string
 var outputFilePath string
 var headless bool
+var payloadEncoding string
+var (
+	anonymous         bool
+	minKeyBits        int
+	sendTo            string
+	expectFingerprint string
+	refreshKeys       bool
+	signWith          string
+)
 
 var sendCmd = &cobra.Command{
 	Use:   "send [file]",
@@ -27,60 +38,147 @@ and generates the encrypted payload.
 
 You can optionally provide a file path as an argument to skip the file selection step.
 
+Instead of -k, --to fetches the recipient's published key: from an https:// URL,
+a .keys file such as https://git.example/alice.keys, or alice@example.com through
+https://example.com/.well-known/airbridge/alice. The first time a key is seen you
+are asked to confirm its fingerprint (or pass it with --fingerprint), and later
+sends refuse a key that changed.
+
+-k also takes the name of an identity (see "airbridge identity"): the file is
+then encrypted for every device key of that identity that is currently valid.
+
+--sign signs the payload with one of your keys, so the recipient can check it
+came from you (see "airbridge receive --from").
+
+Keys their owner revoked are refused. Keys past the expiry they were given
+(see "keygen --expires") are refused too, unless you confirm on the terminal
+or pass --allow-expired.
+
 Use --headless with -k and -o for headless mode.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+
 		var initialFile string
 		if len(args) > 0 {
 			initialFile = args[0]
 		}
 
+		if sendTo != "" && cmd.Flags().Changed("pubkey") {
+			return cli.Errorf(cli.ClassUsage, "use either --to or -k, not both")
+		}
+		recipientPath, err := resolveKeyPath(cfg.Recipient, false)
+		if err != nil {
+			return err
+		}
 		var initialPubKey string
-		if pubKeyPath != "" {
-			content, err := os.ReadFile(pubKeyPath)
+		var warnings []string
+		var statements []statement.Statement
+		recipient := sendTo
+		if sendTo != "" {
+			found, err := lookupRecipient(sendTo, expectFingerprint, refreshKeys)
+			if err != nil {
+				return err
+			}
+			initialPubKey = found.KeyText
+			statements = found.Statements
+			if found.Source == discovery.SourceStaleCache {
+				warnings = append(warnings, fmt.Sprintf("Warning: could not fetch the key of %s, using the cached copy", sendTo))
+			}
+		} else if isIdentity(cfg.Recipient) {
+			keys, skipped, err := identityRecipient(cfg.Recipient)
 			if err != nil {
-				fmt.Printf("Error reading public key file: %v\n", err)
-				os.Exit(1)
+				return err
+			}
+			initialPubKey = keys
+			warnings = append(warnings, skipped...)
+			recipient = cfg.Recipient
+		} else if recipientPath != "" {
+			if isStoredKey(cfg.Recipient) {
+				if err := checkContact(cfg.Recipient); err != nil {
+					return err
+				}
+			}
+			content, err := os.ReadFile(recipientPath)
+			if err != nil {
+				return cli.Errorf(cli.ClassIO, "error reading public key file: %w", err)
 			}
 			initialPubKey = string(content)
 		}
+		if initialPubKey != "" {
+			label := recipient
+			if label == "" {
+				label = cfg.Recipient
+			}
+			if err := checkKeyStatus(label, initialPubKey, statements); err != nil {
+				return err
+			}
+		}
 
-		var appMode = ModeTUI
-		if headless {
+		var appMode = interactiveMode()
+		if headless || outputFormat == cli.FormatJSON {
 			appMode = ModeCLI
 		}
 
+		if appMode != ModeCLI {
+			for _, warning := range warnings {
+				fmt.Fprintln(os.Stderr, warning)
+			}
+		}
+
 		switch appMode {
 		case ModeCLI:
 			if initialFile == "" {
-				fmt.Println("Error: File argument required in headless mode")
-				os.Exit(1)
+				return cli.Errorf(cli.ClassUsage, "file argument required in headless mode")
 			}
 			if initialPubKey == "" {
-				fmt.Println("Error: Public key (-k) required in headless mode")
-				os.Exit(1)
+				return cli.Errorf(cli.ClassUsage, "public key (-k or --to) required in headless mode")
 			}
 
 			// Headless Execution
-			if err := cli.RunSend(initialFile, initialPubKey, outputFilePath); err != nil {
-				fmt.Printf("Error running headless send: %v\n", err)
-				os.Exit(1)
+			result, err := cli.RunSend(initialFile, initialPubKey, outputFilePath, progressFunc())
+			if err != nil {
+				return fmt.Errorf("error running headless send: %w", err)
+			}
+			result.Recipient = recipient
+			result.Warnings = append(warnings, result.Warnings...)
+			return cli.WriteResult(os.Stdout, outputFormat, result)
+
+		case ModePlain:
+			p := cli.NewPrompter(os.Stdin, os.Stdout)
+			if err := cli.RunPlainSend(p, initialFile, initialPubKey, outputFilePath, progressFunc()); err != nil {
+				return fmt.Errorf("error running send: %w", err)
 			}
 
 		case ModeTUI:
 			p := tea.NewProgram(send.InitialModel(initialFile, initialPubKey, outputFilePath), tea.WithAltScreen())
-			if _, err := p.Run(); err != nil {
-				fmt.Printf("Alas, there's been an error: %v", err)
-				os.Exit(1)
+			finalModel, err := p.Run()
+			if err != nil {
+				return fmt.Errorf("alas, there's been an error: %w", err)
 			}
+			handOffClipboardClear()
+			printOutput(finalModel)
 		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(sendCmd)
-	sendCmd.Flags().StringVarP(&pubKeyPath, "pubkey", "k", "", "Path to recipient's public key file (skips manual paste)")
+	sendCmd.Flags().StringVarP(&pubKeyPath, "pubkey", "k", "", "Recipient's public key file, stored key name or identity (skips manual paste, default from config)")
 	sendCmd.Flags().StringVarP(&outputFilePath, "output", "o", "", "Path to save the payload file (default: payload.abp)")
 	// Make the flag optional (NoOptDefVal) so -o works without an argument
 	sendCmd.Flags().Lookup("output").NoOptDefVal = "payload.abp"
 	sendCmd.Flags().BoolVarP(&headless, "headless", "H", false, "Run in headless mode (requires -k and file arg)")
+	sendCmd.Flags().StringVar(&payloadEncoding, "encoding", "", "Payload encoding: base64 or armor (default from config, else base64)")
+	sendCmd.Flags().StringVar(&sendTo, "to", "", "Fetch the recipient's key: an https:// URL, a .keys URL or user@domain")
+	sendCmd.Flags().StringVar(&expectFingerprint, "fingerprint", "", "Fingerprint the key fetched with --to must have; trusts it without asking")
+	sendCmd.Flags().BoolVar(&refreshKeys, "refresh", false, "Fetch the key for --to again even if it is cached")
+	sendCmd.Flags().IntVar(&minKeyBits, "min-bits", 0, "Smallest recipient key to accept (default from config, else 2048)")
+	sendCmd.Flags().BoolVar(&allowExpired, "allow-expired", false, "Send to a key even if it has expired")
+	sendCmd.Flags().BoolVar(&anonymous, "anonymous", false, "Leave the recipient's key ID out of the payload")
+	sendCmd.Flags().StringVar(&signWith, "sign", "", "Sign the payload with this private key file or stored key name")
+	sendCmd.Flags().StringVar(&outputFormat, "output-format", cli.FormatText, "Result format: text or json (json implies --headless)")
+	sendCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Don't print progress in headless mode")
 }
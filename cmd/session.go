@@ -0,0 +1,113 @@
+/*
+Copyright © 2025 Batuhan Sanli <batuhansanli@gmail.com>
+*/
+package cmd
+
+import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/config"
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/session"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Manage receive sessions that can be resumed.",
+	Long: `Manages receive sessions. When receive generates a temporary key pair, it saves
+the private key, encrypted, under a session ID. If AirBridge is closed before the
+sender's payload arrives, "airbridge receive --resume <id>" decrypts it with
+that key later.
+
+Sessions are off by default, since they keep a private key on disk. Turn them
+on by setting how long a receive can be resumed, e.g. "airbridge config set
+session_ttl 24h". A session ends once it has decrypted a payload, when it is
+deleted (Ctrl+X in the receive screen, or "airbridge session delete"), or when it
+expires after session_ttl. Expired sessions are purged whenever receive starts.`,
+}
+
+var sessionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the receive sessions that can be resumed.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		store, err := sessionStore()
+		if err != nil {
+			return err
+		}
+		sessions, err := store.List()
+		if err != nil {
+			return cli.Errorf(cli.ClassIO, "%w", err)
+		}
+		result := cli.SessionListResult{Sessions: []cli.SessionResult{}}
+		for _, s := range sessions {
+			result.Sessions = append(result.Sessions, sessionResult(s))
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, result)
+	},
+}
+
+var sessionDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Delete a receive session and its key.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cli.CheckFormat(outputFormat); err != nil {
+			return err
+		}
+		store, err := sessionStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(args[0]); err != nil {
+			return err
+		}
+		return cli.WriteResult(os.Stdout, outputFormat, cli.SessionResult{ID: args[0], Status: "deleted"})
+	},
+}
+
+// sessionStore returns the receive sessions in the cache directory, encrypted with
+// the sessions key in the config directory.
+func sessionStore() (*session.Store, error) {
+	dir, err := config.SessionsDir()
+	if err != nil {
+		return nil, cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+	configDir, err := config.Dir()
+	if err != nil {
+		return nil, cli.Errorf(cli.ClassConfig, "%w", err)
+	}
+	return session.New(dir, filepath.Join(configDir, session.KeyFileName)), nil
+}
+
+// sessionKey returns the private key of the receive session with the given ID, as PEM.
+func sessionKey(id string) ([]byte, error) {
+	store, err := sessionStore()
+	if err != nil {
+		return nil, err
+	}
+	_, privateKey, err := store.Open(id)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ExportPrivateKeyAsPEM(privateKey)
+}
+
+func sessionResult(s session.Session) cli.SessionResult {
+	return cli.SessionResult{ID: s.ID, Fingerprint: s.Fingerprint, Created: s.Created, Expires: s.Expires}
+}
+
+func init() {
+	rootCmd.AddCommand(sessionCmd)
+	sessionCmd.AddCommand(sessionListCmd, sessionDeleteCmd)
+
+	for _, c := range []*cobra.Command{sessionListCmd, sessionDeleteCmd} {
+		c.Flags().StringVar(&outputFormat, "output-format", cli.FormatText, "Result format: text or json")
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// ContentKind describes what a pasted or loaded text looks like.
+type ContentKind int
+
+const (
+	ContentEmpty ContentKind = iota
+	ContentUnknown
+	ContentPublicKey
+	ContentPrivateKey
+	ContentPayload
+)
+
+func (k ContentKind) String() string {
+	switch k {
+	case ContentEmpty:
+		return "nothing"
+	case ContentPublicKey:
+		return "a public key"
+	case ContentPrivateKey:
+		return "a private key"
+	case ContentPayload:
+		return "a payload"
+	default:
+		return "unknown content"
+	}
+}
+
+// DetectContent guesses whether text is a public key, a private key or a payload.
+// It only looks at the structure and never decrypts anything.
+func DetectContent(text string) ContentKind {
+	text = Dearmor(text)
+	compact := strings.Join(strings.Fields(text), "")
+	if compact == "" {
+		return ContentEmpty
+	}
+
+	if kind := detectPEM(text); kind != ContentUnknown {
+		return kind
+	}
+	if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "ssh-rsa ") || strings.HasPrefix(trimmed, "ssh-ed25519 ") {
+		return ContentPublicKey
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(compact)
+	if err != nil {
+		return ContentUnknown
+	}
+
+	if kind := detectPEM(string(decoded)); kind != ContentUnknown {
+		return kind
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(decoded, &fields); err != nil {
+		return ContentUnknown
+	}
+	if _, hasData := fields["data"]; !hasData {
+		return ContentUnknown
+	}
+	// Version 1 payloads have one key, version 2 a list of them. Anything newer
+	// is still a payload, so receive can say it is from a newer AirBridge
+	var version int
+	_ = json.Unmarshal(fields["version"], &version)
+	_, hasKey := fields["key"]
+	_, hasKeys := fields["keys"]
+	if hasKey || hasKeys || version >= 2 {
+		return ContentPayload
+	}
+	return ContentUnknown
+}
+
+func detectPEM(text string) ContentKind {
+	switch {
+	case strings.Contains(text, "-----BEGIN PUBLIC KEY-----"):
+		return ContentPublicKey
+	case strings.Contains(text, "PRIVATE KEY-----"):
+		return ContentPrivateKey
+	default:
+		return ContentUnknown
+	}
+}
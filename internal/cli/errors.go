@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/discovery"
+	"AirBridge/internal/identity"
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/session"
+	"AirBridge/internal/statement"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+var (
+	// ErrMalformedPayload is returned for text that isn't a complete payload.
+	ErrMalformedPayload = errors.New("malformed payload")
+	// ErrUnsupportedVersion is returned for payloads made by a newer release.
+	ErrUnsupportedVersion = errors.New("unsupported payload version")
+	// ErrNoMatchingKey is returned when none of the given private keys is the payload's recipient.
+	ErrNoMatchingKey = errors.New("none of your keys match the payload")
+	// ErrFileExists is returned when a received file exists and the conflict policy is ConflictFail.
+	ErrFileExists = errors.New("file already exists")
+	// ErrUnsigned is returned for unsigned payloads when a sender is expected.
+	ErrUnsigned = errors.New("payload is not signed")
+)
+
+// sentinelClasses maps known errors to their class, most specific first.
+var sentinelClasses = []struct {
+	err   error
+	class ErrorClass
+}{
+	{crypto.ErrWrongRecipient, ClassDecryption},
+	{crypto.ErrUnwrapFailed, ClassDecryption},
+	{crypto.ErrAuthenticationFailed, ClassDecryption},
+	{crypto.ErrInvalidKey, ClassKey},
+	{crypto.ErrWeakKey, ClassKey},
+	{ErrNoMatchingKey, ClassDecryption},
+	{ErrMalformedPayload, ClassPayload},
+	{ErrUnsupportedVersion, ClassPayload},
+	{ErrFileExists, ClassConflict},
+	{ErrUnsigned, ClassPayload},
+	{keystore.ErrExists, ClassConflict},
+	{keystore.ErrNotFound, ClassKey},
+	{keystore.ErrNoPrivateKey, ClassKey},
+	{keystore.ErrPrivateKeyStored, ClassConflict},
+	{keystore.ErrKeyChanged, ClassKey},
+	{statement.ErrRevoked, ClassKey},
+	{statement.ErrExpired, ClassKey},
+	{statement.ErrNotYetValid, ClassKey},
+	{statement.ErrInvalid, ClassKey},
+	{identity.ErrNotFound, ClassKey},
+	{identity.ErrBadSignature, ClassKey},
+	{identity.ErrNoDevices, ClassKey},
+	{identity.ErrUnsupportedBundle, ClassKey},
+	{identity.ErrNoKey, ClassKey},
+	{discovery.ErrNoResolver, ClassUsage},
+	{discovery.ErrNoKeys, ClassKey},
+	{discovery.ErrNotTrusted, ClassKey},
+	{session.ErrNotFound, ClassKey},
+	{session.ErrExpired, ClassKey},
+}
+
+// ErrorClass groups errors that need the same kind of fix.
+// Each class has its own exit code, see ExitCode.
+type ErrorClass int
+
+const (
+	ClassGeneral ErrorClass = iota + 1
+	ClassUsage
+	ClassIO
+	ClassKey
+	ClassPayload
+	ClassDecryption
+	ClassConflict
+	ClassConfig
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassUsage:
+		return "usage"
+	case ClassIO:
+		return "io"
+	case ClassKey:
+		return "key"
+	case ClassPayload:
+		return "payload"
+	case ClassDecryption:
+		return "decryption"
+	case ClassConflict:
+		return "conflict"
+	case ClassConfig:
+		return "config"
+	default:
+		return "general"
+	}
+}
+
+// ExitCode returns the process exit code for the class.
+func (c ErrorClass) ExitCode() int {
+	return int(c)
+}
+
+// Error is an error with a class attached.
+type Error struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errorf formats an error like fmt.Errorf and attaches the class to it
+func Errorf(class ErrorClass, format string, args ...any) error {
+	return &Error{Class: class, Err: fmt.Errorf(format, args...)}
+}
+
+// ClassOf returns the class of a known error in err's chain, or else the class
+// of the outermost classified error
+func ClassOf(err error) ErrorClass {
+	for _, sentinel := range sentinelClasses {
+		if errors.Is(err, sentinel.err) {
+			return sentinel.class
+		}
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Class
+	}
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return ClassIO
+	}
+	return ClassGeneral
+}
+
+// Remedy returns a hint on how to fix err, or an empty string if there is none
+func Remedy(err error) string {
+	switch {
+	case errors.Is(err, crypto.ErrWrongRecipient):
+		return "This payload was made for a different key. Ask the sender to encrypt it again with your current public key."
+	case errors.Is(err, crypto.ErrUnwrapFailed):
+		return "The payload doesn't say which key it is for, and this key can't open it. It was made for another key, or damaged on the way. Check with the sender."
+	case errors.Is(err, ErrNoMatchingKey):
+		return "None of your keys can decrypt this payload. Ask the sender which public key they used, or point -k at the right key."
+	case errors.Is(err, crypto.ErrAuthenticationFailed):
+		return "The payload was changed or cut off on the way. Ask the sender to send it again and copy all of it."
+	case errors.Is(err, crypto.ErrInvalidKey):
+		return "This is not a valid key. Check that you picked the right key file and that it is an RSA or Ed25519 key (PEM, OpenSSH or authorized_keys format)."
+	case errors.Is(err, crypto.ErrWeakKey):
+		return "Ask the recipient for a larger key (airbridge keygen --bits 3072), or lower min_key_bits if you trust this one."
+	case errors.Is(err, ErrUnsupportedVersion):
+		return "The payload was made by a newer AirBridge. Update AirBridge to read it."
+	case errors.Is(err, ErrMalformedPayload):
+		return "This isn't a complete AirBridge payload. Make sure you copied all of it."
+	case errors.Is(err, ErrUnsigned):
+		return "Ask the sender to sign it with 'airbridge send --sign <key>', or receive it without --from."
+	case errors.Is(err, ErrFileExists):
+		return "Use --on-conflict rename or overwrite, or pick another --output-dir."
+	case errors.Is(err, keystore.ErrExists):
+		return "Use --force to replace the existing key, or pick another name."
+	case errors.Is(err, keystore.ErrNotFound):
+		return "Run 'airbridge key list' to see the stored keys."
+	case errors.Is(err, keystore.ErrPrivateKeyStored):
+		return "Importing a public key would delete your private key. Import it under another name, or run 'airbridge key delete' first if you really mean to replace the key pair."
+	case errors.Is(err, keystore.ErrNoPrivateKey):
+		return "Only the public key is stored under this name. Import the private key with 'airbridge key import --force'."
+	case errors.Is(err, statement.ErrRevoked):
+		return "Its owner revoked this key, usually because it was lost or stolen. Ask them for their new key."
+	case errors.Is(err, statement.ErrExpired), errors.Is(err, statement.ErrNotYetValid):
+		return "The key is outside the time its owner said it is valid for. Ask them for a new key, or pass --allow-expired to send anyway."
+	case errors.Is(err, statement.ErrInvalid):
+		return "A statement kept with this key wasn't signed by it, or was changed. Ask the key's owner to export it again."
+	case errors.Is(err, identity.ErrNotFound):
+		return "Run 'airbridge identity list' to see the known identities."
+	case errors.Is(err, identity.ErrBadSignature):
+		return "The bundle was changed, or a device key wasn't certified by this identity. Ask its owner to export it again."
+	case errors.Is(err, identity.ErrNoDevices):
+		return "Every device key of this identity has expired. Ask its owner to certify a device again and send you a new bundle."
+	case errors.Is(err, identity.ErrNoKey):
+		return "Only identities you created have an identity key. Create your own with 'airbridge identity create <name>'."
+	case errors.Is(err, identity.ErrUnsupportedBundle):
+		return "The bundle was made by a newer AirBridge. Update AirBridge to read it."
+	case errors.Is(err, discovery.ErrNoResolver):
+		return "Use an https:// URL, a .keys URL such as https://git.example/alice.keys, or user@domain."
+	case errors.Is(err, discovery.ErrNoKeys):
+		return "The recipient hasn't published an RSA or Ed25519 key there. Ask them for their public key."
+	case errors.Is(err, keystore.ErrKeyChanged):
+		return "This key is not the one you trusted before, which can mean someone swapped it. Check the new fingerprint with its owner over another channel, then trust it with 'airbridge key trust <name>' (or --fingerprint for --to)."
+	case errors.Is(err, discovery.ErrNotTrusted):
+		return "Check the fingerprint with the recipient, then confirm it, or pass it with --fingerprint when not running interactively."
+	case errors.Is(err, session.ErrNotFound):
+		return "List the sessions that can be resumed with 'airbridge session list'. A session ends once it has decrypted a payload."
+	case errors.Is(err, session.ErrExpired):
+		return "The session's key was deleted. Run 'airbridge receive' and ask the sender to encrypt for the new key."
+	case errors.Is(err, fs.ErrNotExist):
+		return "Check that the path is correct."
+	case errors.Is(err, fs.ErrPermission):
+		return "Check the file permissions."
+	default:
+		return ""
+	}
+}
+
+// Describe returns a short message for err that says what to do about it.
+// Known AirBridge errors are replaced by their remedy, other errors keep
+// their message
+func Describe(err error) string {
+	hint := Remedy(err)
+	switch {
+	case hint == "":
+		return err.Error()
+	case errors.Is(err, crypto.ErrWrongRecipient),
+		errors.Is(err, crypto.ErrUnwrapFailed),
+		errors.Is(err, crypto.ErrAuthenticationFailed),
+		errors.Is(err, crypto.ErrInvalidKey),
+		errors.Is(err, ErrUnsupportedVersion),
+		errors.Is(err, ErrMalformedPayload):
+		return hint
+	default:
+		return err.Error() + ". " + hint
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestClassOfSentinels(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{crypto.ErrWrongRecipient, ClassDecryption},
+		{crypto.ErrAuthenticationFailed, ClassDecryption},
+		{crypto.ErrInvalidKey, ClassKey},
+		{ErrMalformedPayload, ClassPayload},
+		{ErrUnsupportedVersion, ClassPayload},
+		{ErrFileExists, ClassConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			// The sentinel wins over the class of the error wrapping it
+			err := Errorf(ClassGeneral, "context: %w", tt.err)
+			if got := ClassOf(err); got != tt.expected {
+				t.Errorf("ClassOf() = %v, want %v", got, tt.expected)
+			}
+			if Remedy(err) == "" {
+				t.Error("Expected a remedy")
+			}
+		})
+	}
+
+	missing := fmt.Errorf("could not open file: %w", fs.ErrNotExist)
+	if ClassOf(missing) != ClassIO || Remedy(missing) == "" {
+		t.Errorf("Expected an io error with a remedy, got %v", ClassOf(missing))
+	}
+	if hint := Remedy(errors.New("boom")); hint != "" {
+		t.Errorf("Expected no remedy for unknown errors, got %q", hint)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	wrong := fmt.Errorf("failed to decrypt AES key: %w", crypto.ErrWrongRecipient)
+	if got := Describe(wrong); got != Remedy(wrong) {
+		t.Errorf("Expected the remedy for a wrong key, got %q", got)
+	}
+
+	exists := fmt.Errorf("%w: /tmp/a.txt", ErrFileExists)
+	if got := Describe(exists); !strings.Contains(got, "/tmp/a.txt") || !strings.Contains(got, Remedy(exists)) {
+		t.Errorf("Expected the path and the remedy, got %q", got)
+	}
+
+	if got := Describe(errors.New("boom")); got != "boom" {
+		t.Errorf("Describe() = %q, want %q", got, "boom")
+	}
+}
+
+func TestDecryptPayloadErrors(t *testing.T) {
+	privateKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected error
+	}{
+		{"Not base64", "%%%", ErrMalformedPayload},
+		{"Not JSON", base64.StdEncoding.EncodeToString([]byte("hello")), ErrMalformedPayload},
+		{"Newer version", base64.StdEncoding.EncodeToString([]byte(`{"version":99}`)), ErrUnsupportedVersion},
+		{"Version 2 without keys", base64.StdEncoding.EncodeToString([]byte(`{"version":2,"key":"00"}`)), ErrMalformedPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := DecryptPayload(tt.payload, privateKey, nil)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/pkg"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Payload formats reported by InspectPayload.
+const (
+	PayloadSingle  = "single"
+	PayloadPart    = "part"
+	PayloadUnknown = "unknown"
+)
+
+// gcmTagSize is the size of the authentication tag AES-GCM appends to the ciphertext.
+const gcmTagSize = 16
+
+// InspectResult describes a payload as far as it can be read without decrypting it.
+type InspectResult struct {
+	Format     string   `json:"format"`
+	Version    int      `json:"version,omitempty"`
+	Encoding   string   `json:"encoding"`
+	Recipients []string `json:"recipients"`
+	Signed     bool     `json:"signed"`
+	// SenderFingerprint is the key a signed payload claims to be signed with.
+	SenderFingerprint string `json:"sender_fingerprint,omitempty"`
+	Part              *int   `json:"part,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Size              int64  `json:"size,omitempty"`
+	Hash              string `json:"sha256,omitempty"`
+	CiphertextSize    int    `json:"ciphertext_size"`
+	RecipientKeyType  string `json:"recipient_key_type,omitempty"`
+	RecipientKeyBits  int    `json:"recipient_key_bits,omitempty"`
+	// WrappedKeys is how many keys the file key is wrapped for.
+	WrappedKeys int        `json:"wrapped_keys"`
+	Valid       bool       `json:"valid"`
+	Problems    []string   `json:"problems,omitempty"`
+	Keys        []KeyCheck `json:"keys,omitempty"`
+}
+
+// KeyCheck tells whether a private key can decrypt an inspected payload.
+type KeyCheck struct {
+	Path        string `json:"path"`
+	Fingerprint string `json:"fingerprint"`
+	CanDecrypt  bool   `json:"can_decrypt"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+func (r InspectResult) Lines() []string {
+	format := "not an AirBridge payload"
+	switch r.Format {
+	case PayloadSingle:
+		format = fmt.Sprintf("AirBridge payload, version %d", r.Version)
+	case PayloadPart:
+		format = fmt.Sprintf("AirBridge payload part %d", *r.Part)
+	}
+	recipients := "not recorded (anonymous)"
+	if len(r.Recipients) > 0 {
+		recipients = strings.Join(r.Recipients, ", ")
+	}
+	signed := "no"
+	switch {
+	case r.SenderFingerprint != "":
+		signed = "yes, by " + r.SenderFingerprint
+	case r.Signed:
+		signed = "yes, but the signature is invalid"
+	}
+
+	lines := []string{
+		"Format:      " + format,
+		"Encoding:    " + r.Encoding,
+		"Recipients:  " + recipients,
+		"Signed:      " + signed,
+	}
+	if r.Name != "" {
+		lines = append(lines, fmt.Sprintf("File:        %s (%d bytes)", r.Name, r.Size))
+	}
+	if r.Hash != "" {
+		lines = append(lines, "SHA-256:     "+r.Hash)
+	}
+	if r.Format == PayloadSingle {
+		lines = append(lines, fmt.Sprintf("Ciphertext:  %d bytes", r.CiphertextSize))
+	}
+	switch {
+	case r.WrappedKeys > 1:
+		lines = append(lines, fmt.Sprintf("Key type:    wrapped for %d keys", r.WrappedKeys))
+	case r.RecipientKeyType == crypto.TypeEd25519:
+		lines = append(lines, "Key type:    Ed25519 (X25519 key wrap)")
+	case r.RecipientKeyBits > 0:
+		lines = append(lines, fmt.Sprintf("Key type:    RSA-%d", r.RecipientKeyBits))
+	}
+
+	if r.Valid {
+		lines = append(lines, "Problems:    none")
+	} else {
+		lines = append(lines, "Problems:")
+		for _, problem := range r.Problems {
+			lines = append(lines, "  - "+problem)
+		}
+	}
+
+	for _, check := range r.Keys {
+		verdict := "this key can decrypt it"
+		if !check.CanDecrypt {
+			verdict = "this key can't decrypt it: " + check.Reason
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s): %s", check.Path, check.Fingerprint, verdict))
+	}
+	return lines
+}
+
+// InspectPayload reads the structure of a payload and, if keys are given, checks
+// whether each of them can decrypt it. The plaintext is never written anywhere
+func InspectPayload(text string, keys []KeyFile) InspectResult {
+	r := inspectPayload(text, keys)
+	r.Valid = len(r.Problems) == 0
+	return r
+}
+
+func inspectPayload(text string, keys []KeyFile) InspectResult {
+	r := InspectResult{Format: PayloadUnknown, Encoding: EncodingBase64, Recipients: []string{}}
+	if strings.Contains(text, armorHeader) {
+		r.Encoding = EncodingArmor
+		if !strings.Contains(text, armorFooter) {
+			r.problem("the armor END line is missing, the payload is probably truncated")
+		}
+	}
+
+	compact := compactPayload(text)
+	if compact == "" {
+		r.problem("the payload is empty")
+		return r
+	}
+	decoded, err := base64.StdEncoding.DecodeString(compact)
+	if err != nil {
+		if len(compact)%4 != 0 {
+			r.problem("the base64 text has a length of %d, which isn't a multiple of 4, so the payload is probably truncated", len(compact))
+		} else {
+			r.problem("invalid base64: %v", err)
+		}
+		return r
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(decoded)).Decode(&fields); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			r.problem("the payload ends in the middle, it is probably truncated")
+		} else {
+			r.problem("the decoded payload isn't valid JSON: %v", err)
+		}
+		return r
+	}
+
+	if _, ok := fields["chunk_num"]; ok {
+		var part pkg.LargeFilePayload
+		if err := json.Unmarshal(decoded, &part); err != nil {
+			r.problem("invalid part: %v", err)
+			return r
+		}
+		r.Format = PayloadPart
+		r.Part = &part.ChunkNum
+		r.Name = part.Name
+		r.Hash = part.Hash
+		return r
+	}
+
+	var payload pkg.SmallFilePayload
+	if err := json.Unmarshal(decoded, &payload); err != nil {
+		r.problem("invalid payload fields: %v", err)
+		return r
+	}
+	r.Format = PayloadSingle
+	r.Version = max(payload.Version, 1)
+	r.Name = payload.Metadata.Name
+	r.Size = payload.Metadata.Size
+	r.Hash = payload.Metadata.Hash
+	if payload.Version > pkg.PayloadVersion {
+		r.problem("the payload was made by a newer AirBridge (version %d, this release reads up to %d)", payload.Version, pkg.PayloadVersion)
+	}
+	if payload.Version > pkg.PayloadVersionSingle && len(payload.Keys) == 0 {
+		r.problem("the keys field is missing")
+	}
+
+	if payload.Signature != "" || payload.Sender != "" {
+		r.Signed = true
+		if senderKey, err := payloadSigner(payload); err != nil {
+			r.problem("%s", strings.TrimPrefix(err.Error(), ErrMalformedPayload.Error()+": "))
+		} else {
+			r.SenderFingerprint, _ = crypto.FingerprintPublicKey(senderKey)
+		}
+	}
+
+	wrapped := wrappedKeys(payload)
+	r.WrappedKeys = len(wrapped)
+	encryptedKeys := make([][]byte, len(wrapped))
+	usable := 0
+	for i, key := range wrapped {
+		if key.Recipient != "" {
+			r.Recipients = append(r.Recipients, key.Recipient)
+		}
+		field := "key"
+		if len(wrapped) > 1 {
+			field = fmt.Sprintf("key %d", i+1)
+		}
+		encryptedKeys[i] = r.decodeField(field, key.Key)
+		if encryptedKeys[i] != nil {
+			usable++
+		}
+		switch {
+		case encryptedKeys[i] == nil:
+		case key.KeyType == pkg.KeyTypeX25519:
+			r.RecipientKeyType = crypto.TypeEd25519
+		case key.KeyType != "":
+			r.problem("the %s is wrapped with %q, which this release doesn't know", field, key.KeyType)
+		default:
+			r.RecipientKeyType = crypto.TypeRSA
+			r.RecipientKeyBits = len(encryptedKeys[i]) * 8
+		}
+	}
+	if len(wrapped) > 1 {
+		r.RecipientKeyType, r.RecipientKeyBits = "", 0
+	}
+	nonce := r.decodeField("nonce", payload.Nonce)
+	if nonce != nil && len(nonce) != 12 {
+		r.problem("the nonce is %d bytes long, expected 12", len(nonce))
+		nonce = nil
+	}
+	data := r.decodeField("data", payload.Data)
+	r.CiphertextSize = len(data)
+	switch {
+	case data == nil:
+	case len(data) < gcmTagSize:
+		r.problem("the ciphertext is %d bytes long, too short to hold the authentication tag", len(data))
+	case payload.Metadata.Size > 0 && int64(len(data)-gcmTagSize) != payload.Metadata.Size:
+		r.problem("the ciphertext holds %d bytes but the file is %d bytes, the payload is probably truncated", len(data)-gcmTagSize, payload.Metadata.Size)
+	}
+
+	if usable == 0 {
+		return r
+	}
+	for _, key := range keys {
+		check := KeyCheck{Path: key.Path, Fingerprint: key.Fingerprint}
+		aesKey, err := unwrapAny(key.Key, wrapped, encryptedKeys)
+		switch {
+		case errors.Is(err, crypto.ErrWrongRecipient):
+			check.Reason = "the payload was made for a different key"
+		case errors.Is(err, ErrMalformedPayload):
+			check.Reason = "the payload is for this key, but its wrapped key is damaged"
+		case err != nil:
+			check.Reason = "the payload was made for a different key, or is damaged"
+		case nonce == nil || data == nil:
+			check.Reason = "the key matches, but the payload is damaged"
+		default:
+			if _, err := crypto.DecryptDataAES(aesKey, nonce, data); err != nil {
+				check.Reason = "the key matches, but the data was changed or damaged"
+			} else {
+				check.CanDecrypt = true
+			}
+		}
+		r.Keys = append(r.Keys, check)
+	}
+	return r
+}
+
+// unwrapAny returns the file key from the first of the wrapped keys privateKey can unwrap.
+// Wrapped keys naming another recipient are skipped, and a wrapped key naming this
+// one that can't be unwrapped is reported as ErrMalformedPayload
+func unwrapAny(privateKey crypto.PrivateKey, wrapped []pkg.WrappedKey, encryptedKeys [][]byte) ([]byte, error) {
+	fingerprint, err := crypto.FingerprintPublicKey(crypto.PublicKeyOf(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	id := crypto.KeyID(fingerprint)
+	err = crypto.ErrWrongRecipient
+	for i, encryptedKey := range encryptedKeys {
+		if encryptedKey == nil || (wrapped[i].Recipient != "" && wrapped[i].Recipient != id) {
+			continue
+		}
+		aesKey, unwrapErr := crypto.UnwrapKey(privateKey, encryptedKey)
+		if unwrapErr == nil {
+			return aesKey, nil
+		}
+		if wrapped[i].Recipient == id {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedPayload, unwrapErr)
+		}
+		err = unwrapErr
+	}
+	return nil, err
+}
+
+// decodeField decodes a hex field of the payload, recording a problem if it is missing or invalid.
+func (r *InspectResult) decodeField(name, value string) []byte {
+	if value == "" {
+		r.problem("the %s field is missing", name)
+		return nil
+	}
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		r.problem("the %s field isn't valid hex: %v", name, err)
+		return nil
+	}
+	return decoded
+}
+
+func (r *InspectResult) problem(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
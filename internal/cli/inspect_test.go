@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/pkg"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encryptForTest returns a payload of content encrypted for publicKey.
+func encryptForTest(t *testing.T, content string, publicKey crypto.PublicKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, err := crypto.ExportPublicKeyAsPEM(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _, err := EncryptPath(path, string(pubPEM), nil)
+	if err != nil {
+		t.Fatalf("EncryptPath failed: %v", err)
+	}
+	return payload
+}
+
+func TestInspectPayload(t *testing.T) {
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := encryptForTest(t, "inspect me", pubKey)
+
+	keys := []KeyFile{{Path: "mine.pem", Key: privKey}, {Path: "other.pem", Key: otherKey}}
+	r := InspectPayload(payload, keys)
+	if !r.Valid || r.Format != PayloadSingle || r.Version != 1 || r.Encoding != EncodingBase64 {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	if r.Name != "secret.txt" || r.Size != 10 || r.CiphertextSize != 10+gcmTagSize || r.RecipientKeyBits != 2048 {
+		t.Errorf("Unexpected details %+v", r)
+	}
+	if len(r.Keys) != 2 || !r.Keys[0].CanDecrypt || r.Keys[1].CanDecrypt {
+		t.Errorf("Expected only the first key to decrypt, got %+v", r.Keys)
+	}
+}
+
+func TestInspectPayloadProblems(t *testing.T) {
+	_, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := encryptForTest(t, strings.Repeat("data", 100), pubKey)
+	decoded, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		problem string
+	}{
+		{"Empty", "  \n", "empty"},
+		{"Bad base64", "!!!!", "invalid base64"},
+		{"Cut base64", payload[:len(payload)-3], "multiple of 4"},
+		{"Cut JSON", base64.StdEncoding.EncodeToString(decoded[:len(decoded)/2]), "truncated"},
+		{"Missing armor end", armorHeader + "\n" + payload + "\n", "END line"},
+		{"Wrong nonce", base64.StdEncoding.EncodeToString([]byte(`{"key":"00","nonce":"0011","data":"00"}`)), "nonce is 2 bytes"},
+		{"Newer version", base64.StdEncoding.EncodeToString([]byte(`{"version":99,"key":"00","nonce":"000000000000000000000000","data":"00"}`)), "newer AirBridge"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := InspectPayload(tt.payload, nil)
+			if r.Valid {
+				t.Fatal("Expected the payload to be invalid")
+			}
+			if !strings.Contains(strings.Join(r.Problems, "\n"), tt.problem) {
+				t.Errorf("Expected a problem mentioning %q, got %q", tt.problem, r.Problems)
+			}
+		})
+	}
+}
+
+func TestLoadPrivateKeys(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.pem", "b.pem"} {
+		key, _, err := crypto.GenerateRSAKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pemBytes, _ := crypto.ExportRSAPrivateKeyAsPEM(key)
+		if err := os.WriteFile(filepath.Join(dir, name), pemBytes, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadPrivateKeys(dir)
+	if err != nil {
+		t.Fatalf("LoadPrivateKeys failed: %v", err)
+	}
+	if len(keys) != 2 || filepath.Base(keys[0].Path) != "a.pem" || keys[0].Fingerprint == "" {
+		t.Errorf("Unexpected keys %+v", keys)
+	}
+
+	single, err := LoadPrivateKeys(filepath.Join(dir, "b.pem"))
+	if err != nil || len(single) != 1 {
+		t.Errorf("Expected one key, got %d (%v)", len(single), err)
+	}
+
+	if _, err := LoadPrivateKeys(t.TempDir()); ClassOf(err) != ClassKey {
+		t.Errorf("Expected a key error for an empty directory, got %v", err)
+	}
+}
+
+func TestSelectKey(t *testing.T) {
+	var keys []KeyFile
+	for _, name := range []string{"a.pem", "b.pem"} {
+		key, _, err := crypto.GenerateRSAKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprint, _ := crypto.FingerprintRSAPublicKey(&key.PublicKey)
+		keys = append(keys, KeyFile{Path: name, Key: key, Fingerprint: fingerprint})
+	}
+	_, strangerKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	named := encryptForTest(t, "named", crypto.PublicKeyOf(keys[1].Key))
+	if r := InspectPayload(named, nil); len(r.Recipients) != 1 || r.Recipients[0] != crypto.KeyID(keys[1].Fingerprint) {
+		t.Errorf("Expected the recipient key ID in the payload, got %v", r.Recipients)
+	}
+	if key, err := SelectKey(named, keys); err != nil || key.Path != "b.pem" {
+		t.Errorf("Expected b.pem, got %q (%v)", key.Path, err)
+	}
+	if _, _, err := DecryptPayload(named, keys[0].Key, nil); !errors.Is(err, crypto.ErrWrongRecipient) {
+		t.Errorf("Expected ErrWrongRecipient for the other key, got %v", err)
+	}
+
+	withSettings(t, Settings{PayloadEncoding: EncodingBase64, ConflictPolicy: ConflictOverwrite, Anonymous: true})
+	anonymous := encryptForTest(t, "anonymous", crypto.PublicKeyOf(keys[1].Key))
+	if r := InspectPayload(anonymous, nil); len(r.Recipients) != 0 {
+		t.Errorf("Expected no recipient in an anonymous payload, got %v", r.Recipients)
+	}
+	if key, err := SelectKey(anonymous, keys); err != nil || key.Path != "b.pem" {
+		t.Errorf("Expected b.pem by trying the keys, got %q (%v)", key.Path, err)
+	}
+
+	stranger := encryptForTest(t, "stranger", strangerKey)
+	if _, err := SelectKey(stranger, keys); !errors.Is(err, ErrNoMatchingKey) || ClassOf(err) != ClassDecryption {
+		t.Errorf("Expected ErrNoMatchingKey, got %v", err)
+	}
+}
+
+func TestMultiRecipientPayload(t *testing.T) {
+	withSettings(t, Settings{PayloadEncoding: EncodingBase64, ConflictPolicy: ConflictOverwrite, OutputDir: t.TempDir()})
+	rsaKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strangerKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(path, []byte("for both"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM, _ := crypto.ExportPublicKeyAsPEM(&rsaKey.PublicKey)
+	edPEM, _ := crypto.ExportPublicKeyAsPEM(edPublic)
+	payload, _, err := EncryptPath(path, string(rsaPEM)+string(edPEM), nil)
+	if err != nil {
+		t.Fatalf("EncryptPath failed: %v", err)
+	}
+
+	r := InspectPayload(payload, nil)
+	if !r.Valid || r.Version != pkg.PayloadVersion || r.WrappedKeys != 2 || len(r.Recipients) != 2 {
+		t.Errorf("Unexpected inspect result %+v", r)
+	}
+	for _, key := range []crypto.PrivateKey{rsaKey, edKey} {
+		if _, _, err := DecryptPayload(payload, key, nil); err != nil {
+			t.Errorf("Expected %s key to decrypt, got %v", crypto.KeyType(crypto.PublicKeyOf(key)), err)
+		}
+	}
+	if _, _, err := DecryptPayload(payload, strangerKey, nil); !errors.Is(err, crypto.ErrWrongRecipient) {
+		t.Errorf("Expected ErrWrongRecipient for another key, got %v", err)
+	}
+
+	fingerprint, _ := crypto.FingerprintPublicKey(edPublic)
+	keys := []KeyFile{{Path: "ed.pem", Key: edKey, Fingerprint: fingerprint}}
+	if key, err := SelectKey(payload, keys); err != nil || key.Path != "ed.pem" {
+		t.Errorf("Expected ed.pem, got %q (%v)", key.Path, err)
+	}
+	if r := InspectPayload(payload, keys); len(r.Keys) != 1 || !r.Keys[0].CanDecrypt {
+		t.Errorf("Expected inspect to find the Ed25519 key can decrypt, got %+v", r.Keys)
+	}
+}
+
+func TestDamagedWrappedKey(t *testing.T) {
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encryptForTest(t, "damaged", pubKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload pkg.SmallFilePayload
+	if err := json.Unmarshal(decoded, &payload); err != nil {
+		t.Fatal(err)
+	}
+	// Flip the first hex digit of the wrapped key
+	flipped := "0"
+	if payload.Key[0] == '0' {
+		flipped = "1"
+	}
+	payload.Key = flipped + payload.Key[1:]
+	encoded, _ := json.Marshal(payload)
+	damaged := base64.StdEncoding.EncodeToString(encoded)
+
+	// The payload names this key, so a key that won't unwrap means damage, not another recipient
+	_, _, err = DecryptPayload(damaged, privKey, nil)
+	if errors.Is(err, crypto.ErrWrongRecipient) || !errors.Is(err, ErrMalformedPayload) || ClassOf(err) != ClassPayload {
+		t.Errorf("Expected a damaged payload error, got %v", err)
+	}
+	r := InspectPayload(damaged, []KeyFile{{Path: "mine.pem", Key: privKey}})
+	if len(r.Keys) != 1 || r.Keys[0].CanDecrypt || !strings.Contains(r.Keys[0].Reason, "damaged") {
+		t.Errorf("Expected inspect to report damage, got %+v", r.Keys)
+	}
+
+	// Anonymous payloads can't tell the two apart
+	withSettings(t, Settings{PayloadEncoding: EncodingBase64, ConflictPolicy: ConflictOverwrite, Anonymous: true})
+	otherKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	anonymous := encryptForTest(t, "anonymous", pubKey)
+	if _, _, err := DecryptPayload(anonymous, otherKey, nil); !errors.Is(err, crypto.ErrUnwrapFailed) || ClassOf(err) != ClassDecryption {
+		t.Errorf("Expected ErrUnwrapFailed for an anonymous payload, got %v", err)
+	}
+}
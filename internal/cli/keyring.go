@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeyFile is a private key read from disk.
+type KeyFile struct {
+	Path        string
+	Key         crypto.PrivateKey
+	Fingerprint string
+}
+
+// LoadPrivateKeys reads the private key at path, or every private key in path if it is a directory.
+// Files in a directory that aren't private keys are skipped
+func LoadPrivateKeys(path string) ([]KeyFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, Errorf(ClassIO, "error reading private key: %w", err)
+	}
+	if !info.IsDir() {
+		key, err := loadPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return []KeyFile{key}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, Errorf(ClassIO, "error reading key directory: %w", err)
+	}
+	var keys []KeyFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		key, err := loadPrivateKey(filepath.Join(path, entry.Name()))
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, Errorf(ClassKey, "no private keys found in %s", path)
+	}
+	return keys, nil
+}
+
+// SelectKey returns a key the payload is encrypted for. Anonymous payloads
+// don't name their recipients, so each key is tried on them in turn
+func SelectKey(payloadStr string, keys []KeyFile) (KeyFile, error) {
+	payload, err := decodePayload(compactPayload(payloadStr), nil)
+	if err != nil {
+		return KeyFile{}, err
+	}
+
+	var named []string
+	for _, wrapped := range wrappedKeys(payload) {
+		if wrapped.Recipient == "" {
+			continue
+		}
+		named = append(named, wrapped.Recipient)
+		for _, key := range keys {
+			if crypto.KeyID(key.Fingerprint) == wrapped.Recipient {
+				return key, nil
+			}
+		}
+	}
+	if len(named) > 0 {
+		return KeyFile{}, fmt.Errorf("%w: the payload is for key %s, tried %d keys", ErrNoMatchingKey, strings.Join(named, ", "), len(keys))
+	}
+
+	for _, key := range keys {
+		if _, err := unwrapPayloadKey(payload, key.Key); err == nil {
+			return key, nil
+		}
+	}
+	return KeyFile{}, fmt.Errorf("%w: the payload is anonymous, tried %d keys", ErrNoMatchingKey, len(keys))
+}
+
+func loadPrivateKey(path string) (KeyFile, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return KeyFile{}, Errorf(ClassIO, "error reading private key file: %w", err)
+	}
+	key, err := crypto.DecodePrivateKey(pemBytes)
+	if err != nil {
+		return KeyFile{}, Errorf(ClassKey, "error decoding private key %s: %w", path, err)
+	}
+	fingerprint, err := crypto.FingerprintPublicKey(crypto.PublicKeyOf(key))
+	if err != nil {
+		return KeyFile{}, Errorf(ClassKey, "error reading private key %s: %w", path, err)
+	}
+	return KeyFile{Path: path, Key: key, Fingerprint: fingerprint}, nil
+}
@@ -0,0 +1,430 @@
+package cli
+
+import (
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/statement"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Output formats of the headless commands.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Result is what a headless command reports once it is done.
+type Result interface {
+	// Lines returns the result as the lines printed in text format.
+	Lines() []string
+}
+
+// SendResult describes a payload created by send.
+type SendResult struct {
+	File                 string `json:"file"`
+	Size                 int64  `json:"size"`
+	Hash                 string `json:"sha256"`
+	Payload              string `json:"payload"`
+	Encoding             string `json:"encoding"`
+	Recipient            string `json:"recipient,omitempty"`
+	RecipientFingerprint string `json:"recipient_fingerprint"`
+	// RecipientFingerprints lists every key the payload is encrypted for, if there are several.
+	RecipientFingerprints []string `json:"recipient_fingerprints,omitempty"`
+	// SignerFingerprint is the key the payload is signed with, empty if it isn't signed.
+	SignerFingerprint string   `json:"signer_fingerprint,omitempty"`
+	Warnings          []string `json:"warnings,omitempty"`
+}
+
+func (r SendResult) Lines() []string {
+	lines := append([]string{}, r.Warnings...)
+	return append(lines, fmt.Sprintf("Payload saved to %s", r.Payload))
+}
+
+// ReceiveResult describes a file saved by receive.
+type ReceiveResult struct {
+	File           string `json:"file"`
+	Size           int64  `json:"size"`
+	Hash           string `json:"sha256"`
+	Payload        string `json:"payload,omitempty"`
+	PayloadDeleted bool   `json:"payload_deleted"`
+	KeyFingerprint string `json:"key_fingerprint"`
+	// Sender is the contact that signed the payload, empty for unsigned payloads
+	// and for keys no contact is pinned to. SenderFingerprint is set for both.
+	Sender            string   `json:"sender,omitempty"`
+	SenderFingerprint string   `json:"sender_fingerprint,omitempty"`
+	Warnings          []string `json:"warnings,omitempty"`
+}
+
+func (r ReceiveResult) Lines() []string {
+	lines := []string{fmt.Sprintf("File saved successfully: %s", r.File)}
+	if r.Sender != "" {
+		lines = append(lines, fmt.Sprintf("Signed by %s (%s)", r.Sender, r.SenderFingerprint))
+	} else if r.SenderFingerprint != "" {
+		lines = append(lines, fmt.Sprintf("Signed by an unverified key (%s)", r.SenderFingerprint))
+	}
+	lines = append(lines, r.Warnings...)
+	if r.PayloadDeleted {
+		lines = append(lines, "Payload file deleted.")
+	}
+	return lines
+}
+
+// KeygenResult describes a key pair written by keygen.
+type KeygenResult struct {
+	Name        string `json:"name,omitempty"`
+	Type        string `json:"type"`
+	Bits        int    `json:"bits"`
+	PrivateKey  string `json:"private_key"`
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+	// NotAfter is when the key expires, if it was given a validity.
+	NotAfter time.Time `json:"not_after,omitzero"`
+	Warnings []string  `json:"warnings,omitempty"`
+}
+
+func (r KeygenResult) Lines() []string {
+	lines := append([]string{}, r.Warnings...)
+	lines = append(lines,
+		fmt.Sprintf("Private key saved to: %s", r.PrivateKey),
+		fmt.Sprintf("Public key saved to: %s", r.PublicKey),
+	)
+	if !r.NotAfter.IsZero() {
+		lines = append(lines, fmt.Sprintf("Valid until: %s", r.NotAfter.Local().Format("2006-01-02 15:04")))
+	}
+	return lines
+}
+
+// KeyResult describes an identity in the key store.
+type KeyResult struct {
+	keystore.Identity
+	// Status says what was done to the key, e.g. "imported", and is only printed in text format.
+	Status   string   `json:"-"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (r KeyResult) Lines() []string {
+	lines := append([]string{}, r.Warnings...)
+	if r.Status != "" {
+		lines = append(lines, fmt.Sprintf("Key %s %s.", r.Name, r.Status))
+	}
+	kind := "public key only"
+	if r.Private {
+		kind = "key pair"
+	}
+	lines = append(lines,
+		"Name:        "+r.Name,
+		fmt.Sprintf("Type:        %s-%d (%s)", strings.ToUpper(r.Type), r.Bits, kind),
+		"Fingerprint: "+r.Fingerprint,
+		"Created:     "+r.Created.Local().Format("2006-01-02 15:04:05"),
+	)
+	if !r.NotAfter.IsZero() {
+		lines = append(lines, fmt.Sprintf("Valid:       %s to %s", r.NotBefore.Local().Format("2006-01-02 15:04"), r.NotAfter.Local().Format("2006-01-02 15:04")))
+	}
+	if !r.Revoked.IsZero() {
+		revoked := "Revoked:     " + r.Revoked.Local().Format("2006-01-02 15:04")
+		if r.RevocationReason != "" {
+			revoked += " (" + r.RevocationReason + ")"
+		}
+		lines = append(lines, revoked)
+	}
+	return lines
+}
+
+// KeyListResult lists the identities in the key store.
+type KeyListResult struct {
+	Keys []keystore.Identity `json:"keys"`
+}
+
+func (r KeyListResult) Lines() []string {
+	if len(r.Keys) == 0 {
+		return []string{"No keys yet. Create one with 'airbridge keygen --name <name>'."}
+	}
+	lines := []string{fmt.Sprintf("%-16s %-10s %-8s %-51s %-10s %s", "NAME", "TYPE", "PRIVATE", "FINGERPRINT", "CREATED", "STATUS")}
+	for _, identity := range r.Keys {
+		private := "no"
+		if identity.Private {
+			private = "yes"
+		}
+		lines = append(lines, fmt.Sprintf("%-16s %-10s %-8s %-51s %-10s %s",
+			identity.Name,
+			fmt.Sprintf("%s-%d", strings.ToUpper(identity.Type), identity.Bits),
+			private,
+			identity.Fingerprint,
+			identity.Created.Local().Format("2006-01-02"),
+			keyStatus(identity),
+		))
+	}
+	return lines
+}
+
+// keyStatus returns whether a stored key may be used now, for key listings
+func keyStatus(identity keystore.Identity) string {
+	err := identity.Status().Check(time.Now())
+	switch {
+	case errors.Is(err, statement.ErrRevoked):
+		return "revoked"
+	case errors.Is(err, statement.ErrExpired):
+		return "expired"
+	case errors.Is(err, statement.ErrNotYetValid):
+		return "not yet valid"
+	case !identity.NotAfter.IsZero():
+		return "until " + identity.NotAfter.Local().Format("2006-01-02")
+	default:
+		return "-"
+	}
+}
+
+// KeyPoolResult describes the pool of temporary keys.
+type KeyPoolResult struct {
+	// Keys is how many keys of Bits the pool holds, and Size how many it is refilled to.
+	Keys  int `json:"keys"`
+	Bits  int `json:"bits"`
+	Size  int `json:"size"`
+	Added int `json:"added,omitempty"`
+	// Removed is set by clear.
+	Removed int `json:"removed,omitempty"`
+}
+
+func (r KeyPoolResult) Lines() []string {
+	var lines []string
+	if r.Added > 0 {
+		lines = append(lines, fmt.Sprintf("Generated %d keys.", r.Added))
+	}
+	if r.Removed > 0 {
+		lines = append(lines, fmt.Sprintf("Removed %d keys.", r.Removed))
+	}
+	lines = append(lines, fmt.Sprintf("The key pool holds %d RSA-%d keys.", r.Keys, r.Bits))
+	if r.Size == 0 {
+		lines = append(lines, "Refilling after receive is off; set key_pool to turn it on.")
+	} else {
+		lines = append(lines, fmt.Sprintf("It is refilled to %d keys after receive.", r.Size))
+	}
+	return lines
+}
+
+// SessionResult describes a receive session that can be resumed.
+type SessionResult struct {
+	ID          string    `json:"id"`
+	Fingerprint string    `json:"fingerprint"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+	// Status says what was done to the session, e.g. "deleted", and is only printed in text format.
+	Status string `json:"-"`
+}
+
+func (r SessionResult) Lines() []string {
+	if r.Status != "" {
+		return []string{fmt.Sprintf("Session %s %s.", r.ID, r.Status)}
+	}
+	return []string{
+		"Session:     " + r.ID,
+		"Fingerprint: " + r.Fingerprint,
+		"Created:     " + r.Created.Local().Format("2006-01-02 15:04:05"),
+		"Expires:     " + r.Expires.Local().Format("2006-01-02 15:04:05"),
+		"Resume it with 'airbridge receive --resume " + r.ID + "'.",
+	}
+}
+
+// SessionListResult lists the receive sessions that can be resumed.
+type SessionListResult struct {
+	Sessions []SessionResult `json:"sessions"`
+}
+
+func (r SessionListResult) Lines() []string {
+	if len(r.Sessions) == 0 {
+		return []string{"No receive sessions to resume."}
+	}
+	lines := []string{fmt.Sprintf("%-10s %-51s %-16s %s", "ID", "FINGERPRINT", "CREATED", "EXPIRES")}
+	for _, session := range r.Sessions {
+		lines = append(lines, fmt.Sprintf("%-10s %-51s %-16s %s",
+			session.ID,
+			session.Fingerprint,
+			session.Created.Local().Format("2006-01-02 15:04"),
+			session.Expires.Local().Format("2006-01-02 15:04"),
+		))
+	}
+	return lines
+}
+
+// WatchEvent reports a payload handled by receive --watch.
+type WatchEvent struct {
+	Time    time.Time `json:"time"`
+	Payload string    `json:"payload"`
+	// Status is the subdirectory the payload was moved to: "done" or "failed".
+	// A payload that couldn't be moved is "failed", and MovedTo is empty.
+	Status  string `json:"status"`
+	MovedTo string `json:"moved_to"`
+	// Result is set for decrypted payloads, Error and Class for the others and
+	// for payloads that couldn't be moved.
+	Result *ReceiveResult `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+	Class  string         `json:"class,omitempty"`
+}
+
+func (e WatchEvent) Lines() []string {
+	line := fmt.Sprintf("%s %-6s %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Status, e.Payload)
+	if e.Result != nil {
+		line += fmt.Sprintf(": saved %s (%d bytes, sha256 %s)", e.Result.File, e.Result.Size, e.Result.Hash)
+		for _, warning := range e.Result.Warnings {
+			line += "; " + warning
+		}
+		if e.Error != "" {
+			line += "; " + e.Error
+		}
+	} else {
+		line += ": " + e.Error
+	}
+	return []string{line}
+}
+
+// IdentityResult describes an identity and its certified device keys.
+type IdentityResult struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	// Own is set for identities whose identity key is in the identity store.
+	Own     bool           `json:"own"`
+	Devices []DeviceResult `json:"devices"`
+	// Status says what was done to the identity, e.g. "imported", and is only printed in text format.
+	Status   string   `json:"-"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// DeviceResult describes a device key certified by an identity.
+type DeviceResult struct {
+	Device      string    `json:"device"`
+	Type        string    `json:"type"`
+	Bits        int       `json:"bits"`
+	Fingerprint string    `json:"fingerprint"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	Valid       bool      `json:"valid"`
+}
+
+func (r IdentityResult) Lines() []string {
+	lines := append([]string{}, r.Warnings...)
+	if r.Status != "" {
+		lines = append(lines, fmt.Sprintf("Identity %s %s.", r.Name, r.Status))
+	}
+	kind := "imported"
+	if r.Own {
+		kind = "yours"
+	}
+	lines = append(lines,
+		"Name:        "+r.Name+" ("+kind+")",
+		"Fingerprint: "+r.Fingerprint,
+	)
+	if len(r.Devices) == 0 {
+		return append(lines, "Devices:     none yet, add one with 'airbridge identity certify'")
+	}
+	lines = append(lines, "Devices:")
+	for _, device := range r.Devices {
+		validity := "valid until " + device.NotAfter.Local().Format("2006-01-02 15:04")
+		if !device.Valid {
+			validity = "not valid now (" + device.NotBefore.Local().Format("2006-01-02") + " to " + device.NotAfter.Local().Format("2006-01-02") + ")"
+		}
+		lines = append(lines, fmt.Sprintf("  %-14s %-12s %s  %s",
+			device.Device,
+			fmt.Sprintf("%s-%d", strings.ToUpper(device.Type), device.Bits),
+			device.Fingerprint,
+			validity,
+		))
+	}
+	return lines
+}
+
+// IdentityListResult lists the known identities.
+type IdentityListResult struct {
+	Identities []IdentityResult `json:"identities"`
+}
+
+func (r IdentityListResult) Lines() []string {
+	if len(r.Identities) == 0 {
+		return []string{"No identities yet. Create one with 'airbridge identity create <name>'."}
+	}
+	lines := []string{fmt.Sprintf("%-16s %-6s %-8s %s", "NAME", "OWN", "DEVICES", "FINGERPRINT")}
+	for _, identity := range r.Identities {
+		own := "no"
+		if identity.Own {
+			own = "yes"
+		}
+		valid := 0
+		for _, device := range identity.Devices {
+			if device.Valid {
+				valid++
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%-16s %-6s %-8s %s", identity.Name, own, fmt.Sprintf("%d/%d", valid, len(identity.Devices)), identity.Fingerprint))
+	}
+	return lines
+}
+
+type jsonError struct {
+	Class    string `json:"class"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+type jsonOutput struct {
+	OK     bool       `json:"ok"`
+	Result Result     `json:"result,omitempty"`
+	Error  *jsonError `json:"error,omitempty"`
+}
+
+// CheckFormat returns an error for output formats other than text and json
+func CheckFormat(format string) error {
+	switch format {
+	case FormatText, FormatJSON:
+		return nil
+	default:
+		return Errorf(ClassUsage, "unknown output format %q (expected %s or %s)", format, FormatText, FormatJSON)
+	}
+}
+
+// WriteResult prints the result in the given format
+func WriteResult(w io.Writer, format string, result Result) error {
+	if format == FormatJSON {
+		return writeJSON(w, jsonOutput{OK: true, Result: result})
+	}
+	_, err := fmt.Fprintln(w, strings.Join(result.Lines(), "\n"))
+	return err
+}
+
+// WriteEvent prints one event of a long-running command in the given format.
+// JSON events are written one per line, so they can be read as they come
+func WriteEvent(w io.Writer, format string, event Result) error {
+	if format == FormatJSON {
+		return json.NewEncoder(w).Encode(event)
+	}
+	_, err := fmt.Fprintln(w, strings.Join(event.Lines(), "\n"))
+	return err
+}
+
+// WriteError prints the error in the given format
+func WriteError(w io.Writer, format string, err error) error {
+	class := ClassOf(err)
+	if format == FormatJSON {
+		return writeJSON(w, jsonOutput{Error: &jsonError{
+			Class:    class.String(),
+			Message:  err.Error(),
+			Hint:     Remedy(err),
+			ExitCode: class.ExitCode(),
+		}})
+	}
+	text := fmt.Sprintf("Error: %v\n", err)
+	if hint := Remedy(err); hint != "" {
+		text += hint + "\n"
+	}
+	_, writeErr := fmt.Fprint(w, text)
+	return writeErr
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
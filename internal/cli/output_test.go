@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteResult(t *testing.T) {
+	result := SendResult{File: "a.txt", Size: 3, Payload: "payload.abp", Warnings: []string{"Warning: careful"}}
+
+	var text bytes.Buffer
+	if err := WriteResult(&text, FormatText, result); err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "Warning: careful\nPayload saved to payload.abp\n" {
+		t.Errorf("Unexpected text output %q", text.String())
+	}
+
+	var out bytes.Buffer
+	if err := WriteResult(&out, FormatJSON, result); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		OK     bool       `json:"ok"`
+		Result SendResult `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", out.String(), err)
+	}
+	if !decoded.OK || decoded.Result.Payload != "payload.abp" || decoded.Result.Size != 3 {
+		t.Errorf("Unexpected JSON result %+v", decoded)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	err := fmt.Errorf("error running headless receive: %w", Errorf(ClassDecryption, "failed to decrypt AES key"))
+
+	var out bytes.Buffer
+	if writeErr := WriteError(&out, FormatJSON, err); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	var decoded struct {
+		OK    bool `json:"ok"`
+		Error struct {
+			Class    string `json:"class"`
+			Message  string `json:"message"`
+			ExitCode int    `json:"exit_code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", out.String(), err)
+	}
+	if decoded.OK || decoded.Error.Class != "decryption" || decoded.Error.ExitCode != 6 {
+		t.Errorf("Unexpected JSON error %+v", decoded)
+	}
+	if !strings.Contains(decoded.Error.Message, "failed to decrypt AES key") {
+		t.Errorf("Expected the full message, got %q", decoded.Error.Message)
+	}
+}
+
+func TestClassOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorClass
+	}{
+		{"Unclassified", errors.New("boom"), ClassGeneral},
+		{"Classified", Errorf(ClassKey, "bad key"), ClassKey},
+		{"Wrapped", fmt.Errorf("context: %w", Errorf(ClassIO, "disk full")), ClassIO},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassOf(tt.err); got != tt.expected {
+				t.Errorf("ClassOf() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	if err := CheckFormat(FormatJSON); err != nil {
+		t.Errorf("Expected json to be accepted: %v", err)
+	}
+	if err := CheckFormat("yaml"); ClassOf(err) != ClassUsage {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/progress"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Prompter asks questions line by line. It never moves the cursor or redraws
+// anything, so it works with screen readers and recorded sessions.
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter returns a prompter reading answers from in and writing to out.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// Say prints a line.
+func (p *Prompter) Say(format string, args ...any) {
+	_, _ = fmt.Fprintf(p.out, format+"\n", args...)
+}
+
+// Ask prints the question and returns the answer without surrounding spaces.
+func (p *Prompter) Ask(question string) (string, error) {
+	_, _ = fmt.Fprintf(p.out, "%s ", question)
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("no answer: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// AskBlock asks for pasted text, such as a key or a payload. The first line may
+// instead be the path of a file to read. Pasted text ends with an empty line.
+func (p *Prompter) AskBlock(question string) (string, error) {
+	p.Say("%s", question)
+	p.Say("Enter a file path, or paste the text and finish with an empty line:")
+
+	var lines []string
+	for {
+		line, err := p.in.ReadString('\n')
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" && len(lines) > 0 {
+			break
+		}
+		if trimmed != "" {
+			if len(lines) == 0 && isFile(trimmed) {
+				content, err := os.ReadFile(trimmed)
+				if err != nil {
+					return "", fmt.Errorf("error reading %s: %w", trimmed, err)
+				}
+				return string(content), nil
+			}
+			lines = append(lines, trimmed)
+		}
+		if err != nil {
+			if len(lines) > 0 && errors.Is(err, io.EOF) {
+				break
+			}
+			return "", fmt.Errorf("no answer: %w", err)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Confirm asks a yes/no question. Anything but "y" or "yes" means no.
+func (p *Prompter) Confirm(question string) (bool, error) {
+	answer, err := p.Ask(question + " [y/N]")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// RunPlainMenu asks whether to send or receive and runs that flow.
+func RunPlainMenu(p *Prompter, onProgress progress.Func) error {
+	for {
+		answer, err := p.Ask("Do you want to (s)end or (r)eceive a file?")
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(answer) {
+		case "s", "send":
+			return RunPlainSend(p, "", "", "", onProgress)
+		case "r", "receive":
+			return RunPlainReceive(p, nil, "", "", false, onProgress)
+		default:
+			p.Say("Please answer 's' to send or 'r' to receive.")
+		}
+	}
+}
+
+// RunPlainSend runs the send flow as line based prompts.
+// Anything already given (file, key, output path) is not asked for.
+func RunPlainSend(p *Prompter, filePath string, pubKeyPEM string, outputFilePath string, onProgress progress.Func) error {
+	var err error
+	for filePath == "" || !isFile(filePath) {
+		if filePath != "" {
+			p.Say("File not found: %s", filePath)
+		}
+		filePath, err = p.Ask("File to send:")
+		if err != nil {
+			return err
+		}
+	}
+
+	if pubKeyPEM == "" {
+		pubKeyPEM, err = p.AskBlock("Recipient's public key:")
+		if err != nil {
+			return err
+		}
+	}
+
+	p.Say("Encrypting %s...", filePath)
+	result, payload, err := encryptForSend(filePath, pubKeyPEM, onProgress)
+	if err != nil {
+		return err
+	}
+
+	if outputFilePath != "" {
+		if err := result.save(outputFilePath, payload); err != nil {
+			return err
+		}
+		for _, line := range result.Lines() {
+			p.Say("%s", line)
+		}
+		return nil
+	}
+
+	if Default.PayloadEncoding == EncodingArmor {
+		p.Say("Payload (send all of the next lines, from BEGIN to END, to the recipient):")
+	} else {
+		p.Say("Payload (send all of the next line to the recipient):")
+	}
+	p.Say("%s", strings.TrimSuffix(payload, "\n"))
+	return nil
+}
+
+// RunPlainReceive runs the receive flow as line based prompts. Without a private key
+// it creates a temporary key pair and prints the public key to share.
+func RunPlainReceive(p *Prompter, privKeyPEM []byte, payload string, inputPayloadPath string, deletePayload bool, onProgress progress.Func) error {
+	var privKey crypto.PrivateKey
+	var sessionID string
+	if len(privKeyPEM) > 0 {
+		key, err := crypto.DecodePrivateKey(privKeyPEM)
+		if err != nil {
+			return fmt.Errorf("error decoding private key: %w", err)
+		}
+		privKey = key
+	} else {
+		p.Say("Generating a temporary key pair...")
+		key, publicKey, err := GenerateTemporaryKey()
+		if err != nil {
+			return err
+		}
+		encodedKey, err := crypto.EncodeRSAPublicKey(publicKey)
+		if err != nil {
+			return err
+		}
+		privKey = key
+		p.Say("Your public key (send all of the next line to the sender):")
+		p.Say("%s", encodedKey)
+
+		sessionID, err = StartSession(key)
+		if err != nil {
+			p.Say("Warning: %v", err)
+		} else if sessionID != "" {
+			p.Say("If you close this before the payload arrives, resume with 'airbridge receive --resume %s',", sessionID)
+			p.Say("or discard it with 'airbridge session delete %s'.", sessionID)
+		}
+	}
+
+	if payload == "" {
+		var err error
+		payload, err = p.AskBlock("Payload from the sender:")
+		if err != nil {
+			return err
+		}
+	}
+
+	p.Say("Decrypting...")
+	filename, err := ProcessPayloadWithProgress(payload, privKey, onProgress)
+	if err != nil {
+		return fmt.Errorf("error processing payload: %w", err)
+	}
+	p.Say("File saved successfully: %s", filename)
+	if err := EndSession(sessionID); err != nil {
+		p.Say("Warning: %v", err)
+	}
+
+	if deletePayload && inputPayloadPath != "" {
+		if err := os.Remove(inputPayloadPath); err != nil {
+			p.Say("Warning: Failed to delete payload file: %v", err)
+		} else {
+			p.Say("Payload file deleted.")
+		}
+	}
+	return nil
+}
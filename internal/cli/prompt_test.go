@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keypool"
+	"AirBridge/internal/session"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAskBlock(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyPath, []byte("from file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Pasted lines", "line1\nline2\n\n", "line1\nline2"},
+		{"Pasted until EOF", "line1\nline2", "line1\nline2"},
+		{"Leading blank lines", "\n\nline1\n\n", "line1"},
+		{"File path", keyPath + "\n", "from file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPrompter(strings.NewReader(tt.input), &bytes.Buffer{})
+			got, err := p.AskBlock("Key:")
+			if err != nil {
+				t.Fatalf("AskBlock failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("AskBlock() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAskWithoutInput(t *testing.T) {
+	p := NewPrompter(strings.NewReader(""), &bytes.Buffer{})
+	if _, err := p.Ask("File:"); err == nil {
+		t.Error("Expected error when input is closed")
+	}
+}
+
+func TestPlainSendAndReceive(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, _ := crypto.ExportRSAPublicKeyAsPEM(pubKey)
+	privPEM, _ := crypto.ExportRSAPrivateKeyAsPEM(privKey)
+
+	if err := os.WriteFile("secret.txt", []byte("plain mode"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Send: the file is asked for, the key is pasted
+	var sendOut bytes.Buffer
+	sendIn := "secret.txt\n" + string(pubPEM) + "\n"
+	if err := RunPlainSend(NewPrompter(strings.NewReader(sendIn), &sendOut), "", "", "", nil); err != nil {
+		t.Fatalf("RunPlainSend failed: %v\nOutput: %s", err, sendOut.String())
+	}
+	lines := strings.Split(strings.TrimSpace(sendOut.String()), "\n")
+	payload := lines[len(lines)-1]
+	if strings.Contains(sendOut.String(), "\x1b") {
+		t.Error("Plain output must not contain escape sequences")
+	}
+
+	if err := os.Remove("secret.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Receive: the payload is pasted
+	var receiveOut bytes.Buffer
+	receiveIn := payload + "\n\n"
+	if err := RunPlainReceive(NewPrompter(strings.NewReader(receiveIn), &receiveOut), privPEM, "", "", false, nil); err != nil {
+		t.Fatalf("RunPlainReceive failed: %v\nOutput: %s", err, receiveOut.String())
+	}
+
+	content, err := os.ReadFile("secret.txt")
+	if err != nil {
+		t.Fatalf("Failed to read received file: %v", err)
+	}
+	if string(content) != "plain mode" {
+		t.Errorf("Expected 'plain mode', got %q", content)
+	}
+}
+
+func TestPlainSendOutput(t *testing.T) {
+	t.Chdir(t.TempDir())
+	withSettings(t, Settings{PayloadEncoding: EncodingArmor, ConflictPolicy: ConflictOverwrite})
+	_, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, _ := crypto.ExportRSAPublicKeyAsPEM(pubKey)
+	if err := os.WriteFile("secret.txt", []byte("armored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An armored payload spans several lines, and is introduced as such
+	var out bytes.Buffer
+	if err := RunPlainSend(NewPrompter(strings.NewReader(""), &out), "secret.txt", string(pubPEM), "", nil); err != nil {
+		t.Fatalf("RunPlainSend failed: %v\nOutput: %s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "next lines, from BEGIN to END") || !strings.Contains(out.String(), armorFooter) {
+		t.Errorf("Expected the armored payload to be introduced as several lines, got:\n%s", out.String())
+	}
+
+	// -o warns before replacing a file, like headless mode
+	if err := os.WriteFile("payload.abp", []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := RunPlainSend(NewPrompter(strings.NewReader(""), &out), "secret.txt", string(pubPEM), "payload.abp", nil); err != nil {
+		t.Fatalf("RunPlainSend failed: %v\nOutput: %s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Overwriting existing payload file payload.abp") || !strings.Contains(out.String(), "Payload saved to payload.abp") {
+		t.Errorf("Expected the overwrite warning and the saved path, got:\n%s", out.String())
+	}
+}
+
+func TestPlainReceivePrintsTemporaryKey(t *testing.T) {
+	var out bytes.Buffer
+	// No payload is given, so the flow stops after printing the key
+	_ = RunPlainReceive(NewPrompter(strings.NewReader(""), &out), nil, "", "", false, nil)
+
+	lines := strings.Split(out.String(), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Your public key") {
+			if DetectContent(lines[i+1]) != ContentPublicKey {
+				t.Errorf("Expected a public key after %q, got %q", line, lines[i+1])
+			}
+			return
+		}
+	}
+	t.Errorf("Expected the temporary public key to be printed, got:\n%s", out.String())
+}
+
+func TestPlainReceiveSession(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	pool := keypool.New(filepath.Join(dir, "keypool"), filepath.Join(dir, keypool.KeyFileName))
+	sessions := session.New(filepath.Join(dir, "sessions"), filepath.Join(dir, session.KeyFileName))
+	withSettings(t, Settings{
+		PayloadEncoding: EncodingBase64,
+		ConflictPolicy:  ConflictOverwrite,
+		KeyPool:         pool,
+		Sessions:        sessions,
+		SessionTTL:      time.Hour,
+	})
+
+	// The temporary key is taken from the pool, so the payload can be made for it first
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Put(privKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("secret.txt", []byte("resumable"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, _ := crypto.ExportRSAPublicKeyAsPEM(pubKey)
+	payload, _, err := EncryptPath("secret.txt", string(pubPEM), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The receive is closed before the payload arrives, and its session stays
+	var out bytes.Buffer
+	_ = RunPlainReceive(NewPrompter(strings.NewReader(""), &out), nil, "", "", false, nil)
+	open, err := sessions.List()
+	if err != nil || len(open) != 1 {
+		t.Fatalf("Expected one session, got %v (%v)", open, err)
+	}
+	if !strings.Contains(out.String(), "--resume "+open[0].ID) {
+		t.Errorf("Expected the session ID to be printed, got:\n%s", out.String())
+	}
+	_, resumed, err := sessions.Open(open[0].ID)
+	if err != nil || !pubKey.Equal(crypto.PublicKeyOf(resumed)) {
+		t.Fatalf("Expected the session to hold the temporary key, got %v", err)
+	}
+
+	// A receive that decrypts its payload ends its session
+	if err := pool.Put(privKey); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := RunPlainReceive(NewPrompter(strings.NewReader(payload+"\n\n"), &out), nil, "", "", false, nil); err != nil {
+		t.Fatalf("RunPlainReceive failed: %v\nOutput: %s", err, out.String())
+	}
+	if open, err := sessions.List(); err != nil || len(open) != 1 {
+		t.Errorf("Expected only the first session to be left, got %v (%v)", open, err)
+	}
+}
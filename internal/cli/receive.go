@@ -2,87 +2,244 @@ package cli
 
 import (
 	"AirBridge/internal/crypto"
+	"AirBridge/internal/progress"
 	"AirBridge/pkg"
-	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // ProcessPayload decodes, decrypts and saves the file from the payload
-func ProcessPayload(payloadStr string, privateKey *rsa.PrivateKey) (string, error) {
-	// 1. Parse Base64 payload
-	jsonPayloadBytes, err := base64.StdEncoding.DecodeString(payloadStr)
-	if err != nil {
-		return "", fmt.Errorf("invalid base64 payload: %v", err)
-	}
+func ProcessPayload(payloadStr string, privateKey crypto.PrivateKey) (string, error) {
+	return ProcessPayloadWithProgress(payloadStr, privateKey, nil)
+}
 
-	var payload pkg.SmallFilePayload
-	if err := json.Unmarshal(jsonPayloadBytes, &payload); err != nil {
-		return "", fmt.Errorf("invalid json payload: %v", err)
-	}
+// ProcessPayloadWithProgress works like ProcessPayload and reports progress to onProgress
+func ProcessPayloadWithProgress(payloadStr string, privateKey crypto.PrivateKey, onProgress progress.Func) (string, error) {
+	savePath, _, err := DecryptPayload(payloadStr, privateKey, onProgress)
+	return savePath, err
+}
 
-	// 2. Decrypt AES Key
-	encryptedAESKey, err := hex.DecodeString(payload.Key)
+// DecryptPayload works like ProcessPayloadWithProgress, and also returns the
+// metadata of the file: the name the sender gave it, with the size and hash of
+// what was actually written. Signed payloads are checked against the pinned
+// keys, see verifySender
+func DecryptPayload(payloadStr string, privateKey crypto.PrivateKey, onProgress progress.Func) (string, pkg.FileMetadata, error) {
+	r, err := decryptPayload(payloadStr, privateKey, onProgress)
+	return r.path, r.saved, err
+}
+
+// decrypted is a payload saved by decryptPayload
+type decrypted struct {
+	path  string
+	saved pkg.FileMetadata
+	// signer is who signed the payload, if anyone
+	signer signer
+}
+
+func decryptPayload(payloadStr string, privateKey crypto.PrivateKey, onProgress progress.Func) (decrypted, error) {
+	// 1. Parse Base64 payload (armor lines are ignored)
+	payloadStr = compactPayload(payloadStr)
+	// Progress covers decoding the payload, opening the data and writing the file.
+	// The file is about 3/8 of the payload: base64 of the hex ciphertext
+	fileSize := int64(len(payloadStr)) * 3 / 8
+	tracker := progress.NewTracker("Decrypting", int64(len(payloadStr))+2*fileSize, onProgress)
+	payload, err := decodePayload(payloadStr, tracker)
 	if err != nil {
-		return "", fmt.Errorf("invalid hex key: %v", err)
+		return decrypted{}, err
+	}
+	signer, err := verifySender(payload)
+	if err != nil {
+		return decrypted{}, err
 	}
 
-	aesKey, err := crypto.DecryptAESKeyWithRSA(privateKey, encryptedAESKey)
+	// 2. Decrypt AES Key
+	aesKey, err := unwrapPayloadKey(payload, privateKey)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt AES key: %v", err)
+		return decrypted{}, err
 	}
 
 	// 3. Decrypt Data
 	nonce, err := hex.DecodeString(payload.Nonce)
 	if err != nil {
-		return "", fmt.Errorf("invalid hex nonce: %v", err)
+		return decrypted{}, fmt.Errorf("%w: invalid hex nonce: %w", ErrMalformedPayload, err)
 	}
 
 	encryptedData, err := hex.DecodeString(payload.Data)
 	if err != nil {
-		return "", fmt.Errorf("invalid hex data: %v", err)
+		return decrypted{}, fmt.Errorf("%w: invalid hex data: %w", ErrMalformedPayload, err)
 	}
 
+	// Opened in one go, so it's counted once done
 	decryptedData, err := crypto.DecryptDataAES(aesKey, nonce, encryptedData)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt data: %v", err)
+		return decrypted{}, fmt.Errorf("failed to decrypt data: %w", err)
 	}
+	tracker.Add(int64(len(decryptedData)))
 
 	// 4. Save File
-	safeFilename := filepath.Base(payload.Metadata.Name)
-	err = os.WriteFile(safeFilename, decryptedData, 0644)
+	savePath, err := outputPath(filepath.Base(payload.Metadata.Name))
+	if err != nil {
+		return decrypted{}, fmt.Errorf("failed to save file: %w", err)
+	}
+	if err := writeFile(savePath, decryptedData, tracker); err != nil {
+		return decrypted{}, Errorf(ClassIO, "failed to save file: %w", err)
+	}
+	tracker.Finish()
+
+	return decrypted{
+		path: savePath,
+		saved: pkg.FileMetadata{
+			Name: payload.Metadata.Name,
+			Size: int64(len(decryptedData)),
+			Hash: fmt.Sprintf("%x", sha256.Sum256(decryptedData)),
+		},
+		signer: signer,
+	}, nil
+}
+
+// writeChunk is how much of a file is written before progress is counted.
+const writeChunk = 1 << 20
+
+// writeFile writes data to path like os.WriteFile, counting the bytes written on tracker
+func writeFile(path string, data []byte, tracker *progress.Tracker) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := tracker.Writer(file)
+	for len(data) > 0 {
+		n := min(len(data), writeChunk)
+		if _, err := w.Write(data[:n]); err != nil {
+			file.Close()
+			return err
+		}
+		data = data[n:]
+	}
+	return file.Close()
+}
+
+// wrappedKeys returns the file key of the payload as wrapped for each recipient
+func wrappedKeys(payload pkg.SmallFilePayload) []pkg.WrappedKey {
+	if len(payload.Keys) > 0 {
+		return payload.Keys
+	}
+	return []pkg.WrappedKey{{Recipient: payload.Recipient, KeyType: payload.KeyType, Key: payload.Key}}
+}
+
+// unwrapPayloadKey returns the file key of the payload, unwrapped with privateKey.
+// Wrapped keys that name a recipient are only tried if they name this key
+func unwrapPayloadKey(payload pkg.SmallFilePayload, privateKey crypto.PrivateKey) ([]byte, error) {
+	fingerprint, err := crypto.FingerprintPublicKey(crypto.PublicKeyOf(privateKey))
 	if err != nil {
-		return "", fmt.Errorf("failed to save file: %v", err)
+		return nil, Errorf(ClassKey, "error reading private key: %w", err)
 	}
+	id := crypto.KeyID(fingerprint)
 
-	return safeFilename, nil
+	var named []string
+	var lastErr error
+	for _, wrapped := range wrappedKeys(payload) {
+		if wrapped.Recipient != "" && wrapped.Recipient != id {
+			named = append(named, wrapped.Recipient)
+			continue
+		}
+		encryptedAESKey, err := hex.DecodeString(wrapped.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid hex key: %w", ErrMalformedPayload, err)
+		}
+		aesKey, err := crypto.UnwrapKey(privateKey, encryptedAESKey)
+		if err == nil {
+			return aesKey, nil
+		}
+		if wrapped.Recipient == id {
+			// The payload is for this key, so the wrapped key must be damaged
+			return nil, fmt.Errorf("%w: the symmetric key for %s can't be decrypted (%v)", ErrMalformedPayload, id, err)
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("failed to decrypt AES key: %w", lastErr)
+	}
+	return nil, fmt.Errorf("%w: the payload is for key %s, this key is %s", crypto.ErrWrongRecipient, strings.Join(named, ", "), id)
+}
+
+// compactPayload strips the armor lines and the line breaks between them from
+// an armored payload. Plain payloads only lose surrounding whitespace
+func compactPayload(payloadStr string) string {
+	if !strings.Contains(payloadStr, armorHeader) {
+		return strings.TrimSpace(payloadStr)
+	}
+	return strings.Join(strings.Fields(Dearmor(payloadStr)), "")
+}
+
+// decodePayload parses a compact payload, counting the bytes read on tracker
+func decodePayload(payloadStr string, tracker *progress.Tracker) (pkg.SmallFilePayload, error) {
+	decoder := base64.NewDecoder(base64.StdEncoding, tracker.Reader(strings.NewReader(payloadStr)))
+	jsonPayloadBytes, err := io.ReadAll(decoder)
+	if err != nil {
+		return pkg.SmallFilePayload{}, fmt.Errorf("%w: invalid base64: %w", ErrMalformedPayload, err)
+	}
+
+	var payload pkg.SmallFilePayload
+	if err := json.Unmarshal(jsonPayloadBytes, &payload); err != nil {
+		return pkg.SmallFilePayload{}, fmt.Errorf("%w: invalid json: %w", ErrMalformedPayload, err)
+	}
+	if payload.Version > pkg.PayloadVersion {
+		return pkg.SmallFilePayload{}, fmt.Errorf("%w: version %d (this release reads up to %d)", ErrUnsupportedVersion, payload.Version, pkg.PayloadVersion)
+	}
+	if payload.Version > pkg.PayloadVersionSingle && len(payload.Keys) == 0 {
+		return pkg.SmallFilePayload{}, fmt.Errorf("%w: no wrapped keys", ErrMalformedPayload)
+	}
+	for _, wrapped := range wrappedKeys(payload) {
+		if wrapped.KeyType != "" && wrapped.KeyType != pkg.KeyTypeX25519 {
+			return pkg.SmallFilePayload{}, fmt.Errorf("%w: key type %q", ErrUnsupportedVersion, wrapped.KeyType)
+		}
+	}
+	return payload, nil
 }
 
 // RunReceive orchestrates the headless receive command
-func RunReceive(payload string, privKeyPEM []byte, inputPayloadPath string, deletePayload bool) error {
-	privKey, err := crypto.DecodeRSAPrivateKey(privKeyPEM)
+func RunReceive(payload string, privKeyPEM []byte, inputPayloadPath string, deletePayload bool, onProgress progress.Func) (ReceiveResult, error) {
+	privKey, err := crypto.DecodePrivateKey(privKeyPEM)
+	if err != nil {
+		return ReceiveResult{}, fmt.Errorf("error decoding private key: %w", err)
+	}
+	fingerprint, err := crypto.FingerprintPublicKey(crypto.PublicKeyOf(privKey))
 	if err != nil {
-		return fmt.Errorf("error decoding private key: %w", err)
+		return ReceiveResult{}, Errorf(ClassKey, "error reading private key: %w", err)
 	}
 
-	filename, err := ProcessPayload(payload, privKey)
+	r, err := decryptPayload(payload, privKey, onProgress)
 	if err != nil {
-		return fmt.Errorf("error processing payload: %w", err)
+		return ReceiveResult{}, fmt.Errorf("error processing payload: %w", err)
 	}
 
-	fmt.Printf("File saved successfully: %s\n", filename)
+	result := ReceiveResult{
+		File:              r.path,
+		Size:              r.saved.Size,
+		Hash:              r.saved.Hash,
+		Payload:           inputPayloadPath,
+		KeyFingerprint:    fingerprint,
+		Sender:            r.signer.name,
+		SenderFingerprint: r.signer.fingerprint,
+		Warnings:          r.signer.warnings(),
+	}
+	if name := filepath.Base(r.saved.Name); filepath.Base(r.path) != name {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Warning: %s already existed, saved as %s", name, filepath.Base(r.path)))
+	}
 
-	if deletePayload {
-		err := os.Remove(inputPayloadPath)
-		if err != nil {
-			fmt.Printf("Warning: Failed to delete payload file: %v\n", err)
+	if deletePayload && inputPayloadPath != "" {
+		if err := os.Remove(inputPayloadPath); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Warning: Failed to delete payload file: %v", err))
 		} else {
-			fmt.Println("Payload file deleted.")
+			result.PayloadDeleted = true
 		}
 	}
-	return nil
+	return result, nil
 }
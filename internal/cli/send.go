@@ -2,23 +2,30 @@ package cli
 
 import (
 	"AirBridge/internal/crypto"
+	"AirBridge/internal/progress"
 	"AirBridge/pkg"
-	"crypto/rsa"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
 // GetFileMetadata extracts metadata from the file
 func GetFileMetadata(file *os.File) (pkg.FileMetadata, error) {
+	return GetFileMetadataWithProgress(file, nil)
+}
+
+// GetFileMetadataWithProgress extracts metadata from the file, reporting hashing progress to onProgress
+func GetFileMetadataWithProgress(file *os.File, onProgress progress.Func) (pkg.FileMetadata, error) {
 	fileInfo, err := file.Stat()
 	if err != nil {
 		return pkg.FileMetadata{}, err
 	}
 
-	fileHash, err := crypto.CalculateFileHash(file)
+	fileHash, err := crypto.CalculateFileHashWithProgress(file, onProgress)
 	if err != nil {
 		return pkg.FileMetadata{}, err
 	}
@@ -26,7 +33,7 @@ func GetFileMetadata(file *os.File) (pkg.FileMetadata, error) {
 	// Reset file pointer after hash calculation
 	_, err = file.Seek(0, 0)
 	if err != nil {
-		return pkg.FileMetadata{}, fmt.Errorf("failed to reset file pointer: %v", err)
+		return pkg.FileMetadata{}, fmt.Errorf("failed to reset file pointer: %w", err)
 	}
 
 	return pkg.FileMetadata{
@@ -36,93 +43,247 @@ func GetFileMetadata(file *os.File) (pkg.FileMetadata, error) {
 	}, nil
 }
 
-// EncryptFile encrypts the file and returns the base64 encoded payload
-func EncryptFile(file *os.File, metadata pkg.FileMetadata, publicKey *rsa.PublicKey) (string, error) {
+// EncryptFile encrypts the file and returns the payload in the configured encoding
+func EncryptFile(file *os.File, metadata pkg.FileMetadata, publicKey crypto.PublicKey) (string, error) {
+	return EncryptFileWithProgress(file, metadata, publicKey, nil)
+}
+
+// EncryptFileWithProgress encrypts the file like EncryptFile, reporting progress to onProgress
+func EncryptFileWithProgress(file *os.File, metadata pkg.FileMetadata, publicKey crypto.PublicKey, onProgress progress.Func) (string, error) {
+	return EncryptFileForKeys(file, metadata, []crypto.PublicKey{publicKey}, onProgress)
+}
+
+// EncryptFileForKeys encrypts the file so that any of the public keys can decrypt it.
+// Payloads for several keys wrap the file key once for each of them (version 2)
+func EncryptFileForKeys(file *os.File, metadata pkg.FileMetadata, publicKeys []crypto.PublicKey, onProgress progress.Func) (string, error) {
+	if len(publicKeys) == 0 {
+		return "", fmt.Errorf("%w: no public key to encrypt for", crypto.ErrInvalidKey)
+	}
+	// Progress covers reading the file, sealing it and encoding the payload. The
+	// encoded size is estimated from the hex ciphertext, which makes up most of it
+	tracker := progress.NewTracker("Encrypting", 2*metadata.Size+int64(base64.StdEncoding.EncodedLen(2*int(metadata.Size))), onProgress)
+
 	// 5. Encryption process (Generate random key for AES-256)
 	aesKey, err := crypto.GenerateAESKey()
 	if err != nil {
-		return "", fmt.Errorf("could not generate symmetric key: %v", err)
+		return "", fmt.Errorf("could not generate symmetric key: %w", err)
 	}
 
-	// 6. Encrypt AES key with recipient's public key
-	encryptedAESKey, err := crypto.EncryptAESKeyWithRSA(publicKey, aesKey)
-	if err != nil {
-		return "", fmt.Errorf("could not encrypt symmetric key with public key: %v", err)
+	// 6. Encrypt AES key with each recipient's public key
+	wrappedKeys := make([]pkg.WrappedKey, 0, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		wrapped, err := wrapFileKey(publicKey, aesKey)
+		if err != nil {
+			return "", err
+		}
+		wrappedKeys = append(wrappedKeys, wrapped)
 	}
 
 	// Generate Nonce (Number used once) for AES-GCM
 	nonce, err := crypto.GenerateIV()
 	if err != nil {
-		return "", fmt.Errorf("could not generate nonce: %v", err)
+		return "", fmt.Errorf("could not generate nonce: %w", err)
 	}
 
 	// Ensure we read from start
 	_, err = file.Seek(0, 0)
 	if err != nil {
-		return "", fmt.Errorf("failed to reset file pointer: %v", err)
+		return "", fmt.Errorf("failed to reset file pointer: %w", err)
 	}
 
-	fileBytes, err := io.ReadAll(file)
+	fileBytes, err := io.ReadAll(tracker.Reader(file))
 	if err != nil {
-		return "", fmt.Errorf("error reading file into memory: %v", err)
+		return "", fmt.Errorf("error reading file into memory: %w", err)
 	}
 
-	// Encrypt with GCM
+	// Encrypt with GCM (sealed in one go, so it's counted once done)
 	encryptedData, err := crypto.EncryptDataAES(aesKey, nonce, fileBytes)
 	if err != nil {
-		return "", fmt.Errorf("could not encrypt data: %v", err)
+		return "", fmt.Errorf("could not encrypt data: %w", err)
 	}
+	tracker.Add(int64(len(fileBytes)))
 
 	// Make Payload
 	payload := pkg.SmallFilePayload{
-		Key:      fmt.Sprintf("%x", encryptedAESKey),
-		Data:     fmt.Sprintf("%x", encryptedData),
+		Version:  pkg.PayloadVersionSingle,
+		Data:     hex.EncodeToString(encryptedData),
 		Nonce:    fmt.Sprintf("%x", nonce),
 		Metadata: metadata,
 	}
+	if len(wrappedKeys) == 1 {
+		payload.Recipient = wrappedKeys[0].Recipient
+		payload.KeyType = wrappedKeys[0].KeyType
+		payload.Key = wrappedKeys[0].Key
+	} else {
+		payload.Version = pkg.PayloadVersion
+		payload.Keys = wrappedKeys
+	}
+	if Default.SignKey != nil {
+		if err := signPayload(&payload, Default.SignKey); err != nil {
+			return "", fmt.Errorf("could not sign payload: %w", err)
+		}
+	}
 
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("could not marshal JSON payload: %v", err)
+		return "", fmt.Errorf("could not marshal JSON payload: %w", err)
+	}
+
+	var encoded strings.Builder
+	encoded.Grow(base64.StdEncoding.EncodedLen(len(jsonPayload)))
+	encoder := base64.NewEncoder(base64.StdEncoding, tracker.Writer(&encoded))
+	if _, err := encoder.Write(jsonPayload); err != nil {
+		return "", fmt.Errorf("could not encode payload: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("could not encode payload: %w", err)
+	}
+	tracker.Finish()
+	return EncodePayload(encoded.String()), nil
+}
+
+// wrapFileKey wraps the file key for one recipient key
+func wrapFileKey(publicKey crypto.PublicKey, aesKey []byte) (pkg.WrappedKey, error) {
+	encryptedAESKey, err := crypto.WrapKey(publicKey, aesKey)
+	if err != nil {
+		return pkg.WrappedKey{}, fmt.Errorf("could not encrypt symmetric key with public key: %w", err)
 	}
 
-	encodedPayload := base64.StdEncoding.EncodeToString(jsonPayload)
-	return encodedPayload, nil
+	// Name the recipient, so receivers with several keys know which one to use
+	wrapped := pkg.WrappedKey{Key: fmt.Sprintf("%x", encryptedAESKey)}
+	if !Default.Anonymous {
+		fingerprint, err := crypto.FingerprintPublicKey(publicKey)
+		if err != nil {
+			return pkg.WrappedKey{}, fmt.Errorf("could not read public key: %w", err)
+		}
+		wrapped.Recipient = crypto.KeyID(fingerprint)
+	}
+	if crypto.KeyType(publicKey) == crypto.TypeEd25519 {
+		wrapped.KeyType = pkg.KeyTypeX25519
+	}
+	return wrapped, nil
 }
 
-// RunSend orchestrates the headless send command
-func RunSend(filePath string, pubKeyPEM string, outputFilePath string) error {
+// DecodeRecipients decodes the public keys a payload is sent to, refusing RSA keys
+// smaller than the configured minimum
+func DecodeRecipients(pubKeyPEM string) ([]crypto.PublicKey, error) {
+	pubKeys, err := crypto.DecodePublicKeys(pubKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding public key: %w", err)
+	}
+	minBits := Default.MinKeyBits
+	if minBits == 0 {
+		minBits = crypto.DefaultRSABits
+	}
+	for _, pubKey := range pubKeys {
+		if err := crypto.CheckKeyStrength(pubKey, minBits); err != nil {
+			return nil, fmt.Errorf("error using public key: %w", err)
+		}
+	}
+	return pubKeys, nil
+}
+
+// EncryptPath opens the file at filePath and encrypts it for the given public key,
+// or for each key if pubKeyPEM holds several
+func EncryptPath(filePath string, pubKeyPEM string, onProgress progress.Func) (string, pkg.FileMetadata, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("error opening file: %w", err)
+		return "", pkg.FileMetadata{}, Errorf(ClassIO, "error opening file: %w", err)
 	}
 	defer func() { _ = file.Close() }()
 
-	metadata, err := GetFileMetadata(file)
+	metadata, err := GetFileMetadataWithProgress(file, onProgress)
 	if err != nil {
-		return fmt.Errorf("error extracting metadata: %w", err)
+		return "", pkg.FileMetadata{}, Errorf(ClassIO, "error extracting metadata: %w", err)
 	}
 
-	pubKey, err := crypto.DecodeRSAPublicKey(pubKeyPEM)
+	pubKeys, err := DecodeRecipients(pubKeyPEM)
 	if err != nil {
-		return fmt.Errorf("error decoding public key: %w", err)
+		return "", pkg.FileMetadata{}, err
 	}
 
-	payload, err := EncryptFile(file, metadata, pubKey)
+	payload, err := EncryptFileForKeys(file, metadata, pubKeys, onProgress)
 	if err != nil {
-		return fmt.Errorf("error encrypting file: %w", err)
+		return "", pkg.FileMetadata{}, fmt.Errorf("error encrypting file: %w", err)
 	}
+	return payload, metadata, nil
+}
 
+// RunSend orchestrates the headless send command
+func RunSend(filePath string, pubKeyPEM string, outputFilePath string, onProgress progress.Func) (SendResult, error) {
 	outPath := outputFilePath
 	if outPath == "" {
 		outPath = "payload.abp"
 	}
+	result, payload, err := encryptForSend(filePath, pubKeyPEM, onProgress)
+	if err != nil {
+		return SendResult{}, err
+	}
+	if err := result.save(outPath, payload); err != nil {
+		return SendResult{}, err
+	}
+	return result, nil
+}
 
-	err = os.WriteFile(outPath, []byte(payload), 0644)
+// encryptForSend encrypts a file like EncryptPath, and describes it in a
+// SendResult whose payload isn't saved yet
+func encryptForSend(filePath string, pubKeyPEM string, onProgress progress.Func) (SendResult, string, error) {
+	payload, metadata, err := EncryptPath(filePath, pubKeyPEM, onProgress)
 	if err != nil {
-		return fmt.Errorf("error saving payload: %w", err)
+		return SendResult{}, "", err
 	}
 
-	fmt.Printf("Payload saved to %s\n", outPath)
+	// EncryptPath already checked the keys
+	pubKeys, _ := crypto.DecodePublicKeys(pubKeyPEM)
+	var fingerprints []string
+	for _, pubKey := range pubKeys {
+		fingerprint, err := crypto.FingerprintPublicKey(pubKey)
+		if err != nil {
+			return SendResult{}, "", Errorf(ClassKey, "error reading public key: %w", err)
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	result := SendResult{
+		File:                 filePath,
+		Size:                 metadata.Size,
+		Hash:                 metadata.Hash,
+		Encoding:             Default.PayloadEncoding,
+		RecipientFingerprint: fingerprints[0],
+	}
+	if len(fingerprints) > 1 {
+		result.RecipientFingerprints = fingerprints
+	}
+	if Default.SignKey != nil {
+		if result.SignerFingerprint, err = crypto.FingerprintPublicKey(crypto.PublicKeyOf(Default.SignKey)); err != nil {
+			return SendResult{}, "", Errorf(ClassKey, "error reading signing key: %w", err)
+		}
+	}
+	return result, payload, nil
+}
+
+// save writes the payload to path with SavePayload, and records it in the result
+func (r *SendResult) save(path, payload string) error {
+	warning, err := SavePayload(path, payload)
+	if err != nil {
+		return err
+	}
+	r.Payload = path
+	if warning != "" {
+		r.Warnings = append(r.Warnings, warning)
+	}
 	return nil
 }
+
+// SavePayload writes a payload to the -o path of send, replacing a file that is
+// already there and returning a warning that it did. Every mode saves -o this way
+func SavePayload(path, payload string) (string, error) {
+	var warning string
+	if _, err := os.Stat(path); err == nil {
+		warning = fmt.Sprintf("Warning: Overwriting existing payload file %s", path)
+	}
+	if err := os.WriteFile(path, []byte(payload), 0644); err != nil {
+		return "", Errorf(ClassIO, "error saving payload: %w", err)
+	}
+	return warning, nil
+}
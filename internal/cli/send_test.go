@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/progress"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProgressCoversEveryStep(t *testing.T) {
+	t.Chdir(t.TempDir())
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "big.bin")
+	content := strings.Repeat("airbridge", 100_000)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	metadata, err := GetFileMetadata(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sendUpdates []progress.Update
+	payload, err := EncryptFileWithProgress(file, metadata, pubKey, func(u progress.Update) { sendUpdates = append(sendUpdates, u) })
+	if err != nil {
+		t.Fatalf("EncryptFileWithProgress failed: %v", err)
+	}
+	var receiveUpdates []progress.Update
+	if _, err := ProcessPayloadWithProgress(payload, privKey, func(u progress.Update) { receiveUpdates = append(receiveUpdates, u) }); err != nil {
+		t.Fatalf("ProcessPayloadWithProgress failed: %v", err)
+	}
+
+	// Reading, the cipher and encoding or writing all count, not just the file read
+	for name, updates := range map[string][]progress.Update{"send": sendUpdates, "receive": receiveUpdates} {
+		if len(updates) == 0 {
+			t.Fatalf("Expected %s progress", name)
+		}
+		last := updates[len(updates)-1]
+		if !last.Finished || last.Done != last.Total {
+			t.Errorf("Expected %s to finish complete, got %+v", name, last)
+		}
+		if last.Total < 2*int64(len(content)) {
+			t.Errorf("Expected %s progress to cover more than one pass over the file, total %d", name, last.Total)
+		}
+	}
+}
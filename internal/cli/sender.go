@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keystore"
+	"AirBridge/pkg"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// signPayload signs the payload with privateKey, adding the sender's public key
+func signPayload(payload *pkg.SmallFilePayload, privateKey crypto.PrivateKey) error {
+	sender, err := crypto.EncodePublicKey(crypto.PublicKeyOf(privateKey))
+	if err != nil {
+		return fmt.Errorf("could not encode signing key: %w", err)
+	}
+	payload.Sender = sender
+	message, err := signedContent(*payload)
+	if err != nil {
+		return err
+	}
+	signature, err := crypto.Sign(privateKey, message)
+	if err != nil {
+		return err
+	}
+	payload.Signature = base64.StdEncoding.EncodeToString(signature)
+	return nil
+}
+
+// signedContent returns what the signature of a payload is made over: its JSON
+// without the signature
+func signedContent(payload pkg.SmallFilePayload) ([]byte, error) {
+	payload.Signature = ""
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal JSON payload: %w", err)
+	}
+	return content, nil
+}
+
+// payloadSigner checks the signature of a signed payload and returns the
+// sender's public key
+func payloadSigner(payload pkg.SmallFilePayload) (crypto.PublicKey, error) {
+	senderKey, err := crypto.DecodePublicKey(payload.Sender)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid sender key: %w", ErrMalformedPayload, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(payload.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid base64 signature: %w", ErrMalformedPayload, err)
+	}
+	message, err := signedContent(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := crypto.VerifySignature(senderKey, message, signature); err != nil {
+		return nil, fmt.Errorf("%w: the sender's signature doesn't match, the payload was changed: %w", ErrMalformedPayload, err)
+	}
+	return senderKey, nil
+}
+
+// signer is who signed a payload, as found by verifySender
+type signer struct {
+	// name is the contact the key is pinned for, empty if none is
+	name        string
+	fingerprint string
+	// firstSeen is set when this payload pinned the key for name
+	firstSeen bool
+}
+
+// verifySender checks the signature of a payload and looks its key up in the
+// pins. Unsigned payloads have no signer, and are refused if Default.Sender
+// expects one. Without Default.Sender, a key no contact is pinned to is accepted
+// but has no name, so it can be reported as unverified. With it, the key is
+// pinned for Default.Sender the first time, and a payload signed by another key
+// than the pinned one is refused with keystore.ErrKeyChanged
+func verifySender(payload pkg.SmallFilePayload) (signer, error) {
+	if payload.Signature == "" && payload.Sender == "" {
+		if Default.Sender != "" {
+			return signer{}, fmt.Errorf("%w: expected it to be signed by %s", ErrUnsigned, Default.Sender)
+		}
+		return signer{}, nil
+	}
+	senderKey, err := payloadSigner(payload)
+	if err != nil {
+		return signer{}, err
+	}
+	fingerprint, err := crypto.FingerprintPublicKey(senderKey)
+	if err != nil {
+		return signer{}, Errorf(ClassKey, "error reading sender key: %w", err)
+	}
+
+	if Default.Sender != "" {
+		if Default.Pins == nil {
+			return signer{name: Default.Sender, fingerprint: fingerprint, firstSeen: true}, nil
+		}
+		firstSeen, err := Default.Pins.Check(Default.Sender, fingerprint)
+		if errors.Is(err, keystore.ErrKeyChanged) {
+			return signer{}, err
+		} else if err != nil {
+			return signer{}, Errorf(ClassConfig, "%w", err)
+		}
+		return signer{name: Default.Sender, fingerprint: fingerprint, firstSeen: firstSeen}, nil
+	}
+	var name string
+	if Default.Pins != nil {
+		if name, err = Default.Pins.Find(fingerprint); err != nil {
+			return signer{}, Errorf(ClassConfig, "%w", err)
+		}
+	}
+	return signer{name: name, fingerprint: fingerprint}, nil
+}
+
+// warnings returns what the receiver should check about an unverified or
+// first-seen signer
+func (s signer) warnings() []string {
+	switch {
+	case s.fingerprint == "":
+		return nil
+	case s.name == "":
+		return []string{fmt.Sprintf("Warning: the payload is signed by %s, which isn't pinned for any contact. Check it with the sender, then import their key with 'airbridge key import <name> <file>' or receive with --from <name>", s.fingerprint)}
+	case s.firstSeen:
+		return []string{fmt.Sprintf("Warning: first payload from %s, their key %s is now pinned. Check the fingerprint with %s", s.name, s.fingerprint, s.name)}
+	}
+	return nil
+}
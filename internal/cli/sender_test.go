@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keystore"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignedPayloadSender(t *testing.T) {
+	t.Chdir(t.TempDir())
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPEM, _ := crypto.ExportRSAPrivateKeyAsPEM(privKey)
+	_, aliceKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, malloryKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceFingerprint, _ := crypto.FingerprintPublicKey(aliceKey.Public())
+
+	previous := Default
+	t.Cleanup(func() { Default = previous })
+	pins := &keystore.Pins{Path: filepath.Join(t.TempDir(), keystore.PinsFile)}
+	if err := pins.Set("alice", aliceFingerprint); err != nil {
+		t.Fatal(err)
+	}
+	Default.Pins = pins
+
+	signedBy := func(key ed25519.PrivateKey) string {
+		Default.SignKey = key
+		defer func() { Default.SignKey = nil }()
+		return encryptForTest(t, "signed", pubKey)
+	}
+	fromAlice, fromMallory := signedBy(aliceKey), signedBy(malloryKey)
+	unsigned := encryptForTest(t, "unsigned", pubKey)
+
+	result, err := RunReceive(fromAlice, privPEM, "", false, nil)
+	if err != nil {
+		t.Fatalf("Expected a payload signed by a contact to be received: %v", err)
+	}
+	if result.Sender != "alice" || result.SenderFingerprint != aliceFingerprint {
+		t.Errorf("Expected the payload to be signed by alice, got %q (%s)", result.Sender, result.SenderFingerprint)
+	}
+	// A key no contact has is received, but reported as unverified
+	malloryFingerprint, _ := crypto.FingerprintPublicKey(malloryKey.Public())
+	result, err = RunReceive(fromMallory, privPEM, "", false, nil)
+	if err != nil || result.Sender != "" || result.SenderFingerprint != malloryFingerprint {
+		t.Errorf("Expected a payload from an unpinned key to be received as unverified, got %+v (%v)", result, err)
+	}
+	if !strings.Contains(strings.Join(result.Lines(), "\n"), "unverified key ("+malloryFingerprint) || len(result.Warnings) != 1 {
+		t.Errorf("Expected a warning about the unverified key, got %v", result.Lines())
+	}
+	if _, err := RunReceive(unsigned, privPEM, "", false, nil); err != nil {
+		t.Errorf("Expected unsigned payloads to be received without --from: %v", err)
+	}
+
+	// Changing the payload breaks the signature
+	decoded, _ := base64.StdEncoding.DecodeString(fromAlice)
+	var fields map[string]any
+	if err := json.Unmarshal(decoded, &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields["metadata"].(map[string]any)["name"] = "other.txt"
+	changed, _ := json.Marshal(fields)
+	if _, err := RunReceive(base64.StdEncoding.EncodeToString(changed), privPEM, "", false, nil); !errors.Is(err, crypto.ErrBadSignature) || ClassOf(err) != ClassPayload {
+		t.Errorf("Expected a changed payload to fail its signature, got %v", err)
+	}
+	if r := InspectPayload(fromAlice, nil); !r.Signed || r.SenderFingerprint != aliceFingerprint || !r.Valid {
+		t.Errorf("Expected inspect to show alice's signature, got %+v", r)
+	}
+	if r := InspectPayload(base64.StdEncoding.EncodeToString(changed), nil); !r.Signed || r.SenderFingerprint != "" || r.Valid {
+		t.Errorf("Expected inspect to report the broken signature, got %+v", r)
+	}
+
+	Default.Sender = "alice"
+	if _, err := RunReceive(fromAlice, privPEM, "", false, nil); err != nil {
+		t.Errorf("Expected the payload from alice to pass --from alice: %v", err)
+	}
+	_, err = RunReceive(fromMallory, privPEM, "", false, nil)
+	if !errors.Is(err, keystore.ErrKeyChanged) || !strings.Contains(err.Error(), aliceFingerprint) {
+		t.Errorf("Expected ErrKeyChanged naming alice's key, got %v", err)
+	}
+	if _, err := RunReceive(unsigned, privPEM, "", false, nil); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned with --from, got %v", err)
+	}
+
+	// A name seen for the first time pins the key it was signed with
+	Default.Sender = "bob"
+	result, err = RunReceive(fromMallory, privPEM, "", false, nil)
+	if err != nil || result.Sender != "bob" || len(result.Warnings) != 1 {
+		t.Errorf("Expected the first payload from bob to be received and pinned, got %+v (%v)", result, err)
+	}
+	if pinned, _ := pins.Get("bob"); pinned != malloryFingerprint {
+		t.Errorf("Expected bob to be pinned to the signing key, got %q", pinned)
+	}
+	if result, err := RunReceive(fromMallory, privPEM, "", false, nil); err != nil || len(result.Warnings) != 0 {
+		t.Errorf("Expected bob's pinned key to be received quietly, got %+v (%v)", result, err)
+	}
+	if _, err := RunReceive(fromAlice, privPEM, "", false, nil); !errors.Is(err, keystore.ErrKeyChanged) || ClassOf(err) != ClassKey {
+		t.Errorf("Expected another key for bob to be refused with ErrKeyChanged, got %v", err)
+	}
+}
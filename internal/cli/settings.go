@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keypool"
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/session"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Payload encodings.
+const (
+	EncodingBase64 = "base64"
+	EncodingArmor  = "armor"
+)
+
+// Policies for received files whose name is already taken.
+const (
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+	ConflictFail      = "fail"
+)
+
+const (
+	armorHeader = "-----BEGIN AIRBRIDGE PAYLOAD-----"
+	armorFooter = "-----END AIRBRIDGE PAYLOAD-----"
+	armorWidth  = 64
+)
+
+// Settings are the transfer defaults taken from the config file.
+type Settings struct {
+	// OutputDir is where received files are saved; empty means the current directory.
+	OutputDir string
+	// PayloadEncoding is EncodingBase64 or EncodingArmor.
+	PayloadEncoding string
+	// ConflictPolicy is ConflictOverwrite, ConflictRename or ConflictFail.
+	ConflictPolicy string
+	// Anonymous leaves the recipient's key ID out of payloads.
+	Anonymous bool
+	// KeyBits is the size of the temporary keys receive generates; zero means crypto.DefaultRSABits.
+	KeyBits int
+	// KeyPool holds temporary keys generated ahead of time; nil generates every key.
+	KeyPool *keypool.Pool
+	// Sessions keeps the temporary keys of receives so they can be resumed; nil keeps none.
+	Sessions *session.Store
+	// SessionTTL is how long a receive can be resumed; zero starts no sessions.
+	SessionTTL time.Duration
+	// MinKeyBits is the smallest RSA recipient key send accepts; zero means crypto.DefaultRSABits.
+	MinKeyBits int
+	// SignKey signs the payloads sent; nil sends them unsigned.
+	SignKey crypto.PrivateKey
+	// Pins are the contact fingerprints the senders of signed payloads are checked
+	// against; nil knows no senders, and pins none.
+	Pins *keystore.Pins
+	// Sender is the contact received payloads must be signed by, pinning its key on
+	// first use; empty also accepts unsigned payloads and unpinned signers.
+	Sender string
+}
+
+// Default holds the settings used by all transfers.
+var Default = Settings{
+	PayloadEncoding: EncodingBase64,
+	ConflictPolicy:  ConflictOverwrite,
+}
+
+// Configure checks the settings and makes them the default
+func Configure(settings Settings) error {
+	switch settings.PayloadEncoding {
+	case EncodingBase64, EncodingArmor:
+	default:
+		return fmt.Errorf("unknown payload encoding %q", settings.PayloadEncoding)
+	}
+	switch settings.ConflictPolicy {
+	case ConflictOverwrite, ConflictRename, ConflictFail:
+	default:
+		return fmt.Errorf("unknown conflict policy %q", settings.ConflictPolicy)
+	}
+	if settings.KeyBits != 0 {
+		if err := crypto.CheckRSAKeySize(settings.KeyBits); err != nil {
+			return err
+		}
+	}
+	Default = settings
+	return nil
+}
+
+// GenerateTemporaryKey returns the key pair of a receive without a private key,
+// with the configured size. It is taken from the key pool if that has one, and
+// generated otherwise
+func GenerateTemporaryKey() (*rsa.PrivateKey, *rsa.PublicKey, error) {
+	bits := Default.KeyBits
+	if bits == 0 {
+		bits = crypto.DefaultRSABits
+	}
+	if Default.KeyPool != nil {
+		if privateKey, err := Default.KeyPool.Take(bits); err == nil {
+			return privateKey, &privateKey.PublicKey, nil
+		}
+	}
+	return crypto.GenerateRSAKeyPairBits(bits)
+}
+
+// StartSession saves the temporary key of a receive, so the receive can be resumed
+// with --resume if AirBridge is closed. It returns an empty ID when sessions are off
+func StartSession(privateKey crypto.PrivateKey) (string, error) {
+	if Default.Sessions == nil || Default.SessionTTL <= 0 {
+		return "", nil
+	}
+	s, err := Default.Sessions.Create(privateKey, Default.SessionTTL)
+	if err != nil {
+		return "", fmt.Errorf("could not save receive session: %w", err)
+	}
+	return s.ID, nil
+}
+
+// EndSession deletes a session once its payload was decrypted or the receive was
+// cancelled, so its key can't be used again. An empty ID or a session that is
+// already gone is ignored
+func EndSession(id string) error {
+	if id == "" || Default.Sessions == nil {
+		return nil
+	}
+	if err := Default.Sessions.Delete(id); err != nil && !errors.Is(err, session.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// PurgeSessions deletes the receive sessions that have expired. It runs even when
+// SessionTTL is zero, so sessions left from before it was turned off still go
+func PurgeSessions() error {
+	if Default.Sessions == nil {
+		return nil
+	}
+	if _, err := Default.Sessions.Purge(); err != nil {
+		return fmt.Errorf("could not purge expired sessions: %w", err)
+	}
+	return nil
+}
+
+// EncodePayload writes a base64 payload in the configured encoding
+func EncodePayload(payload string) string {
+	if Default.PayloadEncoding != EncodingArmor {
+		return payload
+	}
+
+	var b strings.Builder
+	b.WriteString(armorHeader + "\n")
+	for len(payload) > armorWidth {
+		b.WriteString(payload[:armorWidth] + "\n")
+		payload = payload[armorWidth:]
+	}
+	if payload != "" {
+		b.WriteString(payload + "\n")
+	}
+	b.WriteString(armorFooter + "\n")
+	return b.String()
+}
+
+// Dearmor strips the armor lines from a payload, if there are any
+func Dearmor(text string) string {
+	start := strings.Index(text, armorHeader)
+	if start < 0 {
+		return text
+	}
+	text = text[start+len(armorHeader):]
+	if end := strings.Index(text, armorFooter); end >= 0 {
+		text = text[:end]
+	}
+	return text
+}
+
+// outputPath returns where a received file called name is saved,
+// following the output directory and the conflict policy.
+func outputPath(name string) (string, error) {
+	if Default.OutputDir != "" {
+		if err := os.MkdirAll(Default.OutputDir, 0755); err != nil {
+			return "", Errorf(ClassIO, "could not create output directory: %w", err)
+		}
+	}
+	path := filepath.Join(Default.OutputDir, name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return path, nil
+	}
+
+	switch Default.ConflictPolicy {
+	case ConflictFail:
+		return "", fmt.Errorf("%w: %s", ErrFileExists, path)
+	case ConflictRename:
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for i := 1; ; i++ {
+			candidate := filepath.Join(Default.OutputDir, fmt.Sprintf("%s-%d%s", base, i, ext))
+			if _, err := os.Stat(candidate); errors.Is(err, os.ErrNotExist) {
+				return candidate, nil
+			}
+		}
+	default:
+		return path, nil
+	}
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func withSettings(t *testing.T, settings Settings) {
+	previous := Default
+	t.Cleanup(func() { Default = previous })
+	if err := Configure(settings); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+}
+
+func TestArmorRoundTrip(t *testing.T) {
+	withSettings(t, Settings{PayloadEncoding: EncodingArmor, ConflictPolicy: ConflictOverwrite})
+
+	payload := strings.Repeat("QUJD", 40)
+	armored := EncodePayload(payload)
+	if !strings.HasPrefix(armored, armorHeader+"\n") {
+		t.Fatalf("Expected armor header, got %q", armored)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(armored), "\n") {
+		if len(line) > armorWidth {
+			t.Errorf("Line longer than %d characters: %q", armorWidth, line)
+		}
+	}
+
+	if got := strings.Join(strings.Fields(Dearmor(armored)), ""); got != payload {
+		t.Errorf("Dearmor() = %q, want %q", got, payload)
+	}
+	if got := Dearmor(payload); got != payload {
+		t.Error("Expected plain payloads to be left alone")
+	}
+}
+
+func TestConfigureRejectsUnknownValues(t *testing.T) {
+	if err := Configure(Settings{PayloadEncoding: "hex", ConflictPolicy: ConflictOverwrite}); err == nil {
+		t.Error("Expected error for unknown encoding")
+	}
+	if err := Configure(Settings{PayloadEncoding: EncodingBase64, ConflictPolicy: "sometimes"}); err == nil {
+		t.Error("Expected error for unknown conflict policy")
+	}
+}
+
+func TestOutputPathConflicts(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.pdf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		policy   string
+		expected string
+		wantErr  bool
+	}{
+		{ConflictOverwrite, "report.pdf", false},
+		{ConflictRename, "report-1.pdf", false},
+		{ConflictFail, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			withSettings(t, Settings{OutputDir: dir, PayloadEncoding: EncodingBase64, ConflictPolicy: tt.policy})
+			path, err := outputPath("report.pdf")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("outputPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && path != filepath.Join(dir, tt.expected) {
+				t.Errorf("outputPath() = %q, want %q", path, filepath.Join(dir, tt.expected))
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"AirBridge/internal/progress"
+)
+
+// Subdirectories of a watched directory that handled payloads are moved to.
+const (
+	WatchDone   = "done"
+	WatchFailed = "failed"
+)
+
+// PayloadExt is the extension of the payload files a Watcher picks up.
+const PayloadExt = ".abp"
+
+// DefaultWatchInterval is how often a Watcher scans its directory by default.
+const DefaultWatchInterval = 2 * time.Second
+
+// Watcher decrypts the payloads dropped into a directory, moving each one into
+// done/ or failed/ once it is handled
+type Watcher struct {
+	// Dir is the watched directory.
+	Dir string
+	// Keys are the private keys payloads are decrypted with. With more than one,
+	// the key is picked by the payload's recipient.
+	Keys []KeyFile
+	// Interval is how often Dir is scanned. A payload is only handled once its
+	// size stayed the same for a whole interval, so files still being written
+	// are left alone.
+	Interval time.Duration
+	// Log is called with every payload handled.
+	Log        func(WatchEvent)
+	OnProgress progress.Func
+
+	seen map[string]fileState
+	// stuck are the payloads that couldn't be moved out of Dir. They are left
+	// alone until they change, instead of being handled again every scan.
+	stuck map[string]fileState
+}
+
+// fileState is what a scan saw of a payload file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Run scans the directory until ctx is done. It only returns early if the
+// directory can't be read
+func (w *Watcher) Run(ctx context.Context) error {
+	for _, dir := range []string{WatchDone, WatchFailed} {
+		if err := os.MkdirAll(filepath.Join(w.Dir, dir), 0755); err != nil {
+			return Errorf(ClassIO, "could not create %s directory: %w", dir, err)
+		}
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Scan(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan handles the payloads whose size and modification time haven't changed
+// since the previous scan. The others are handled by a later scan
+func (w *Watcher) Scan() error {
+	entries, err := os.ReadDir(w.Dir)
+	if err != nil {
+		return Errorf(ClassIO, "error reading watched directory: %w", err)
+	}
+
+	seen := make(map[string]fileState)
+	stuck := make(map[string]fileState)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), PayloadExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read
+			continue
+		}
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if previous, ok := w.stuck[name]; ok && previous == state {
+			stuck[name] = state
+			continue
+		}
+		if previous, ok := w.seen[name]; !ok || previous != state || state.size == 0 {
+			seen[name] = state
+			continue
+		}
+		if !w.handle(name) {
+			stuck[name] = state
+		}
+	}
+	w.seen, w.stuck = seen, stuck
+	return nil
+}
+
+// handle decrypts a payload, moves it into done/ or failed/ and logs it. It
+// reports whether the payload was moved; one that wasn't is logged as failed.
+func (w *Watcher) handle(name string) bool {
+	path := filepath.Join(w.Dir, name)
+	event := WatchEvent{Payload: name, Status: WatchDone}
+	result, err := w.receive(path)
+	if err != nil {
+		event.Status = WatchFailed
+		event.Error, event.Class = err.Error(), ClassOf(err).String()
+	} else {
+		event.Result = &result
+	}
+
+	moved, err := movePayload(path, filepath.Join(w.Dir, event.Status))
+	event.Time = time.Now()
+	if err != nil {
+		moveErr := fmt.Sprintf("could not move it into %s/: %v", event.Status, err)
+		if event.Error != "" {
+			event.Error += "; " + moveErr
+		} else {
+			event.Error, event.Class = moveErr, ClassIO.String()
+		}
+		event.Status = WatchFailed
+	} else {
+		event.MovedTo = moved
+		if event.Result != nil {
+			event.Result.Payload = moved
+		}
+	}
+	if w.Log != nil {
+		w.Log(event)
+	}
+	return err == nil
+}
+
+func (w *Watcher) receive(path string) (ReceiveResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ReceiveResult{}, Errorf(ClassIO, "error reading payload file: %w", err)
+	}
+	if len(w.Keys) == 0 {
+		return ReceiveResult{}, Errorf(ClassUsage, "no private key to decrypt with")
+	}
+	key := w.Keys[0]
+	if len(w.Keys) > 1 {
+		if key, err = SelectKey(string(content), w.Keys); err != nil {
+			return ReceiveResult{}, err
+		}
+	}
+	privKeyPEM, err := os.ReadFile(key.Path)
+	if err != nil {
+		return ReceiveResult{}, Errorf(ClassIO, "error reading private key file: %w", err)
+	}
+	return RunReceive(string(content), privKeyPEM, path, false, w.OnProgress)
+}
+
+// movePayload moves the file at path into dir, renaming it if dir already has a
+// file of that name, and returns where it was moved.
+func movePayload(path, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	target := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		target = filepath.Join(dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext))
+	}
+	if err := os.Rename(path, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
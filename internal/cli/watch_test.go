@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"AirBridge/internal/crypto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWatcherScan(t *testing.T) {
+	out := t.TempDir()
+	t.Chdir(out)
+	dir := t.TempDir()
+
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "priv.pem")
+	privPEM, _ := crypto.ExportRSAPrivateKeyAsPEM(privKey)
+	if err := os.WriteFile(keyPath, privPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadPrivateKeys(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []WatchEvent
+	w := &Watcher{Dir: dir, Keys: keys, Log: func(e WatchEvent) { events = append(events, e) }}
+	scan := func() {
+		t.Helper()
+		if err := w.Scan(); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+	}
+
+	// A payload still being written is left alone until its size settles
+	payload := encryptForTest(t, "watched", pubKey)
+	good := filepath.Join(dir, "good.abp")
+	if err := os.WriteFile(good, []byte(payload[:len(payload)/2]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scan()
+	if err := os.WriteFile(good, []byte(payload), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scan()
+	if len(events) != 0 {
+		t.Fatalf("Expected no events while the payload changes, got %+v", events)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.abp"), []byte("not a payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scan()
+	if len(events) != 1 || events[0].Status != WatchDone || events[0].Result == nil {
+		t.Fatalf("Expected the good payload to be done, got %+v", events)
+	}
+	content, err := os.ReadFile(filepath.Join(out, "secret.txt"))
+	if err != nil || string(content) != "watched" {
+		t.Errorf("Expected the decrypted file, got %q (%v)", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, WatchDone, "good.abp")); err != nil {
+		t.Errorf("Expected the payload in done/: %v", err)
+	}
+
+	scan()
+	if len(events) != 2 || events[1].Status != WatchFailed || events[1].Error == "" {
+		t.Fatalf("Expected the bad payload to fail, got %+v", events)
+	}
+	if _, err := os.Stat(filepath.Join(dir, WatchFailed, "bad.abp")); err != nil {
+		t.Errorf("Expected the payload in failed/: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("Files that aren't payloads must be left alone: %v", err)
+	}
+
+	// A payload of the same name is renamed when moved
+	if err := os.WriteFile(filepath.Join(dir, "bad.abp"), []byte("still not a payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scan()
+	scan()
+	if len(events) != 3 || filepath.Base(events[2].MovedTo) != "bad-1.abp" {
+		t.Errorf("Expected the second bad payload to be moved to bad-1.abp, got %+v", events)
+	}
+}
+
+func TestWatcherKeepsGoingWhenMoveFails(t *testing.T) {
+	dir := t.TempDir()
+	// failed/ is a file, so nothing can be moved into it
+	if err := os.WriteFile(filepath.Join(dir, WatchFailed), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.abp"), []byte("not a payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []WatchEvent
+	w := &Watcher{Dir: dir, Log: func(e WatchEvent) { events = append(events, e) }}
+	for range 4 {
+		if err := w.Scan(); err != nil {
+			t.Fatalf("Expected the watcher to keep going, got %v", err)
+		}
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected the payload to be logged once, got %+v", events)
+	}
+	if e := events[0]; e.Status != WatchFailed || e.MovedTo != "" || !strings.Contains(e.Error, "could not move") {
+		t.Errorf("Expected a failed event for the move, got %+v", e)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad.abp")); err != nil {
+		t.Errorf("Expected the payload to stay where it was: %v", err)
+	}
+
+	// Once it changes, it is handled again
+	if err := os.WriteFile(filepath.Join(dir, "bad.abp"), []byte("still not a payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := w.Scan(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected the changed payload to be handled again, got %+v", events)
+	}
+}
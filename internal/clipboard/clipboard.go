@@ -0,0 +1,148 @@
+package clipboard
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend names accepted by Detect.
+const (
+	BackendAuto   = "auto"
+	BackendSystem = "system"
+	BackendOSC52  = "osc52"
+)
+
+// Backend writes text to a clipboard.
+type Backend interface {
+	Name() string
+	Write(text string) error
+	Read() (string, error)
+	Clear() error
+}
+
+// Clipboard copies text through a backend and remembers secret content,
+// so it can be cleared again once ClearAfter has passed.
+type Clipboard struct {
+	Backend    Backend
+	ClearAfter time.Duration
+
+	mu       sync.Mutex
+	hash     [sha256.Size]byte
+	deadline time.Time
+}
+
+// Default is the clipboard used by the TUI. It is configured by the cmd package.
+var Default = New(Detect(BackendAuto), 45*time.Second)
+
+// New returns a clipboard using the given backend.
+func New(backend Backend, clearAfter time.Duration) *Clipboard {
+	return &Clipboard{Backend: backend, ClearAfter: clearAfter}
+}
+
+// Configure replaces the Default clipboard.
+func Configure(backendName string, clearAfter time.Duration) error {
+	name := strings.ToLower(strings.TrimSpace(backendName))
+	switch name {
+	case "", BackendAuto, BackendSystem, BackendOSC52:
+	default:
+		return fmt.Errorf("unknown clipboard backend %q (use auto, system or osc52)", backendName)
+	}
+	Default = New(Detect(name), clearAfter)
+	return nil
+}
+
+// Detect returns the backend for name. For "auto" it picks OSC 52 when
+// running under SSH or tmux, where the system clipboard belongs to another machine.
+func Detect(name string) Backend {
+	switch name {
+	case BackendSystem:
+		return SystemBackend{}
+	case BackendOSC52:
+		return NewOSC52Backend(os.Stderr)
+	}
+	if IsRemoteSession() || os.Getenv("TMUX") != "" {
+		return NewOSC52Backend(os.Stderr)
+	}
+	return SystemBackend{}
+}
+
+// IsRemoteSession reports whether AirBridge runs in an SSH session.
+func IsRemoteSession() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_CLIENT") != ""
+}
+
+// Copy writes text to the clipboard. Secrets are cleared after ClearAfter.
+func (c *Clipboard) Copy(text string, secret bool) error {
+	if err := c.Backend.Write(text); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if secret && c.ClearAfter > 0 {
+		c.hash = sha256.Sum256([]byte(text))
+		c.deadline = time.Now().Add(c.ClearAfter)
+	} else {
+		c.deadline = time.Time{}
+	}
+	return nil
+}
+
+// Read returns the clipboard content.
+func (c *Clipboard) Read() (string, error) {
+	return c.Backend.Read()
+}
+
+// Pending returns the time left until copied secret text gets cleared.
+func (c *Clipboard) Pending() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deadline.IsZero() {
+		return 0, false
+	}
+	remaining := time.Until(c.deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// Hash returns the hex encoded SHA256 of the secret waiting to be cleared.
+func (c *Clipboard) Hash() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprintf("%x", c.hash)
+}
+
+// Expire clears the clipboard if the secret's deadline has passed.
+// It returns true if the clipboard was cleared.
+func (c *Clipboard) Expire() (bool, error) {
+	c.mu.Lock()
+	if c.deadline.IsZero() || time.Now().Before(c.deadline) {
+		c.mu.Unlock()
+		return false, nil
+	}
+	hash := fmt.Sprintf("%x", c.hash)
+	c.deadline = time.Time{}
+	c.mu.Unlock()
+
+	return ClearIfMatches(c.Backend, hash)
+}
+
+// ClearIfMatches clears the clipboard if it still holds the text with the given hash.
+// Backends that cannot be read are cleared unconditionally.
+func ClearIfMatches(backend Backend, hash string) (bool, error) {
+	current, err := backend.Read()
+	if err == nil && fmt.Sprintf("%x", sha256.Sum256([]byte(current))) != hash {
+		// The user copied something else in the meantime
+		return false, nil
+	}
+	if err := backend.Clear(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,111 @@
+package clipboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memoryBackend struct {
+	content string
+	cleared int
+}
+
+func (b *memoryBackend) Name() string            { return "memory" }
+func (b *memoryBackend) Write(text string) error { b.content = text; return nil }
+func (b *memoryBackend) Read() (string, error)   { return b.content, nil }
+func (b *memoryBackend) Clear() error            { b.content = ""; b.cleared++; return nil }
+
+func TestCopySecretIsCleared(t *testing.T) {
+	backend := &memoryBackend{}
+	c := New(backend, time.Millisecond)
+
+	if err := c.Copy("secret payload", true); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if _, ok := c.Pending(); !ok {
+		t.Fatal("Expected pending clear for secret")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	cleared, err := c.Expire()
+	if err != nil {
+		t.Fatalf("Expire failed: %v", err)
+	}
+	if !cleared || backend.content != "" {
+		t.Error("Expected clipboard to be cleared")
+	}
+	if _, ok := c.Pending(); ok {
+		t.Error("Expected no pending clear after expiring")
+	}
+}
+
+func TestCopyPublicTextIsKept(t *testing.T) {
+	backend := &memoryBackend{}
+	c := New(backend, time.Millisecond)
+
+	if err := c.Copy("public key", false); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if _, ok := c.Pending(); ok {
+		t.Error("Expected no pending clear for public text")
+	}
+}
+
+func TestExpireKeepsNewerContent(t *testing.T) {
+	backend := &memoryBackend{}
+	c := New(backend, time.Millisecond)
+
+	_ = c.Copy("secret payload", true)
+	backend.content = "something the user copied later"
+	time.Sleep(5 * time.Millisecond)
+
+	cleared, err := c.Expire()
+	if err != nil {
+		t.Fatalf("Expire failed: %v", err)
+	}
+	if cleared || backend.cleared != 0 {
+		t.Error("Expected newer clipboard content to be kept")
+	}
+}
+
+func TestOSC52Backend(t *testing.T) {
+	var out bytes.Buffer
+	backend := &OSC52Backend{Out: &out}
+
+	if err := backend.Write("hello"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// "hello" base64 encoded
+	if !strings.Contains(out.String(), "\x1b]52;c;aGVsbG8=") {
+		t.Errorf("Unexpected OSC 52 sequence %q", out.String())
+	}
+	if _, err := backend.Read(); err != ErrReadUnsupported {
+		t.Errorf("Expected ErrReadUnsupported, got %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("SSH_TTY", "")
+	t.Setenv("SSH_CONNECTION", "")
+	t.Setenv("SSH_CLIENT", "")
+	t.Setenv("TMUX", "")
+	if name := Detect(BackendAuto).Name(); name != BackendSystem {
+		t.Errorf("Expected system backend locally, got %s", name)
+	}
+
+	t.Setenv("SSH_CONNECTION", "10.0.0.1 1234 10.0.0.2 22")
+	if name := Detect(BackendAuto).Name(); name != BackendOSC52 {
+		t.Errorf("Expected osc52 backend over SSH, got %s", name)
+	}
+	if name := Detect(BackendSystem).Name(); name != BackendSystem {
+		t.Errorf("Expected explicit system backend, got %s", name)
+	}
+}
+
+func TestConfigureRejectsUnknownBackend(t *testing.T) {
+	if err := Configure("carrier-pigeon", time.Second); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
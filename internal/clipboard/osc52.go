@@ -0,0 +1,48 @@
+package clipboard
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// ErrReadUnsupported is returned by backends that can only write.
+var ErrReadUnsupported = errors.New("reading the clipboard is not supported by this backend")
+
+// OSC52Backend asks the terminal emulator to set its clipboard with an
+// OSC 52 escape sequence, so it works across SSH sessions.
+type OSC52Backend struct {
+	Out io.Writer
+	// Mode wraps the sequence for tmux or screen so it reaches the outer terminal.
+	Mode osc52.Mode
+}
+
+// NewOSC52Backend returns an OSC 52 backend writing to out, wrapped for tmux or screen when needed.
+func NewOSC52Backend(out io.Writer) *OSC52Backend {
+	mode := osc52.DefaultMode
+	if os.Getenv("TMUX") != "" {
+		mode = osc52.TmuxMode
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		mode = osc52.ScreenMode
+	}
+	return &OSC52Backend{Out: out, Mode: mode}
+}
+
+func (b *OSC52Backend) Name() string { return BackendOSC52 }
+
+func (b *OSC52Backend) Write(text string) error {
+	_, err := osc52.New(text).Mode(b.Mode).WriteTo(b.Out)
+	return err
+}
+
+func (b *OSC52Backend) Read() (string, error) {
+	return "", ErrReadUnsupported
+}
+
+func (b *OSC52Backend) Clear() error {
+	_, err := osc52.Clear().Mode(b.Mode).WriteTo(b.Out)
+	return err
+}
@@ -0,0 +1,21 @@
+package clipboard
+
+import "github.com/atotto/clipboard"
+
+// SystemBackend uses the clipboard of the machine AirBridge runs on
+// (pbcopy, xclip/xsel, wl-copy or the Windows clipboard).
+type SystemBackend struct{}
+
+func (SystemBackend) Name() string { return BackendSystem }
+
+func (SystemBackend) Write(text string) error {
+	return clipboard.WriteAll(text)
+}
+
+func (SystemBackend) Read() (string, error) {
+	return clipboard.ReadAll()
+}
+
+func (SystemBackend) Clear() error {
+	return clipboard.WriteAll("")
+}
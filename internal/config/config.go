@@ -0,0 +1,229 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// FileName is the name of the config file inside the config directory.
+const FileName = "config.toml"
+
+// EnvPrefix is the prefix of environment variables that override settings,
+// e.g. AIRBRIDGE_OUTPUT_DIR for output_dir.
+const EnvPrefix = "AIRBRIDGE_"
+
+// Config holds the user settings read from config.toml.
+type Config struct {
+	// Theme is a built-in theme or the name of a file in the themes directory.
+	Theme     string    `toml:"theme"`
+	Clipboard Clipboard `toml:"clipboard"`
+	// PrivateKey is the private key file receive decrypts with.
+	PrivateKey string `toml:"private_key"`
+	// Recipient is the public key file send encrypts for.
+	Recipient string `toml:"recipient"`
+	// OutputDir is the directory received files are saved to.
+	OutputDir string `toml:"output_dir"`
+	// PayloadEncoding is "base64" or "armor".
+	PayloadEncoding string `toml:"payload_encoding"`
+	// ConflictPolicy is "overwrite", "rename" or "fail" when a received file already exists.
+	ConflictPolicy string `toml:"conflict_policy"`
+	// Anonymous leaves the recipient's key ID out of payloads.
+	Anonymous bool `toml:"anonymous"`
+	// KeyType is the algorithm of generated keys. Only "rsa" is supported.
+	KeyType string `toml:"key_type"`
+	// KeyBits is the size of generated keys, including the ephemeral ones of receive.
+	KeyBits int `toml:"key_bits"`
+	// MinKeyBits is the smallest recipient key send accepts.
+	MinKeyBits int `toml:"min_key_bits"`
+	// KeyPool is how many ephemeral keys to keep generated ahead for receive. Zero disables the pool.
+	KeyPool int `toml:"key_pool"`
+	// SessionTTL is how long the temporary key of a receive is kept to resume it.
+	// Zero, the default, keeps none, so sessions are opt-in.
+	SessionTTL Duration `toml:"session_ttl"`
+	// Keys replaces the keys of TUI actions, e.g. copy = ["ctrl+y"].
+	Keys map[string][]string `toml:"keys"`
+	// Profiles are named sets of settings applied on top of the ones above.
+	Profiles map[string]toml.Primitive `toml:"profiles"`
+
+	meta toml.MetaData
+}
+
+// Clipboard configures how AirBridge copies text.
+type Clipboard struct {
+	// Backend is one of "auto", "system" or "osc52".
+	Backend string `toml:"backend"`
+	// ClearAfter clears copied payloads and secrets after this long. Zero disables it.
+	ClearAfter Duration `toml:"clear_after"`
+}
+
+// Duration is a time.Duration written as "30s", "2m" etc. in the config file.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", text, err)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Default returns the settings used when there is no config file.
+func Default() Config {
+	return Config{
+		Theme: "dark",
+		Clipboard: Clipboard{
+			Backend:    "auto",
+			ClearAfter: Duration{45 * time.Second},
+		},
+		PayloadEncoding: "base64",
+		ConflictPolicy:  "overwrite",
+		KeyType:         "rsa",
+		KeyBits:         2048,
+		MinKeyBits:      2048,
+	}
+}
+
+// Dir returns the AirBridge config directory ($XDG_CONFIG_HOME/airbridge).
+func Dir() (string, error) {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		var err error
+		base, err = os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("could not find config directory: %w", err)
+		}
+	}
+	return filepath.Join(base, "airbridge"), nil
+}
+
+// Path returns the path of the config file.
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// ThemesDir returns the directory user themes are loaded from.
+func ThemesDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "themes"), nil
+}
+
+// KeysDir returns the directory of the key store.
+func KeysDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keys"), nil
+}
+
+// IdentitiesDir returns the directory of the identity bundles.
+func IdentitiesDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "identities"), nil
+}
+
+// KeyPoolDir returns the directory of the ephemeral key pool. It is in the
+// cache directory, while the key it is encrypted with is in the config directory.
+func KeyPoolDir() (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keypool"), nil
+}
+
+// SessionsDir returns the directory of resumable receive sessions. Like the key
+// pool, it is in the cache directory.
+func SessionsDir() (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
+
+// CacheDir returns the AirBridge cache directory ($XDG_CACHE_HOME/airbridge).
+func CacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find cache directory: %w", err)
+	}
+	return filepath.Join(base, "airbridge"), nil
+}
+
+// Load reads the config file, falling back to defaults if it doesn't exist.
+func Load() (Config, error) {
+	path, err := Path()
+	if err != nil {
+		return Default(), err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads the config file at path on top of the defaults.
+func LoadFile(path string) (Config, error) {
+	cfg := Default()
+	meta, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("could not read config file %s: %w", path, err)
+	}
+	cfg.meta = meta
+	return cfg, nil
+}
+
+// UseProfile applies the settings of the named profile.
+func (c *Config) UseProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	if err := c.meta.PrimitiveDecode(profile, c); err != nil {
+		return fmt.Errorf("invalid profile %q: %w", name, err)
+	}
+	return nil
+}
+
+// ApplyEnv overrides settings with the AIRBRIDGE_* environment variables that are set.
+func (c *Config) ApplyEnv() error {
+	for _, name := range Names {
+		value, ok := os.LookupEnv(EnvName(name))
+		if !ok {
+			continue
+		}
+		if err := c.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvName(name), err)
+		}
+	}
+	return nil
+}
+
+// EnvName returns the environment variable that overrides a setting.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadFileMissing(t *testing.T) {
+	cfg, err := LoadFile(filepath.Join(t.TempDir(), "missing.toml"))
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Clipboard.Backend != "auto" {
+		t.Errorf("Expected default backend auto, got %q", cfg.Clipboard.Backend)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	content := "[clipboard]\nbackend = \"osc52\"\nclear_after = \"10s\"\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Clipboard.Backend != "osc52" {
+		t.Errorf("Expected backend osc52, got %q", cfg.Clipboard.Backend)
+	}
+	if cfg.Clipboard.ClearAfter.Duration != 10*time.Second {
+		t.Errorf("Expected clear_after 10s, got %v", cfg.Clipboard.ClearAfter)
+	}
+}
+
+func TestLoadFileInvalidDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("[clipboard]\nclear_after = \"soon\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadFile(path); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+}
+
+func TestDirUsesXDG(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	dir, err := Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join("/tmp/xdg", "airbridge") {
+		t.Errorf("Unexpected config dir %s", dir)
+	}
+}
+
+func TestLoadFileKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	content := "[keys]\ncopy = [\"ctrl+y\"]\nsubmit = [\"ctrl+d\", \"alt+enter\"]\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(cfg.Keys["submit"]) != 2 || cfg.Keys["copy"][0] != "ctrl+y" {
+		t.Errorf("Unexpected keys %v", cfg.Keys)
+	}
+}
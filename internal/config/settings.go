@@ -0,0 +1,229 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Names lists the settings that can be read and changed by name.
+var Names = []string{
+	"theme",
+	"clipboard.backend",
+	"clipboard.clear_after",
+	"private_key",
+	"recipient",
+	"output_dir",
+	"payload_encoding",
+	"conflict_policy",
+	"anonymous",
+	"key_type",
+	"key_bits",
+	"min_key_bits",
+	"key_pool",
+	"session_ttl",
+}
+
+// MaxKeyPool is the largest key_pool setting.
+const MaxKeyPool = 20
+
+var (
+	clipboardBackends = []string{"auto", "system", "osc52"}
+	payloadEncodings  = []string{"base64", "armor"}
+	conflictPolicies  = []string{"overwrite", "rename", "fail"}
+	keyTypes          = []string{"rsa"}
+	keySizes          = []string{"2048", "3072", "4096"}
+)
+
+// Get returns a setting as it would be written in the config file.
+func (c *Config) Get(name string) (string, error) {
+	switch name {
+	case "theme":
+		return c.Theme, nil
+	case "clipboard.backend":
+		return c.Clipboard.Backend, nil
+	case "clipboard.clear_after":
+		return c.Clipboard.ClearAfter.String(), nil
+	case "private_key":
+		return c.PrivateKey, nil
+	case "recipient":
+		return c.Recipient, nil
+	case "output_dir":
+		return c.OutputDir, nil
+	case "payload_encoding":
+		return c.PayloadEncoding, nil
+	case "conflict_policy":
+		return c.ConflictPolicy, nil
+	case "anonymous":
+		return strconv.FormatBool(c.Anonymous), nil
+	case "key_type":
+		return c.KeyType, nil
+	case "key_bits":
+		return strconv.Itoa(c.KeyBits), nil
+	case "min_key_bits":
+		return strconv.Itoa(c.MinKeyBits), nil
+	case "key_pool":
+		return strconv.Itoa(c.KeyPool), nil
+	case "session_ttl":
+		return c.SessionTTL.String(), nil
+	default:
+		return "", unknownSetting(name)
+	}
+}
+
+// Set changes a setting from its text form, checking that the value is valid.
+func (c *Config) Set(name, value string) error {
+	switch name {
+	case "theme":
+		c.Theme = value
+	case "clipboard.backend":
+		if err := oneOf(value, clipboardBackends); err != nil {
+			return err
+		}
+		c.Clipboard.Backend = value
+	case "clipboard.clear_after":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		c.Clipboard.ClearAfter = Duration{d}
+	case "private_key":
+		c.PrivateKey = value
+	case "recipient":
+		c.Recipient = value
+	case "output_dir":
+		c.OutputDir = value
+	case "payload_encoding":
+		if err := oneOf(value, payloadEncodings); err != nil {
+			return err
+		}
+		c.PayloadEncoding = value
+	case "conflict_policy":
+		if err := oneOf(value, conflictPolicies); err != nil {
+			return err
+		}
+		c.ConflictPolicy = value
+	case "anonymous":
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		c.Anonymous = b
+	case "key_type":
+		if err := oneOf(value, keyTypes); err != nil {
+			return err
+		}
+		c.KeyType = value
+	case "key_bits":
+		if err := oneOf(value, keySizes); err != nil {
+			return err
+		}
+		c.KeyBits, _ = strconv.Atoi(value)
+	case "min_key_bits":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid key size %q", value)
+		}
+		c.MinKeyBits = n
+	case "key_pool":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > MaxKeyPool {
+			return fmt.Errorf("invalid key pool size %q (0 to %d)", value, MaxKeyPool)
+		}
+		c.KeyPool = n
+	case "session_ttl":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		c.SessionTTL = Duration{d}
+	default:
+		return unknownSetting(name)
+	}
+	return nil
+}
+
+// SetFile writes a single setting to the config file at path, inside the
+// named profile if profile isn't empty. Comments in the file are not kept.
+func SetFile(path, profile, name, value string) error {
+	check := Default()
+	if err := check.Set(name, value); err != nil {
+		return err
+	}
+
+	raw := map[string]any{}
+	if _, err := toml.DecodeFile(path, &raw); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not read config file %s: %w", path, err)
+	}
+
+	table := raw
+	if profile != "" {
+		table = subtable(subtable(table, "profiles"), profile)
+	}
+	parts := strings.Split(name, ".")
+	for _, part := range parts[:len(parts)-1] {
+		table = subtable(table, part)
+	}
+	switch name {
+	case "anonymous":
+		table[parts[len(parts)-1]] = check.Anonymous
+	case "key_bits":
+		table[parts[len(parts)-1]] = check.KeyBits
+	case "min_key_bits":
+		table[parts[len(parts)-1]] = check.MinKeyBits
+	case "key_pool":
+		table[parts[len(parts)-1]] = check.KeyPool
+	default:
+		table[parts[len(parts)-1]] = value
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("could not write config file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if err := toml.NewEncoder(file).Encode(raw); err != nil {
+		return fmt.Errorf("could not write config file: %w", err)
+	}
+	return nil
+}
+
+// subtable returns the table stored under key, creating it if needed.
+func subtable(parent map[string]any, key string) map[string]any {
+	if table, ok := parent[key].(map[string]any); ok {
+		return table
+	}
+	table := map[string]any{}
+	parent[key] = table
+	return table
+}
+
+func parseBool(value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean %q", value)
+	}
+	return b, nil
+}
+
+func oneOf(value string, allowed []string) error {
+	if !slices.Contains(allowed, value) {
+		return fmt.Errorf("invalid value %q (expected one of %s)", value, strings.Join(allowed, ", "))
+	}
+	return nil
+}
+
+func unknownSetting(name string) error {
+	return fmt.Errorf("unknown setting %q (expected one of %s)", name, strings.Join(Names, ", "))
+}
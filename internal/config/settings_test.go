@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetAndGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"theme", "light", false},
+		{"clipboard.backend", "osc52", false},
+		{"clipboard.backend", "carrier-pigeon", true},
+		{"clipboard.clear_after", "10s", false},
+		{"clipboard.clear_after", "soon", true},
+		{"payload_encoding", "armor", false},
+		{"payload_encoding", "hex", true},
+		{"conflict_policy", "rename", false},
+		{"conflict_policy", "sometimes", true},
+		{"anonymous", "true", false},
+		{"anonymous", "maybe", true},
+		{"output_dir", "/tmp/in", false},
+		{"key_type", "rsa", false},
+		{"key_type", "dsa", true},
+		{"key_bits", "4096", false},
+		{"key_bits", "1000", true},
+		{"min_key_bits", "3072", false},
+		{"min_key_bits", "-1", true},
+		{"key_pool", "3", false},
+		{"key_pool", "100", true},
+		{"session_ttl", "2h0m0s", false},
+		{"session_ttl", "-1h", true},
+		{"unknown", "x", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			cfg := Default()
+			err := cfg.Set(tt.name, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := cfg.Get(tt.name)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			if got != tt.value {
+				t.Errorf("Get() = %q, want %q", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestUseProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	content := `theme = "light"
+recipient = "base.pem"
+
+[profiles.work]
+recipient = "work.pem"
+anonymous = true
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if err := cfg.UseProfile("work"); err != nil {
+		t.Fatalf("UseProfile failed: %v", err)
+	}
+	if cfg.Recipient != "work.pem" || !cfg.Anonymous {
+		t.Errorf("Expected profile settings, got recipient %q, anonymous %v", cfg.Recipient, cfg.Anonymous)
+	}
+	if cfg.Theme != "light" {
+		t.Errorf("Expected theme from the top level, got %q", cfg.Theme)
+	}
+	if err := cfg.UseProfile("home"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("AIRBRIDGE_OUTPUT_DIR", "/tmp/in")
+	t.Setenv("AIRBRIDGE_CLIPBOARD_BACKEND", "system")
+
+	cfg := Default()
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	if cfg.OutputDir != "/tmp/in" || cfg.Clipboard.Backend != "system" {
+		t.Errorf("Expected env overrides, got %+v", cfg)
+	}
+
+	t.Setenv("AIRBRIDGE_ANONYMOUS", "maybe")
+	if err := cfg.ApplyEnv(); err == nil {
+		t.Error("Expected error for invalid AIRBRIDGE_ANONYMOUS")
+	}
+}
+
+func TestSetFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airbridge", FileName)
+
+	if err := SetFile(path, "", "clipboard.backend", "osc52"); err != nil {
+		t.Fatalf("SetFile failed: %v", err)
+	}
+	if err := SetFile(path, "work", "anonymous", "true"); err != nil {
+		t.Fatalf("SetFile failed: %v", err)
+	}
+	if err := SetFile(path, "", "payload_encoding", "hex"); err == nil {
+		t.Error("Expected invalid value to be rejected")
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Clipboard.Backend != "osc52" {
+		t.Errorf("Expected backend osc52, got %q", cfg.Clipboard.Backend)
+	}
+	if cfg.Anonymous {
+		t.Error("Expected anonymous only in the work profile")
+	}
+	if err := cfg.UseProfile("work"); err != nil || !cfg.Anonymous {
+		t.Errorf("Expected anonymous in the work profile (%v)", err)
+	}
+}
@@ -11,7 +11,7 @@ import (
 func GenerateAESKey() ([]byte, error) {
 	key := make([]byte, 32) // 32 bytes = 256 bits
 	if _, err := rand.Read(key); err != nil {
-		return nil, fmt.Errorf("could not generate symmetric key: %v", err)
+		return nil, fmt.Errorf("could not generate symmetric key: %w", err)
 	}
 	return key, nil
 }
@@ -21,7 +21,7 @@ func GenerateIV() ([]byte, error) {
 	// GCM standard nonce size is 12 bytes
 	nonce := make([]byte, 12)
 	if _, err := rand.Read(nonce); err != nil {
-		return nil, fmt.Errorf("could not generate nonce: %v", err)
+		return nil, fmt.Errorf("could not generate nonce: %w", err)
 	}
 	return nonce, nil
 }
@@ -30,12 +30,12 @@ func GenerateIV() ([]byte, error) {
 func EncryptDataAES(key, nonce, data []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, fmt.Errorf("could not create AES cipher: %v", err)
+		return nil, fmt.Errorf("could not create AES cipher: %w", err)
 	}
 
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		return nil, fmt.Errorf("could not create GCM: %v", err)
+		return nil, fmt.Errorf("could not create GCM: %w", err)
 	}
 
 	// Seal encrypts and authenticates the data.
@@ -50,17 +50,17 @@ func EncryptDataAES(key, nonce, data []byte) ([]byte, error) {
 func DecryptDataAES(key, nonce, data []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, fmt.Errorf("could not create AES cipher: %v", err)
+		return nil, fmt.Errorf("could not create AES cipher: %w", err)
 	}
 
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		return nil, fmt.Errorf("could not create GCM: %v", err)
+		return nil, fmt.Errorf("could not create GCM: %w", err)
 	}
 
 	plaintext, err := aesGCM.Open(nil, nonce, data, nil)
 	if err != nil {
-		return nil, fmt.Errorf("could not decrypt/authenticate data: %v", err)
+		return nil, fmt.Errorf("%w: could not decrypt/authenticate data: %w", ErrAuthenticationFailed, err)
 	}
 
 	return plaintext, nil
@@ -1,13 +1,18 @@
 package crypto
 
 import (
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"os"
+	"strings"
 	"testing"
+
+	"golang.org/x/crypto/ssh"
 )
 
 func TestCalculateFileHash(t *testing.T) {
@@ -255,3 +260,297 @@ func TestDecodeRSAPrivateKey(t *testing.T) {
 		t.Error("Decoded private key D does not match original key")
 	}
 }
+
+func TestFingerprintRSAPublicKey(t *testing.T) {
+	_, pub1, err := GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pub2, err := GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fp1, err := FingerprintRSAPublicKey(pub1)
+	if err != nil {
+		t.Fatalf("FingerprintRSAPublicKey failed: %v", err)
+	}
+	if !strings.HasPrefix(fp1, "SHA256:") || len(fp1) != len("SHA256:")+43 {
+		t.Errorf("Unexpected fingerprint format %q", fp1)
+	}
+
+	again, _ := FingerprintRSAPublicKey(pub1)
+	fp2, _ := FingerprintRSAPublicKey(pub2)
+	if fp1 != again {
+		t.Error("Expected the same key to have the same fingerprint")
+	}
+	if fp1 == fp2 {
+		t.Error("Expected different keys to have different fingerprints")
+	}
+}
+
+func TestDecryptionErrors(t *testing.T) {
+	_, publicKey, err := GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _, err := GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedKey, err := EncryptAESKeyWithRSA(publicKey, []byte("12345678901234567890123456789012"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptAESKeyWithRSA(otherKey, encryptedKey); !errors.Is(err, ErrUnwrapFailed) {
+		t.Errorf("Expected ErrUnwrapFailed, got %v", err)
+	}
+
+	key, _ := GenerateAESKey()
+	nonce, _ := GenerateIV()
+	encrypted, err := EncryptDataAES(key, nonce, []byte("test data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted[0] ^= 0xff
+	if _, err := DecryptDataAES(key, nonce, encrypted); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Errorf("Expected ErrAuthenticationFailed, got %v", err)
+	}
+
+	if _, err := DecodeRSAPublicKey("not a key"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for public key, got %v", err)
+	}
+	if _, err := DecodeRSAPrivateKey([]byte("not a key")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for private key, got %v", err)
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	fingerprint := "SHA256:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU"
+	if id := KeyID(fingerprint); id != "47DEQpj8HBSa+/TI" {
+		t.Errorf("KeyID() = %q", id)
+	}
+	if id := KeyID("SHA256:short"); id != "short" {
+		t.Errorf("KeyID() = %q, want %q", id, "short")
+	}
+}
+
+func TestKeySizes(t *testing.T) {
+	privateKey, publicKey, err := GenerateRSAKeyPairBits(3072)
+	if err != nil {
+		t.Fatalf("GenerateRSAKeyPairBits failed: %v", err)
+	}
+	if privateKey.N.BitLen() != 3072 {
+		t.Errorf("Expected a 3072 bit key, got %d", privateKey.N.BitLen())
+	}
+	if _, _, err := GenerateRSAKeyPairBits(1000); err == nil {
+		t.Error("Expected an error for an unsupported key size")
+	}
+
+	pubPEM, err := ExportRSAPublicKeyAsPEM(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeRSAPublicKey(string(pubPEM))
+	if err != nil {
+		t.Fatalf("Decoding doesn't depend on the key size: %v", err)
+	}
+	if err := CheckKeyStrength(decoded, 4096); !errors.Is(err, ErrWeakKey) {
+		t.Errorf("Expected ErrWeakKey below the minimum, got %v", err)
+	}
+	if err := CheckKeyStrength(decoded, 3072); err != nil {
+		t.Errorf("Expected the key to meet the minimum: %v", err)
+	}
+}
+
+func TestDecodeKeyFormats(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openSSH, err := ssh.MarshalPrivateKey(edPrivate, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected, err := ssh.MarshalPrivateKeyWithPassphrase(edPrivate, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateTests := []struct {
+		name    string
+		pem     []byte
+		keyType string
+	}{
+		{"PKCS#8 RSA", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), TypeRSA},
+		{"OpenSSH Ed25519", pem.EncodeToMemory(openSSH), TypeEd25519},
+	}
+	for _, tt := range privateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := DecodePrivateKey(tt.pem)
+			if err != nil {
+				t.Fatalf("DecodePrivateKey failed: %v", err)
+			}
+			if got := KeyType(PublicKeyOf(key)); got != tt.keyType {
+				t.Errorf("KeyType() = %q, want %q", got, tt.keyType)
+			}
+		})
+	}
+	if _, err := DecodePrivateKey(pem.EncodeToMemory(protected)); !errors.Is(err, ErrInvalidKey) || !strings.Contains(err.Error(), "passphrase") {
+		t.Errorf("Expected a passphrase error, got %v", err)
+	}
+
+	sshPublic, err := ssh.NewPublicKey(edPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(ssh.MarshalAuthorizedKey(sshPublic))
+	publicKey, err := DecodePublicKey(strings.TrimSpace(line) + " me@laptop\n")
+	if err != nil {
+		t.Fatalf("DecodePublicKey failed for an authorized_keys line: %v", err)
+	}
+	if !edPublic.Equal(publicKey) || KeyBits(publicKey) != 256 {
+		t.Errorf("Expected the Ed25519 key back, got %v", publicKey)
+	}
+	if _, err := DecodeRSAPublicKey(line); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected DecodeRSAPublicKey to reject an Ed25519 key, got %v", err)
+	}
+}
+
+func TestWrapKeyEd25519(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesKey, err := GenerateAESKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped, err := WrapKey(publicKey, aesKey)
+	if err != nil {
+		t.Fatalf("WrapKey failed: %v", err)
+	}
+	if len(wrapped) != x25519WrappedSize {
+		t.Errorf("Expected %d bytes, got %d", x25519WrappedSize, len(wrapped))
+	}
+	unwrapped, err := UnwrapKey(privateKey, wrapped)
+	if err != nil {
+		t.Fatalf("UnwrapKey failed: %v", err)
+	}
+	if string(unwrapped) != string(aesKey) {
+		t.Error("Unwrapped key does not match")
+	}
+
+	if _, err := UnwrapKey(otherKey, wrapped); !errors.Is(err, ErrUnwrapFailed) {
+		t.Errorf("Expected ErrUnwrapFailed for another key, got %v", err)
+	}
+	rsaKey, _, err := GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnwrapKey(rsaKey, wrapped); !errors.Is(err, ErrUnwrapFailed) {
+		t.Errorf("Expected ErrUnwrapFailed for an RSA key, got %v", err)
+	}
+}
+
+func TestDecodePublicKeys(t *testing.T) {
+	_, rsaPublic, err := GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM, _ := ExportPublicKeyAsPEM(rsaPublic)
+	edPEM, _ := ExportPublicKeyAsPEM(edPublic)
+	rsaBase64, _ := EncodePublicKey(rsaPublic)
+	sshPublic, err := ssh.NewPublicKey(edPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshLine := string(ssh.MarshalAuthorizedKey(sshPublic))
+
+	// A base64 key wrapped over several lines is still one key
+	var wrapped strings.Builder
+	for rest := rsaBase64; rest != ""; {
+		n := min(64, len(rest))
+		wrapped.WriteString(rest[:n] + "\n")
+		rest = rest[n:]
+	}
+
+	tests := []struct {
+		name  string
+		text  string
+		count int
+	}{
+		{"One PEM key", string(rsaPEM), 1},
+		{"Wrapped base64 key", wrapped.String(), 1},
+		{"Several PEM blocks", string(rsaPEM) + string(edPEM), 2},
+		{"One key per line", rsaBase64 + "\n" + sshLine + "\n", 2},
+		{"Key with a statement block", string(rsaPEM) + "-----BEGIN AIRBRIDGE KEY STATEMENT-----\ne30=\n-----END AIRBRIDGE KEY STATEMENT-----\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := DecodePublicKeys(tt.text)
+			if err != nil {
+				t.Fatalf("DecodePublicKeys failed: %v", err)
+			}
+			if len(keys) != tt.count {
+				t.Errorf("Expected %d keys, got %d", tt.count, len(keys))
+			}
+		})
+	}
+
+	if _, err := DecodePublicKeys(rsaBase64 + "\nnot a key\n"); !errors.Is(err, ErrInvalidKey) || !strings.Contains(err.Error(), "key 2") {
+		t.Errorf("Expected an error naming the second key, got %v", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	rsaKey, _, err := GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("key statement")
+
+	for _, privateKey := range []PrivateKey{rsaKey, edKey} {
+		publicKey := PublicKeyOf(privateKey)
+		t.Run(KeyType(publicKey), func(t *testing.T) {
+			signature, err := Sign(privateKey, message)
+			if err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+			if err := VerifySignature(publicKey, message, signature); err != nil {
+				t.Errorf("VerifySignature failed: %v", err)
+			}
+			if err := VerifySignature(publicKey, []byte("another statement"), signature); !errors.Is(err, ErrBadSignature) {
+				t.Errorf("Expected ErrBadSignature for another message, got %v", err)
+			}
+		})
+	}
+
+	signature, _ := Sign(edKey, message)
+	if err := VerifySignature(PublicKeyOf(rsaKey), message, signature); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature for another key, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package crypto
+
+import "errors"
+
+var (
+	// ErrInvalidKey is returned for keys that can't be decoded or aren't RSA keys.
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrWeakKey is returned by CheckKeyStrength for keys smaller than the minimum.
+	ErrWeakKey = errors.New("key is too weak")
+	// ErrWrongRecipient is returned when the symmetric key was encrypted for another key pair.
+	ErrWrongRecipient = errors.New("payload was encrypted for a different key")
+	// ErrUnwrapFailed is returned when a wrapped symmetric key can't be decrypted. The
+	// wrapped key is damaged, or was made for another key pair of the same type.
+	ErrUnwrapFailed = errors.New("symmetric key could not be decrypted")
+	// ErrAuthenticationFailed is returned when AES-GCM finds the data was changed or cut off.
+	ErrAuthenticationFailed = errors.New("payload failed authentication")
+	// ErrBadSignature is returned when a signature wasn't made by the key's private key.
+	ErrBadSignature = errors.New("signature is invalid")
+)
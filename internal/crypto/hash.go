@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"AirBridge/internal/progress"
 	"crypto/sha256"
 	"fmt"
 	"io"
@@ -10,14 +11,27 @@ import (
 // CalculateFileHash computes the SHA256 hash of a file.
 // It resets the file pointer to the beginning before and after reading.
 func CalculateFileHash(file *os.File) (string, error) {
+	return CalculateFileHashWithProgress(file, nil)
+}
+
+// CalculateFileHashWithProgress computes the SHA256 hash of a file and reports
+// the bytes hashed so far to onProgress.
+func CalculateFileHashWithProgress(file *os.File, onProgress progress.Func) (string, error) {
 	if _, err := file.Seek(0, 0); err != nil {
 		return "", err
 	}
 
+	var total int64
+	if info, err := file.Stat(); err == nil {
+		total = info.Size()
+	}
+	tracker := progress.NewTracker("Hashing", total, onProgress)
+
 	hasher := sha256.New()
-	if _, err := io.Copy(hasher, file); err != nil {
+	if _, err := io.Copy(hasher, tracker.Reader(file)); err != nil {
 		return "", err
 	}
+	tracker.Finish()
 	fileHash := hasher.Sum(nil)
 
 	if _, err := file.Seek(0, 0); err != nil {
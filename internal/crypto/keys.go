@@ -0,0 +1,302 @@
+package crypto
+
+import (
+	stdcrypto "crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key types, as recorded in key metadata.
+const (
+	TypeRSA     = "rsa"
+	TypeEd25519 = "ed25519"
+)
+
+// PublicKey is an *rsa.PublicKey or an ed25519.PublicKey.
+type PublicKey = stdcrypto.PublicKey
+
+// PrivateKey is an *rsa.PrivateKey or an ed25519.PrivateKey.
+type PrivateKey = stdcrypto.PrivateKey
+
+// DecodePrivateKey decodes a PEM encoded private key: PKCS#1 or PKCS#8 RSA keys,
+// PKCS#8 Ed25519 keys and OpenSSH private key files.
+func DecodePrivateKey(pemBytes []byte) (PrivateKey, error) {
+	// Decode PEM block
+	pemBlock, _ := pem.Decode(pemBytes)
+	if pemBlock == nil {
+		return nil, fmt.Errorf("%w: could not decode PEM block", ErrInvalidKey)
+	}
+
+	var key any
+	var err error
+	switch pemBlock.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(pemBlock.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
+	case "OPENSSH PRIVATE KEY":
+		key, err = ssh.ParseRawPrivateKey(pemBytes)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("%w: the key is protected by a passphrase, which is not supported yet (remove it with ssh-keygen -p)", ErrInvalidKey)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM block %q", ErrInvalidKey, pemBlock.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not parse private key: %w", ErrInvalidKey, err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	case *ed25519.PrivateKey:
+		return *key, nil
+	default:
+		return nil, fmt.Errorf("%w: only RSA and Ed25519 keys are supported, got %T", ErrInvalidKey, key)
+	}
+}
+
+// DecodePublicKey decodes a public key given as PEM, as base64 encoded PEM (the form
+// the receive screen shows) or as an authorized_keys line ("ssh-ed25519 AAAA... comment").
+func DecodePublicKey(pubKeyStr string) (PublicKey, error) {
+	if line := strings.TrimSpace(pubKeyStr); strings.HasPrefix(line, "ssh-") {
+		return decodeAuthorizedKey(line)
+	}
+
+	// Base64 decode
+	pemBytes, err := base64.StdEncoding.DecodeString(pubKeyStr)
+	if err != nil {
+		// If base64 decoding fails, assume it's a raw PEM string
+		pemBytes = []byte(pubKeyStr)
+	}
+
+	// Decode PEM block
+	pemBlock, _ := pem.Decode(pemBytes)
+	if pemBlock == nil {
+		return nil, fmt.Errorf("%w: could not decode PEM block", ErrInvalidKey)
+	}
+
+	// Parse PKIX public key
+	key, err := x509.ParsePKIXPublicKey(pemBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not parse public key: %w", ErrInvalidKey, err)
+	}
+	return checkPublicKey(key)
+}
+
+// DecodePublicKeys decodes one or more public keys, for payloads sent to several
+// recipients: a single key in any form DecodePublicKey accepts, several PEM
+// blocks, or one key per line. PEM blocks that aren't keys, such as the key
+// statements kept with a public key, are skipped.
+func DecodePublicKeys(text string) ([]PublicKey, error) {
+	if strings.Count(text, "-----BEGIN") > 1 {
+		var keys []PublicKey
+		for rest := []byte(text); ; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if !strings.HasSuffix(block.Type, "KEY") {
+				continue
+			}
+			key, err := DecodePublicKey(string(pem.EncodeToMemory(block)))
+			if err != nil {
+				return nil, fmt.Errorf("key %d: %w", len(keys)+1, err)
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("%w: no public key found", ErrInvalidKey)
+		}
+		return keys, nil
+	}
+
+	// A base64 key may be wrapped over several lines, so lines are only taken as
+	// separate keys if the first one is a key on its own
+	lines := strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == '\r' })
+	if len(lines) > 1 {
+		if first, err := DecodePublicKey(lines[0]); err == nil {
+			keys := []PublicKey{first}
+			for _, line := range lines[1:] {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				key, err := DecodePublicKey(line)
+				if err != nil {
+					return nil, fmt.Errorf("key %d: %w", len(keys)+1, err)
+				}
+				keys = append(keys, key)
+			}
+			return keys, nil
+		}
+	}
+
+	key, err := DecodePublicKey(text)
+	if err != nil {
+		return nil, err
+	}
+	return []PublicKey{key}, nil
+}
+
+// decodeAuthorizedKey decodes a public key in the authorized_keys format.
+func decodeAuthorizedKey(line string) (PublicKey, error) {
+	sshKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not parse SSH public key: %w", ErrInvalidKey, err)
+	}
+	cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported SSH key type %s", ErrInvalidKey, sshKey.Type())
+	}
+	return checkPublicKey(cryptoKey.CryptoPublicKey())
+}
+
+// checkPublicKey returns key if it is a supported public key.
+func checkPublicKey(key any) (PublicKey, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return key, nil
+	case ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: only RSA and Ed25519 keys are supported, got %T", ErrInvalidKey, key)
+	}
+}
+
+// CheckKeyStrength returns ErrWeakKey if key is an RSA key of fewer than minBits
+// bits. Ed25519 keys have a fixed strength and always pass.
+func CheckKeyStrength(key PublicKey, minBits int) error {
+	if key, ok := key.(*rsa.PublicKey); ok {
+		if bits := key.N.BitLen(); bits < minBits {
+			return fmt.Errorf("%w: the key has %d bits, at least %d are required", ErrWeakKey, bits, minBits)
+		}
+	}
+	return nil
+}
+
+// PublicKeyOf returns the public half of a private key.
+func PublicKeyOf(privateKey PrivateKey) PublicKey {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &key.PublicKey
+	case ed25519.PrivateKey:
+		return key.Public()
+	default:
+		return nil
+	}
+}
+
+// KeyType returns TypeRSA or TypeEd25519.
+func KeyType(publicKey PublicKey) string {
+	if _, ok := publicKey.(ed25519.PublicKey); ok {
+		return TypeEd25519
+	}
+	return TypeRSA
+}
+
+// KeyBits returns the size of a public key in bits.
+func KeyBits(publicKey PublicKey) int {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case ed25519.PublicKey:
+		return 8 * ed25519.PublicKeySize
+	default:
+		return 0
+	}
+}
+
+// WrapKey encrypts an AES key for a public key: with RSA-OAEP for RSA keys,
+// and with X25519 (see wrapKeyX25519) for Ed25519 keys.
+func WrapKey(publicKey PublicKey, aesKey []byte) ([]byte, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return EncryptAESKeyWithRSA(key, aesKey)
+	case ed25519.PublicKey:
+		return wrapKeyX25519(key, aesKey)
+	default:
+		return nil, fmt.Errorf("%w: unsupported public key %T", ErrInvalidKey, publicKey)
+	}
+}
+
+// UnwrapKey decrypts an AES key wrapped by WrapKey. It returns ErrWrongRecipient
+// if the key was wrapped for another type of key, and ErrUnwrapFailed if it is
+// damaged or was wrapped for another key pair of the same type.
+func UnwrapKey(privateKey PrivateKey, wrappedKey []byte) ([]byte, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return DecryptAESKeyWithRSA(key, wrappedKey)
+	case ed25519.PrivateKey:
+		return unwrapKeyX25519(key, wrappedKey)
+	default:
+		return nil, fmt.Errorf("%w: unsupported private key %T", ErrInvalidKey, privateKey)
+	}
+}
+
+// FingerprintPublicKey returns the SHA256 fingerprint of a public key,
+// written like OpenSSH does ("SHA256:" and unpadded base64).
+func FingerprintPublicKey(publicKey PublicKey) (string, error) {
+	pubASN1, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal public key: %w", err)
+	}
+	sum := sha256.Sum256(pubASN1)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// EncodePublicKey encodes a public key to a base64 encoded PEM string.
+func EncodePublicKey(publicKey PublicKey) (string, error) {
+	pubBytes, err := ExportPublicKeyAsPEM(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pubBytes), nil
+}
+
+// ExportPublicKeyAsPEM exports a public key as PEM encoded bytes.
+func ExportPublicKeyAsPEM(publicKey PublicKey) ([]byte, error) {
+	pubASN1, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal public key: %w", err)
+	}
+
+	pubBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pubASN1,
+	})
+
+	return pubBytes, nil
+}
+
+// ExportPrivateKeyAsPEM exports a private key as PEM encoded bytes: PKCS#1 for
+// RSA keys, as before, and PKCS#8 for Ed25519 keys.
+func ExportPrivateKeyAsPEM(privateKey PrivateKey) ([]byte, error) {
+	if key, ok := privateKey.(*rsa.PrivateKey); ok {
+		return ExportRSAPrivateKeyAsPEM(key)
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal private key: %w", err)
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: privBytes,
+	})
+
+	return pemBytes, nil
+}
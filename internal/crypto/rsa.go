@@ -5,55 +5,42 @@ import (
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
-	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"slices"
+	"strings"
 )
 
-// DecodeRSAPrivateKey decodes a PEM encoded bytes into an RSA private key.
-func DecodeRSAPrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
-	// Decode PEM block
-	pemBlock, _ := pem.Decode(pemBytes)
-	if pemBlock == nil {
-		return nil, fmt.Errorf("could not decode PEM block")
-	}
-
-	// Parse PKCS1 private key
-	privateKey, err := x509.ParsePKCS1PrivateKey(pemBlock.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse private key: %v", err)
-	}
+// DefaultRSABits is the size of RSA keys generated when no size is given.
+const DefaultRSABits = 2048
 
-	return privateKey, nil
-}
+// RSAKeySizes are the sizes RSA keys can be generated with.
+var RSAKeySizes = []int{2048, 3072, 4096}
 
-// DecodeRSAPublicKey decodes a base64 encoded PEM string into an RSA public key.
-func DecodeRSAPublicKey(pubKeyStr string) (*rsa.PublicKey, error) {
-	// Base64 decode
-	pemBytes, err := base64.StdEncoding.DecodeString(pubKeyStr)
+// DecodeRSAPrivateKey decodes a PEM encoded private key (see DecodePrivateKey)
+// and checks that it is an RSA key.
+func DecodeRSAPrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	key, err := DecodePrivateKey(pemBytes)
 	if err != nil {
-		// If base64 decoding fails, assume it's a raw PEM string
-		pemBytes = []byte(pubKeyStr)
+		return nil, err
 	}
-
-	// Decode PEM block
-	pemBlock, _ := pem.Decode(pemBytes)
-	if pemBlock == nil {
-		return nil, fmt.Errorf("could not decode PEM block")
+	rsaPrivateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: the provided key is not an RSA private key", ErrInvalidKey)
 	}
+	return rsaPrivateKey, nil
+}
 
-	// Parse PKIX public key
-	genericPublicKey, err := x509.ParsePKIXPublicKey(pemBlock.Bytes)
+// DecodeRSAPublicKey decodes a public key (see DecodePublicKey) and checks that it is an RSA key.
+func DecodeRSAPublicKey(pubKeyStr string) (*rsa.PublicKey, error) {
+	key, err := DecodePublicKey(pubKeyStr)
 	if err != nil {
 		return nil, err
 	}
-
-	// Assert RSA public key
-	rsaPublicKey, ok := genericPublicKey.(*rsa.PublicKey)
+	rsaPublicKey, ok := key.(*rsa.PublicKey)
 	if !ok {
-		return nil, fmt.Errorf("the provided key is not an RSA public key")
+		return nil, fmt.Errorf("%w: the provided key is not an RSA public key", ErrInvalidKey)
 	}
-
 	return rsaPublicKey, nil
 }
 
@@ -61,48 +48,44 @@ func DecodeRSAPublicKey(pubKeyStr string) (*rsa.PublicKey, error) {
 func EncryptAESKeyWithRSA(publicKey *rsa.PublicKey, aesKey []byte) ([]byte, error) {
 	encryptedAESKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, aesKey, nil)
 	if err != nil {
-		return nil, fmt.Errorf("could not encrypt symmetric key with public key: %v", err)
+		return nil, fmt.Errorf("could not encrypt symmetric key with public key: %w", err)
 	}
 	return encryptedAESKey, nil
 }
 
-// GenerateRSAKeyPair generates a new RSA key pair of 2048 bits.
+// GenerateRSAKeyPair generates a new RSA key pair of DefaultRSABits bits.
 func GenerateRSAKeyPair() (*rsa.PrivateKey, *rsa.PublicKey, error) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	return GenerateRSAKeyPairBits(DefaultRSABits)
+}
+
+// GenerateRSAKeyPairBits generates a new RSA key pair of one of the RSAKeySizes.
+func GenerateRSAKeyPairBits(bits int) (*rsa.PrivateKey, *rsa.PublicKey, error) {
+	if err := CheckRSAKeySize(bits); err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not generate RSA key pair: %v", err)
+		return nil, nil, fmt.Errorf("could not generate RSA key pair: %w", err)
 	}
 	return privateKey, &privateKey.PublicKey, nil
 }
 
-// EncodeRSAPublicKey encodes an RSA public key to a base64 encoded PEM string.
-func EncodeRSAPublicKey(publicKey *rsa.PublicKey) (string, error) {
-	pubASN1, err := x509.MarshalPKIXPublicKey(publicKey)
-	if err != nil {
-		return "", fmt.Errorf("could not marshal public key: %v", err)
+// CheckRSAKeySize returns an error if RSA keys can't be generated with the given size.
+func CheckRSAKeySize(bits int) error {
+	if !slices.Contains(RSAKeySizes, bits) {
+		return fmt.Errorf("unsupported RSA key size %d (expected one of %v)", bits, RSAKeySizes)
 	}
+	return nil
+}
 
-	pubBytes := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: pubASN1,
-	})
-
-	return base64.StdEncoding.EncodeToString(pubBytes), nil
+// EncodeRSAPublicKey encodes an RSA public key to a base64 encoded PEM string.
+func EncodeRSAPublicKey(publicKey *rsa.PublicKey) (string, error) {
+	return EncodePublicKey(publicKey)
 }
 
 // ExportRSAPublicKeyAsPEM exports an RSA public key as PEM encoded bytes.
 func ExportRSAPublicKeyAsPEM(publicKey *rsa.PublicKey) ([]byte, error) {
-	pubASN1, err := x509.MarshalPKIXPublicKey(publicKey)
-	if err != nil {
-		return nil, fmt.Errorf("could not marshal public key: %v", err)
-	}
-
-	pubBytes := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: pubASN1,
-	})
-
-	return pubBytes, nil
+	return ExportPublicKeyAsPEM(publicKey)
 }
 
 // ExportRSAPrivateKeyAsPEM exports an RSA private key as PEM encoded bytes.
@@ -121,7 +104,25 @@ func ExportRSAPrivateKeyAsPEM(privateKey *rsa.PrivateKey) ([]byte, error) {
 func DecryptAESKeyWithRSA(privateKey *rsa.PrivateKey, encryptedAESKey []byte) ([]byte, error) {
 	decryptedAESKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, encryptedAESKey, nil)
 	if err != nil {
-		return nil, fmt.Errorf("could not decrypt symmetric key with private key: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrUnwrapFailed, err)
 	}
 	return decryptedAESKey, nil
 }
+
+// FingerprintRSAPublicKey returns the SHA256 fingerprint of an RSA public key (see FingerprintPublicKey).
+func FingerprintRSAPublicKey(publicKey *rsa.PublicKey) (string, error) {
+	return FingerprintPublicKey(publicKey)
+}
+
+// keyIDLength is the number of fingerprint characters kept in a key ID.
+const keyIDLength = 16
+
+// KeyID returns the short ID of a key, the start of its fingerprint's hash.
+// Payloads name their recipient by key ID.
+func KeyID(fingerprint string) string {
+	id := strings.TrimPrefix(fingerprint, "SHA256:")
+	if len(id) > keyIDLength {
+		id = id[:keyIDLength]
+	}
+	return id
+}
@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	stdcrypto "crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Sign signs message with a private key: with RSA-PSS and SHA-256 for RSA keys,
+// and with Ed25519 for Ed25519 keys.
+func Sign(privateKey PrivateKey, message []byte) ([]byte, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(message)
+		signature, err := rsa.SignPSS(rand.Reader, key, stdcrypto.SHA256, digest[:], nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not sign: %w", err)
+		}
+		return signature, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, message), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported private key %T", ErrInvalidKey, privateKey)
+	}
+}
+
+// VerifySignature checks a signature made by Sign. It returns ErrBadSignature if
+// the signature wasn't made over message by the key's private key.
+func VerifySignature(publicKey PublicKey, message, signature []byte) error {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		if rsa.VerifyPSS(key, stdcrypto.SHA256, digest[:], signature, nil) != nil {
+			return ErrBadSignature
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, signature) {
+			return ErrBadSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported public key %T", ErrInvalidKey, publicKey)
+	}
+}
@@ -0,0 +1,130 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// x25519Info separates keys derived for AirBridge from other uses of the same shared secret.
+const x25519Info = "AirBridge X25519 key wrap"
+
+// x25519WrappedSize is the size of a key wrapped by wrapKeyX25519: the ephemeral
+// public key, the 32 byte AES key and the GCM tag.
+const x25519WrappedSize = 32 + 32 + 16
+
+// curve25519P is the field prime 2^255 - 19.
+var curve25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// wrapKeyX25519 encrypts an AES key for an Ed25519 public key. The key is converted
+// to its X25519 form, and an ephemeral X25519 key agrees a secret with it; HKDF-SHA256
+// derives a one-time key from the secret, which seals aesKey with AES-GCM. The result
+// is the ephemeral public key followed by the sealed key.
+func wrapKeyX25519(publicKey ed25519.PublicKey, aesKey []byte) ([]byte, error) {
+	recipient, err := x25519PublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate ephemeral key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt symmetric key with public key: %w", err)
+	}
+
+	gcm, err := x25519Cipher(shared, ephemeral.PublicKey().Bytes(), recipient.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	// The derived key is only used once, so a zero nonce is safe
+	sealed := gcm.Seal(nil, make([]byte, gcm.NonceSize()), aesKey, nil)
+	return append(ephemeral.PublicKey().Bytes(), sealed...), nil
+}
+
+// unwrapKeyX25519 decrypts an AES key wrapped by wrapKeyX25519.
+func unwrapKeyX25519(privateKey ed25519.PrivateKey, wrappedKey []byte) ([]byte, error) {
+	if len(wrappedKey) != x25519WrappedSize {
+		return nil, fmt.Errorf("%w: the symmetric key was not encrypted for an Ed25519 key", ErrWrongRecipient)
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(wrappedKey[:32])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ephemeral key: %w", ErrUnwrapFailed, err)
+	}
+
+	// The X25519 scalar of an Ed25519 key is the first half of the hashed seed
+	digest := sha512.Sum512(privateKey.Seed())
+	scalar, err := ecdh.X25519().NewPrivateKey(digest[:32])
+	if err != nil {
+		return nil, fmt.Errorf("could not convert private key: %w", err)
+	}
+	shared, err := scalar.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not agree on a key: %w", ErrUnwrapFailed, err)
+	}
+
+	gcm, err := x25519Cipher(shared, wrappedKey[:32], scalar.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	aesKey, err := gcm.Open(nil, make([]byte, gcm.NonceSize()), wrappedKey[32:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnwrapFailed, err)
+	}
+	return aesKey, nil
+}
+
+// x25519Cipher returns the AES-GCM cipher keyed from a shared secret and both public keys.
+func x25519Cipher(shared, ephemeral, recipient []byte) (cipher.AEAD, error) {
+	salt := append(slices.Clone(ephemeral), recipient...)
+	key, err := hkdf.Key(sha256.New, shared, salt, x25519Info, 32)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// x25519PublicKey converts an Ed25519 public key to the X25519 key of the same
+// key pair, using the birational map u = (1 + y) / (1 - y) from Edwards to Montgomery form.
+func x25519PublicKey(publicKey ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: Ed25519 public key is %d bytes", ErrInvalidKey, len(publicKey))
+	}
+
+	// y is encoded little-endian, with the sign of x in the top bit
+	encoded := slices.Clone(publicKey)
+	encoded[31] &= 0x7f
+	slices.Reverse(encoded)
+	y := new(big.Int).SetBytes(encoded)
+
+	one := big.NewInt(1)
+	numerator := new(big.Int).Add(one, y)
+	denominator := new(big.Int).Sub(one, y)
+	denominator.Mod(denominator, curve25519P)
+	if denominator.Sign() == 0 {
+		return nil, fmt.Errorf("%w: Ed25519 public key has no X25519 form", ErrInvalidKey)
+	}
+	u := numerator.Mul(numerator, denominator.ModInverse(denominator, curve25519P))
+	u.Mod(u, curve25519P)
+
+	uBytes := u.FillBytes(make([]byte, 32))
+	slices.Reverse(uBytes)
+	key, err := ecdh.X25519().NewPublicKey(uBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not convert public key: %w", ErrInvalidKey, err)
+	}
+	return key, nil
+}
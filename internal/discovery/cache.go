@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCacheTTL is how long fetched keys are used without fetching them again.
+const DefaultCacheTTL = 24 * time.Hour
+
+// Cache keeps the keys fetched for each target, one file per target.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// Entry is what was fetched for a target.
+type Entry struct {
+	Target  string    `json:"target"`
+	Keys    []string  `json:"keys"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// Get returns the cached entry of target, or nil if there is none.
+func (c *Cache) Get(target string) (*Entry, error) {
+	data, err := os.ReadFile(c.path(target))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read key cache: %w", err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Target != target {
+		// A damaged entry is as good as a missing one
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Fresh reports whether entry was fetched less than TTL ago.
+func (c *Cache) Fresh(entry Entry) bool {
+	return time.Since(entry.Fetched) < c.TTL
+}
+
+// Put stores the keys fetched for target.
+func (c *Cache) Put(target string, keys []string) error {
+	data, err := json.MarshalIndent(Entry{Target: target, Keys: keys, Fetched: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode key cache: %w", err)
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return fmt.Errorf("could not create key cache: %w", err)
+	}
+	if err := os.WriteFile(c.path(target), append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("could not write key cache: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) path(target string) string {
+	sum := sha256.Sum256([]byte(target))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:16])+".json")
+}
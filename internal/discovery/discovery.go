@@ -0,0 +1,242 @@
+// Package discovery finds the public keys people publish, so senders can name a
+// recipient (an HTTPS URL, a .keys file or user@domain) instead of pasting a key.
+package discovery
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/statement"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoResolver is returned for targets no resolver understands.
+	ErrNoResolver = errors.New("don't know how to look up keys for this recipient")
+	// ErrNoKeys is returned when a recipient publishes no usable key.
+	ErrNoKeys = errors.New("no usable public key published")
+	// ErrNotTrusted is returned when a key seen for the first time was not confirmed.
+	ErrNotTrusted = errors.New("key was not trusted")
+)
+
+// DefaultTimeout limits how long a key lookup may take.
+const DefaultTimeout = 10 * time.Second
+
+// Sources of a looked up key.
+const (
+	SourceNetwork    = "network"
+	SourceCache      = "cache"
+	SourceStaleCache = "stale cache"
+)
+
+// Discoverer looks up keys with the first resolver that matches a target, caches
+// what it fetched and checks the key against the fingerprint pinned for the target.
+type Discoverer struct {
+	Resolvers []Resolver
+	// Cache keeps fetched keys; nil disables caching.
+	Cache *Cache
+	Pins  *keystore.Pins
+	// Confirm is asked whether to trust a key seen for the first time; nil refuses.
+	Confirm func(target, fingerprint string) (bool, error)
+	// Refresh fetches keys even if the cache holds a fresh copy.
+	Refresh bool
+}
+
+// Result is a key found for a target.
+type Result struct {
+	Target string
+	Key    crypto.PublicKey
+	// KeyText is the key as published, accepted by crypto.DecodePublicKey.
+	KeyText     string
+	Fingerprint string
+	Source      string
+	// Statements are the key statements published with the keys, and Status what
+	// they say about this key.
+	Statements []statement.Statement
+	Status     statement.Status
+	// NewlyPinned is set if the key was seen for the first time and is now pinned.
+	NewlyPinned bool
+}
+
+// New returns a discoverer with the default resolvers, caching in cacheDir and
+// keeping pins in pinsPath.
+func New(client *http.Client, cacheDir, pinsPath string) *Discoverer {
+	return &Discoverer{
+		Resolvers: DefaultResolvers(client),
+		Cache:     &Cache{Dir: cacheDir, TTL: DefaultCacheTTL},
+		Pins:      &keystore.Pins{Path: pinsPath},
+	}
+}
+
+// NewClient returns the HTTP client used for lookups. It only follows redirects
+// that stay on HTTPS.
+func NewClient() *http.Client {
+	return &http.Client{Timeout: DefaultTimeout, CheckRedirect: checkRedirect}
+}
+
+// maxRedirects is how many redirects a lookup follows, like net/http by default.
+const maxRedirects = 10
+
+// checkRedirect refuses redirects away from HTTPS, which would let anyone on the
+// network replace the key.
+func checkRedirect(request *http.Request, via []*http.Request) error {
+	if request.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect to %s: keys are only fetched over HTTPS", request.URL.Redacted())
+	}
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return nil
+}
+
+// Lookup returns the key published for target. If expected is set, the key must
+// have that fingerprint and is pinned without asking. A key revoked by a published
+// revocation statement is refused.
+func (d *Discoverer) Lookup(ctx context.Context, target, expected string) (Result, error) {
+	resolver := d.resolver(target)
+	if resolver == nil {
+		return Result{}, fmt.Errorf("%w: %s", ErrNoResolver, target)
+	}
+
+	texts, source, err := d.fetch(ctx, resolver, target)
+	if err != nil {
+		return Result{}, err
+	}
+
+	pinned, err := d.Pins.Get(target)
+	if err != nil {
+		return Result{}, err
+	}
+	want := expected
+	if want == "" {
+		want = pinned
+	}
+	result, err := pickKey(texts, want)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", target, err)
+	}
+	result.Target = target
+	result.Source = source
+	if result.Status.Revoked != nil {
+		return Result{}, fmt.Errorf("%s: %s: %w", target, result.Fingerprint, result.Status.Check(time.Now()))
+	}
+
+	switch {
+	case expected != "" && result.Fingerprint != expected:
+		return Result{}, fmt.Errorf("%w: %s publishes %s, expected %s", keystore.ErrKeyChanged, target, result.Fingerprint, expected)
+	case pinned != "" && result.Fingerprint != pinned && expected == "":
+		return Result{}, fmt.Errorf("%w: %s was pinned to %s, but publishes %s", keystore.ErrKeyChanged, target, pinned, result.Fingerprint)
+	case pinned == result.Fingerprint:
+		return result, nil
+	case expected == "":
+		trusted := false
+		if d.Confirm != nil {
+			if trusted, err = d.Confirm(target, result.Fingerprint); err != nil {
+				return Result{}, err
+			}
+		}
+		if !trusted {
+			return Result{}, fmt.Errorf("%w: %s (%s)", ErrNotTrusted, target, result.Fingerprint)
+		}
+	}
+
+	if err := d.Pins.Set(target, result.Fingerprint); err != nil {
+		return Result{}, err
+	}
+	result.NewlyPinned = true
+	return result, nil
+}
+
+func (d *Discoverer) resolver(target string) Resolver {
+	for _, resolver := range d.Resolvers {
+		if resolver.Match(target) {
+			return resolver
+		}
+	}
+	return nil
+}
+
+// fetch returns the key texts for target from the cache or the network. If the
+// network fails, a stale cached copy is used instead.
+func (d *Discoverer) fetch(ctx context.Context, resolver Resolver, target string) ([]string, string, error) {
+	var cached *Entry
+	if d.Cache != nil {
+		entry, err := d.Cache.Get(target)
+		if err != nil {
+			return nil, "", err
+		}
+		cached = entry
+	}
+	if cached != nil && !d.Refresh && d.Cache.Fresh(*cached) {
+		return cached.Keys, SourceCache, nil
+	}
+
+	texts, err := resolver.Resolve(ctx, target)
+	if err != nil {
+		if cached != nil {
+			return cached.Keys, SourceStaleCache, nil
+		}
+		return nil, "", err
+	}
+	if d.Cache != nil {
+		if err := d.Cache.Put(target, texts); err != nil {
+			return nil, "", err
+		}
+	}
+	return texts, SourceNetwork, nil
+}
+
+// pickKey returns the key with the wanted fingerprint, or the first usable key
+// if no fingerprint is wanted or none matches. Keys that were revoked are only
+// returned if they are wanted, so the caller can refuse them.
+func pickKey(texts []string, want string) (Result, error) {
+	var statements []statement.Statement
+	for _, text := range texts {
+		found, err := statement.Parse(text)
+		if err != nil {
+			return Result{}, err
+		}
+		statements = append(statements, found...)
+	}
+
+	var found []Result
+	var lastErr error
+	for _, text := range texts {
+		key, err := crypto.DecodePublicKey(text)
+		if err != nil {
+			if !strings.Contains(text, statement.BlockType) {
+				lastErr = err
+			}
+			continue
+		}
+		fingerprint, err := crypto.FingerprintPublicKey(key)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		status, err := statement.StatusOf(key, statements)
+		if err != nil {
+			return Result{}, err
+		}
+		result := Result{Key: key, KeyText: text, Fingerprint: fingerprint, Statements: statements, Status: status}
+		if fingerprint == want {
+			return result, nil
+		}
+		if status.Revoked != nil {
+			lastErr = fmt.Errorf("%s: %w", fingerprint, statement.ErrRevoked)
+			continue
+		}
+		found = append(found, result)
+	}
+	if len(found) == 0 {
+		if lastErr != nil {
+			return Result{}, fmt.Errorf("%w: %w", ErrNoKeys, lastErr)
+		}
+		return Result{}, ErrNoKeys
+	}
+	return found[0], nil
+}
@@ -0,0 +1,274 @@
+package discovery
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/statement"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// keyServer serves published keys over TLS and counts the requests it gets.
+type keyServer struct {
+	*httptest.Server
+	docs map[string]string
+	// redirects maps paths to the URL they redirect to.
+	redirects map[string]string
+	requests  atomic.Int32
+}
+
+func newKeyServer(t *testing.T) *keyServer {
+	t.Helper()
+	s := &keyServer{docs: map[string]string{}, redirects: map[string]string{}}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if target, ok := s.redirects[r.URL.Path]; ok {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		doc, ok := s.docs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(doc))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// host returns the server address, for user@host targets.
+func (s *keyServer) host() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+func newDiscoverer(t *testing.T, s *keyServer) *Discoverer {
+	t.Helper()
+	dir := t.TempDir()
+	d := New(s.Client(), filepath.Join(dir, "cache"), filepath.Join(dir, keystore.PinsFile))
+	d.Confirm = func(target, fingerprint string) (bool, error) { return true, nil }
+	return d
+}
+
+// authorizedKey returns a new Ed25519 key as an authorized_keys line, with its fingerprint.
+func authorizedKey(t *testing.T) (string, string) {
+	t.Helper()
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, err := crypto.FingerprintPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshKey))) + " alice@laptop", fingerprint
+}
+
+func TestLookupResolvers(t *testing.T) {
+	s := newKeyServer(t)
+	sshLine, sshFingerprint := authorizedKey(t)
+	_, rsaPublic, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM, _ := crypto.ExportPublicKeyAsPEM(rsaPublic)
+	rsaFingerprint, _ := crypto.FingerprintPublicKey(rsaPublic)
+
+	s.docs["/alice.keys"] = "ssh-dss AAAAB3NzaC1kc3MAAACBAP old@key\n" + sshLine + "\n"
+	s.docs["/bob.pem"] = string(rsaPEM)
+	s.docs[WellKnownPath+"carol"] = `{"keys": ["` + sshLine + `"]}`
+
+	tests := []struct {
+		name        string
+		target      string
+		fingerprint string
+	}{
+		{".keys file", s.URL + "/alice.keys", sshFingerprint},
+		{"HTTPS URL", s.URL + "/bob.pem", rsaFingerprint},
+		{"Well-known", "carol@" + s.host(), sshFingerprint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newDiscoverer(t, s).Lookup(context.Background(), tt.target, "")
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+			if result.Fingerprint != tt.fingerprint || result.Source != SourceNetwork || !result.NewlyPinned {
+				t.Errorf("Unexpected result %+v", result)
+			}
+			if _, err := crypto.DecodePublicKey(result.KeyText); err != nil {
+				t.Errorf("Expected a usable key text: %v", err)
+			}
+		})
+	}
+
+	d := newDiscoverer(t, s)
+	for _, target := range []string{"http://" + s.host() + "/bob.pem", "alice"} {
+		if _, err := d.Lookup(context.Background(), target, ""); !errors.Is(err, ErrNoResolver) {
+			t.Errorf("Expected ErrNoResolver for %q, got %v", target, err)
+		}
+	}
+	if _, err := d.Lookup(context.Background(), "dave@"+s.host(), ""); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a 404 for an unknown user, got %v", err)
+	}
+	s.redirects["/moved.pem"] = s.URL + "/bob.pem"
+	if result, err := d.Lookup(context.Background(), s.URL+"/moved.pem", ""); err != nil || result.Fingerprint != rsaFingerprint {
+		t.Errorf("Expected a redirect within HTTPS to be followed, got %+v (%v)", result, err)
+	}
+	s.redirects["/insecure.pem"] = "http://" + s.host() + "/bob.pem"
+	if _, err := d.Lookup(context.Background(), s.URL+"/insecure.pem", ""); err == nil || !strings.Contains(err.Error(), "only fetched over HTTPS") {
+		t.Errorf("Expected a redirect to http:// to be refused, got %v", err)
+	}
+	s.docs["/empty.keys"] = "# nothing here\n"
+	if _, err := d.Lookup(context.Background(), s.URL+"/empty.keys", ""); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("Expected ErrNoKeys, got %v", err)
+	}
+}
+
+func TestPinning(t *testing.T) {
+	s := newKeyServer(t)
+	firstLine, firstFingerprint := authorizedKey(t)
+	secondLine, secondFingerprint := authorizedKey(t)
+	s.docs["/alice.keys"] = firstLine + "\n"
+	target := s.URL + "/alice.keys"
+
+	d := newDiscoverer(t, s)
+	d.Refresh = true
+	var asked []string
+	d.Confirm = func(target, fingerprint string) (bool, error) {
+		asked = append(asked, fingerprint)
+		return false, nil
+	}
+	if _, err := d.Lookup(context.Background(), target, ""); !errors.Is(err, ErrNotTrusted) {
+		t.Fatalf("Expected ErrNotTrusted when the key is refused, got %v", err)
+	}
+	if len(asked) != 1 || asked[0] != firstFingerprint {
+		t.Errorf("Expected to be asked about %s, got %v", firstFingerprint, asked)
+	}
+
+	d.Confirm = func(target, fingerprint string) (bool, error) { return true, nil }
+	if result, err := d.Lookup(context.Background(), target, ""); err != nil || !result.NewlyPinned {
+		t.Fatalf("Expected the key to be pinned, got %+v (%v)", result, err)
+	}
+	d.Confirm = nil
+	if result, err := d.Lookup(context.Background(), target, ""); err != nil || result.NewlyPinned {
+		t.Errorf("Expected the pinned key without asking, got %+v (%v)", result, err)
+	}
+
+	// A new key next to the pinned one is ignored, a replaced key is refused
+	s.docs["/alice.keys"] = secondLine + "\n" + firstLine + "\n"
+	if result, err := d.Lookup(context.Background(), target, ""); err != nil || result.Fingerprint != firstFingerprint {
+		t.Errorf("Expected the pinned key to be picked, got %+v (%v)", result, err)
+	}
+	s.docs["/alice.keys"] = secondLine + "\n"
+	if _, err := d.Lookup(context.Background(), target, ""); !errors.Is(err, keystore.ErrKeyChanged) {
+		t.Errorf("Expected ErrKeyChanged, got %v", err)
+	}
+
+	// An expected fingerprint re-pins without asking
+	if _, err := d.Lookup(context.Background(), target, firstFingerprint); !errors.Is(err, keystore.ErrKeyChanged) {
+		t.Errorf("Expected ErrKeyChanged for the wrong expected fingerprint, got %v", err)
+	}
+	if result, err := d.Lookup(context.Background(), target, secondFingerprint); err != nil || !result.NewlyPinned {
+		t.Errorf("Expected the expected key to be pinned, got %+v (%v)", result, err)
+	}
+	if pinned, _ := d.Pins.Get(target); pinned != secondFingerprint {
+		t.Errorf("Expected %s to be pinned, got %s", secondFingerprint, pinned)
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	s := newKeyServer(t)
+	oldPublic, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, newPublic, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPEM, _ := crypto.ExportPublicKeyAsPEM(oldPublic)
+	newPEM, _ := crypto.ExportPublicKeyAsPEM(newPublic)
+	newFingerprint, _ := crypto.FingerprintPublicKey(newPublic)
+	revocation, err := statement.Revocation(oldKey, time.Now(), "laptop stolen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocationPEM, _ := revocation.Encode()
+
+	publish := func(keys, revocations []string) {
+		doc, _ := json.Marshal(map[string][]string{"keys": keys, "revocations": revocations})
+		s.docs[WellKnownPath+"alice"] = string(doc)
+	}
+	target := "alice@" + s.host()
+	d := newDiscoverer(t, s)
+	d.Refresh = true
+	publish([]string{string(oldPEM)}, nil)
+	if _, err := d.Lookup(context.Background(), target, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pinned key is refused once its revocation is published
+	publish([]string{string(oldPEM), string(newPEM)}, []string{string(revocationPEM)})
+	if _, err := d.Lookup(context.Background(), target, ""); !errors.Is(err, statement.ErrRevoked) || !strings.Contains(err.Error(), "laptop stolen") {
+		t.Errorf("Expected ErrRevoked for the pinned key, got %v", err)
+	}
+	if result, err := d.Lookup(context.Background(), target, newFingerprint); err != nil || result.Fingerprint != newFingerprint {
+		t.Errorf("Expected the new key to be pinned, got %+v (%v)", result, err)
+	}
+
+	// Without a pin, a revoked key is passed over
+	if result, err := newDiscoverer(t, s).Lookup(context.Background(), target, ""); err != nil || result.Fingerprint != newFingerprint {
+		t.Errorf("Expected the key that wasn't revoked, got %+v (%v)", result, err)
+	}
+	publish([]string{string(oldPEM)}, []string{string(revocationPEM)})
+	if _, err := newDiscoverer(t, s).Lookup(context.Background(), target, ""); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("Expected ErrNoKeys when every key is revoked, got %v", err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	s := newKeyServer(t)
+	line, fingerprint := authorizedKey(t)
+	s.docs["/alice.keys"] = line + "\n"
+	target := s.URL + "/alice.keys"
+	d := newDiscoverer(t, s)
+
+	if _, err := d.Lookup(context.Background(), target, ""); err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.Lookup(context.Background(), target, "")
+	if err != nil || result.Source != SourceCache || s.requests.Load() != 1 {
+		t.Errorf("Expected the second lookup from the cache, got %+v after %d requests (%v)", result, s.requests.Load(), err)
+	}
+
+	d.Refresh = true
+	if result, err := d.Lookup(context.Background(), target, ""); err != nil || result.Source != SourceNetwork {
+		t.Errorf("Expected --refresh to fetch again, got %+v (%v)", result, err)
+	}
+
+	// An expired entry is fetched again, and used if the server is gone
+	d.Refresh = false
+	d.Cache.TTL = time.Nanosecond
+	s.Close()
+	result, err = d.Lookup(context.Background(), target, "")
+	if err != nil || result.Source != SourceStaleCache || result.Fingerprint != fingerprint {
+		t.Errorf("Expected the stale cached key, got %+v (%v)", result, err)
+	}
+}
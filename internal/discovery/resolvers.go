@@ -0,0 +1,139 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxDocumentSize limits how much of a key document is read.
+const maxDocumentSize = 64 << 10
+
+// WellKnownPath is where a domain publishes the keys of its users, followed by the user name.
+const WellKnownPath = "/.well-known/airbridge/"
+
+// Resolver finds the public keys published for a target.
+type Resolver interface {
+	// Match reports whether the resolver handles target.
+	Match(target string) bool
+	// Resolve returns the published keys of target, one text per key, and any
+	// key statements published with them.
+	Resolve(ctx context.Context, target string) ([]string, error)
+}
+
+// DefaultResolvers returns the built-in resolvers, most specific first.
+func DefaultResolvers(client *http.Client) []Resolver {
+	return []Resolver{
+		&SSHKeysResolver{Client: client},
+		&URLResolver{Client: client},
+		&WellKnownResolver{Client: client},
+	}
+}
+
+// SSHKeysResolver reads authorized_keys style .keys files, as served by Git
+// forges at https://<host>/<user>.keys.
+type SSHKeysResolver struct {
+	Client *http.Client
+}
+
+func (r *SSHKeysResolver) Match(target string) bool {
+	return strings.HasPrefix(target, "https://") && strings.HasSuffix(target, ".keys")
+}
+
+func (r *SSHKeysResolver) Resolve(ctx context.Context, target string) ([]string, error) {
+	body, err := get(ctx, r.Client, target)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, "ssh-") {
+			keys = append(keys, line)
+		}
+	}
+	return keys, nil
+}
+
+// URLResolver reads a single public key from an HTTPS URL, in any form
+// crypto.DecodePublicKey accepts.
+type URLResolver struct {
+	Client *http.Client
+}
+
+func (r *URLResolver) Match(target string) bool {
+	return strings.HasPrefix(target, "https://")
+}
+
+func (r *URLResolver) Resolve(ctx context.Context, target string) ([]string, error) {
+	body, err := get(ctx, r.Client, target)
+	if err != nil {
+		return nil, err
+	}
+	return []string{strings.TrimSpace(string(body))}, nil
+}
+
+// WellKnownResolver looks up user@domain at https://<domain>/.well-known/airbridge/<user>,
+// a JSON document of the form {"keys": ["<public key>", ...], "revocations": ["<statement>", ...]}.
+type WellKnownResolver struct {
+	Client *http.Client
+}
+
+var emailLike = regexp.MustCompile(`^[^@/\s]+@[^@/\s]+$`)
+
+func (r *WellKnownResolver) Match(target string) bool {
+	return emailLike.MatchString(target)
+}
+
+func (r *WellKnownResolver) Resolve(ctx context.Context, target string) ([]string, error) {
+	user, domain, _ := strings.Cut(target, "@")
+	body, err := get(ctx, r.Client, "https://"+domain+WellKnownPath+url.PathEscape(user))
+	if err != nil {
+		return nil, err
+	}
+	var document struct {
+		Keys        []string `json:"keys"`
+		Revocations []string `json:"revocations"`
+	}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("invalid key document for %s: %w", target, err)
+	}
+	return append(document.Keys, document.Revocations...), nil
+}
+
+// get fetches a key document.
+func get(ctx context.Context, client *http.Client, target string) ([]byte, error) {
+	if client == nil {
+		client = NewClient()
+	} else if client.CheckRedirect == nil {
+		// Clients passed in must not follow redirects away from HTTPS either
+		secure := *client
+		secure.CheckRedirect = checkRedirect
+		client = &secure
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key URL %s: %w", target, err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch key: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch key from %s: %s", target, response.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxDocumentSize))
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch key from %s: %w", target, err)
+	}
+	return body, nil
+}
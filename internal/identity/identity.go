@@ -0,0 +1,232 @@
+// Package identity ties short-lived device keys to a long-term identity key. The
+// identity key (Ed25519) signs a certificate for each device key, and the bundle
+// of these certificates is what people import to send to all devices at once.
+package identity
+
+import (
+	"AirBridge/internal/crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for identities that aren't in the store.
+	ErrNotFound = errors.New("identity not found")
+	// ErrBadSignature is returned when a device certificate wasn't signed by the identity key.
+	ErrBadSignature = errors.New("device certificate signature is invalid")
+	// ErrNoDevices is returned when an identity has no device key that is valid now.
+	ErrNoDevices = errors.New("no valid device keys")
+	// ErrUnsupportedBundle is returned for bundles made by a newer release.
+	ErrUnsupportedBundle = errors.New("unsupported identity bundle")
+	// ErrNoKey is returned when the private key of an identity that isn't your own is needed.
+	ErrNoKey = errors.New("no identity key stored")
+)
+
+// BundleVersion is the version of the bundle format written by this release.
+const BundleVersion = 1
+
+// DefaultValidity is how long a device certificate is valid by default.
+const DefaultValidity = 30 * 24 * time.Hour
+
+// signaturePrefix starts the message signed for a certificate, so the signature
+// can't be mistaken for one over anything else.
+const signaturePrefix = "AirBridge device certificate v1"
+
+// Certificate is the identity key's signature over a device key and the time it is valid.
+type Certificate struct {
+	Device string `json:"device"`
+	// Key is the device public key, base64 encoded PEM as crypto.EncodePublicKey writes it.
+	Key       string    `json:"key"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	Signature string    `json:"signature"`
+}
+
+// Bundle is an identity with the certificates of its device keys.
+type Bundle struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	// Identity is the identity public key, base64 encoded PEM.
+	Identity string        `json:"identity"`
+	Devices  []Certificate `json:"devices"`
+}
+
+// New returns an empty bundle for the identity key.
+func New(name string, identityKey ed25519.PublicKey) (Bundle, error) {
+	encoded, err := crypto.EncodePublicKey(identityKey)
+	if err != nil {
+		return Bundle{}, err
+	}
+	return Bundle{Version: BundleVersion, Name: name, Identity: encoded, Devices: []Certificate{}}, nil
+}
+
+// Parse decodes a bundle and checks the signature of every certificate in it.
+func Parse(data []byte) (Bundle, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Bundle{}, fmt.Errorf("%w: invalid json: %w", crypto.ErrInvalidKey, err)
+	}
+	if b.Version > BundleVersion {
+		return Bundle{}, fmt.Errorf("%w: version %d (this release reads up to %d)", ErrUnsupportedBundle, b.Version, BundleVersion)
+	}
+	if err := b.Verify(); err != nil {
+		return Bundle{}, err
+	}
+	return b, nil
+}
+
+// Marshal encodes the bundle as indented JSON.
+func (b Bundle) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not encode identity bundle: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// IdentityKey returns the identity public key.
+func (b Bundle) IdentityKey() (ed25519.PublicKey, error) {
+	key, err := crypto.DecodePublicKey(b.Identity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity key: %w", err)
+	}
+	identityKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: identity keys must be Ed25519 keys", crypto.ErrInvalidKey)
+	}
+	return identityKey, nil
+}
+
+// Fingerprint returns the fingerprint of the identity key.
+func (b Bundle) Fingerprint() (string, error) {
+	identityKey, err := b.IdentityKey()
+	if err != nil {
+		return "", err
+	}
+	return crypto.FingerprintPublicKey(identityKey)
+}
+
+// Certify signs a certificate for the device key, valid from notBefore for
+// validity, and puts it in the bundle in place of any earlier one for the device.
+func (b *Bundle) Certify(identityKey ed25519.PrivateKey, device string, deviceKey crypto.PublicKey, notBefore time.Time, validity time.Duration) (Certificate, error) {
+	publicKey, err := b.IdentityKey()
+	if err != nil {
+		return Certificate{}, err
+	}
+	if !publicKey.Equal(identityKey.Public()) {
+		return Certificate{}, fmt.Errorf("%w: the private key is not the key of identity %s", crypto.ErrInvalidKey, b.Name)
+	}
+	encoded, err := crypto.EncodePublicKey(deviceKey)
+	if err != nil {
+		return Certificate{}, err
+	}
+
+	notBefore = notBefore.UTC().Truncate(time.Second)
+	c := Certificate{
+		Device:    device,
+		Key:       encoded,
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(validity),
+	}
+	c.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(identityKey, c.signedMessage(b.Identity)))
+
+	devices := []Certificate{}
+	for _, existing := range b.Devices {
+		if existing.Device != device {
+			devices = append(devices, existing)
+		}
+	}
+	b.Devices = append(devices, c)
+	return c, nil
+}
+
+// Verify checks that every certificate in the bundle was signed by its identity key.
+func (b Bundle) Verify() error {
+	identityKey, err := b.IdentityKey()
+	if err != nil {
+		return err
+	}
+	for _, c := range b.Devices {
+		if err := c.verify(identityKey, b.Identity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeviceKeys checks the certificates and returns the keys of the devices that are
+// valid at now, with the names of those that aren't.
+func (b Bundle) DeviceKeys(now time.Time) ([]crypto.PublicKey, []string, error) {
+	if err := b.Verify(); err != nil {
+		return nil, nil, err
+	}
+	var keys []crypto.PublicKey
+	var skipped []string
+	for _, c := range b.Devices {
+		if !c.ValidAt(now) {
+			skipped = append(skipped, c.Device)
+			continue
+		}
+		key, err := c.PublicKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, skipped, fmt.Errorf("%w: %s", ErrNoDevices, b.Name)
+	}
+	return keys, skipped, nil
+}
+
+// Current returns the bundle with only the certificates valid at now or later.
+func (b Bundle) Current(now time.Time) Bundle {
+	current := b
+	current.Devices = []Certificate{}
+	for _, c := range b.Devices {
+		if now.Before(c.NotAfter) {
+			current.Devices = append(current.Devices, c)
+		}
+	}
+	return current
+}
+
+// PublicKey returns the device public key.
+func (c Certificate) PublicKey() (crypto.PublicKey, error) {
+	key, err := crypto.DecodePublicKey(c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key for device %s: %w", c.Device, err)
+	}
+	return key, nil
+}
+
+// ValidAt reports whether the certificate is valid at t.
+func (c Certificate) ValidAt(t time.Time) bool {
+	return !t.Before(c.NotBefore) && t.Before(c.NotAfter)
+}
+
+func (c Certificate) verify(identityKey ed25519.PublicKey, identity string) error {
+	signature, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil || !ed25519.Verify(identityKey, c.signedMessage(identity), signature) {
+		return fmt.Errorf("%w: device %s", ErrBadSignature, c.Device)
+	}
+	return nil
+}
+
+// signedMessage returns what the identity key signs for the certificate. It names
+// the identity too, so a certificate can't be moved to another bundle.
+func (c Certificate) signedMessage(identity string) []byte {
+	return []byte(strings.Join([]string{
+		signaturePrefix,
+		identity,
+		c.Device,
+		c.Key,
+		c.NotBefore.UTC().Format(time.RFC3339),
+		c.NotAfter.UTC().Format(time.RFC3339),
+	}, "\n"))
+}
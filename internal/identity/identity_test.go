@@ -0,0 +1,161 @@
+package identity
+
+import (
+	"AirBridge/internal/crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newIdentity(t *testing.T, name string) (Bundle, ed25519.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(name, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, privateKey
+}
+
+func TestCertify(t *testing.T) {
+	b, identityKey := newIdentity(t, "alice")
+	_, laptopKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bastionKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if _, err := b.Certify(identityKey, "laptop", laptopKey, now.Add(-time.Hour), DefaultValidity); err != nil {
+		t.Fatalf("Certify failed: %v", err)
+	}
+	if _, err := b.Certify(identityKey, "bastion", bastionKey, now.Add(-48*time.Hour), 24*time.Hour); err != nil {
+		t.Fatalf("Certify failed: %v", err)
+	}
+
+	// The bundle survives a round trip, and only valid devices are used
+	data, err := b.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	keys, skipped, err := parsed.DeviceKeys(now)
+	if err != nil {
+		t.Fatalf("DeviceKeys failed: %v", err)
+	}
+	if len(keys) != 1 || crypto.KeyType(keys[0]) != crypto.TypeRSA || len(skipped) != 1 || skipped[0] != "bastion" {
+		t.Errorf("Expected only the laptop key, got %d keys, skipped %v", len(keys), skipped)
+	}
+	if current := parsed.Current(now); len(current.Devices) != 1 || current.Devices[0].Device != "laptop" {
+		t.Errorf("Expected the current bundle to hold the laptop only, got %+v", current.Devices)
+	}
+	if _, _, err := parsed.DeviceKeys(now.Add(2 * DefaultValidity)); !errors.Is(err, ErrNoDevices) {
+		t.Errorf("Expected ErrNoDevices once every certificate expired, got %v", err)
+	}
+
+	// Certifying a device again replaces its certificate
+	if _, err := b.Certify(identityKey, "bastion", bastionKey, now, DefaultValidity); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _, err := b.DeviceKeys(now); err != nil || len(keys) != 2 || len(b.Devices) != 2 {
+		t.Errorf("Expected two valid devices, got %d keys of %d (%v)", len(keys), len(b.Devices), err)
+	}
+
+	// Only the identity key can certify
+	other, otherKey := newIdentity(t, "mallory")
+	if _, err := b.Certify(otherKey, "phone", bastionKey, now, DefaultValidity); !errors.Is(err, crypto.ErrInvalidKey) {
+		t.Errorf("Expected another identity key to be refused, got %v", err)
+	}
+	if _, err := other.Certify(otherKey, "phone", bastionKey, now, DefaultValidity); err != nil {
+		t.Fatal(err)
+	}
+	b.Devices = append(b.Devices, other.Devices[0])
+	if err := b.Verify(); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected a certificate from another bundle to fail, got %v", err)
+	}
+}
+
+func TestTamperedBundle(t *testing.T) {
+	b, identityKey := newIdentity(t, "alice")
+	_, deviceKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, swappedKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Certify(identityKey, "laptop", deviceKey, time.Now(), DefaultValidity); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(c *Certificate)
+	}{
+		{"Swapped key", func(c *Certificate) { c.Key, _ = crypto.EncodePublicKey(swappedKey) }},
+		{"Extended validity", func(c *Certificate) { c.NotAfter = c.NotAfter.Add(time.Hour) }},
+		{"Renamed device", func(c *Certificate) { c.Device = "workstation" }},
+		{"Bad signature", func(c *Certificate) { c.Signature = "AAAA" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := b
+			tampered.Devices = append([]Certificate{}, b.Devices...)
+			tt.tamper(&tampered.Devices[0])
+			data, _ := tampered.Marshal()
+			if _, err := Parse(data); !errors.Is(err, ErrBadSignature) {
+				t.Errorf("Expected ErrBadSignature, got %v", err)
+			}
+		})
+	}
+
+	if _, err := Parse([]byte(`{"version": 99}`)); !errors.Is(err, ErrUnsupportedBundle) {
+		t.Errorf("Expected ErrUnsupportedBundle, got %v", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "identities"))
+	if bundles, err := store.List(); err != nil || len(bundles) != 0 {
+		t.Fatalf("Expected an empty store, got %v (%v)", bundles, err)
+	}
+	if _, err := store.Get("alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	var aliceKey ed25519.PrivateKey
+	for _, name := range []string{"bob", "alice"} {
+		b, privateKey := newIdentity(t, name)
+		if err := store.Put(b); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		aliceKey = privateKey
+	}
+	if err := store.PutKey("alice", aliceKey); err != nil {
+		t.Fatalf("PutKey failed: %v", err)
+	}
+	bundles, err := store.List()
+	if err != nil || len(bundles) != 2 || bundles[0].Name != "alice" || !store.Has("bob") {
+		t.Errorf("Unexpected bundles %+v (%v)", bundles, err)
+	}
+
+	if key, err := store.Key("alice"); err != nil || !key.Equal(aliceKey) {
+		t.Errorf("Expected alice's identity key back, got %v", err)
+	}
+	if _, err := store.Key("bob"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey for an identity that isn't your own, got %v", err)
+	}
+}
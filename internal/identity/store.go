@@ -0,0 +1,151 @@
+package identity
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keystore"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File name suffixes of stored bundles and identity private keys.
+const (
+	bundleSuffix = ".json"
+	keySuffix    = ".key.pem"
+)
+
+// Store is a directory of bundles: your own identities and the ones you imported,
+// each kept as <name>.json. The private keys of your own identities are kept
+// next to them as <name>.key.pem, out of the key store, so a key store given to
+// receive -k never offers them as decryption keys.
+type Store struct {
+	Dir string
+}
+
+// NewStore returns the store kept in dir. The directory is created on the first write.
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// Get returns the bundle called name, with its signatures checked.
+func (s *Store) Get(name string) (Bundle, error) {
+	if err := keystore.CheckName(name); err != nil {
+		return Bundle{}, err
+	}
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return Bundle{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	} else if err != nil {
+		return Bundle{}, fmt.Errorf("could not read identity %s: %w", name, err)
+	}
+	b, err := Parse(data)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("identity %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// Has reports whether a bundle called name is stored.
+func (s *Store) Has(name string) bool {
+	if keystore.CheckName(name) != nil {
+		return false
+	}
+	_, err := os.Stat(s.path(name))
+	return err == nil
+}
+
+// Put stores the bundle under its name, replacing any earlier one.
+func (s *Store) Put(b Bundle) error {
+	if err := keystore.CheckName(b.Name); err != nil {
+		return err
+	}
+	data, err := b.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("could not create identity store: %w", err)
+	}
+	if err := os.WriteFile(s.path(b.Name), data, 0644); err != nil {
+		return fmt.Errorf("could not write identity %s: %w", b.Name, err)
+	}
+	return nil
+}
+
+// List returns the stored bundles sorted by name.
+func (s *Store) List() ([]Bundle, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read identity store: %w", err)
+	}
+
+	var bundles []Bundle
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), bundleSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		b, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, b)
+	}
+	sort.Slice(bundles, func(i, j int) bool { return bundles[i].Name < bundles[j].Name })
+	return bundles, nil
+}
+
+// PutKey stores the identity private key of name, replacing any earlier one.
+func (s *Store) PutKey(name string, privateKey ed25519.PrivateKey) error {
+	if err := keystore.CheckName(name); err != nil {
+		return err
+	}
+	pemBytes, err := crypto.ExportPrivateKeyAsPEM(privateKey)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("could not create identity store: %w", err)
+	}
+	if err := os.WriteFile(s.keyPath(name), pemBytes, 0600); err != nil {
+		return fmt.Errorf("could not write identity key %s: %w", name, err)
+	}
+	return nil
+}
+
+// Key returns the identity private key of name. It returns ErrNoKey for
+// identities that aren't your own.
+func (s *Store) Key(name string) (ed25519.PrivateKey, error) {
+	if err := keystore.CheckName(name); err != nil {
+		return nil, err
+	}
+	pemBytes, err := os.ReadFile(s.keyPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoKey, name)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read identity key %s: %w", name, err)
+	}
+	privateKey, err := crypto.DecodePrivateKey(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("identity key %s: %w", name, err)
+	}
+	identityKey, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: identity key %s is not an Ed25519 key", crypto.ErrInvalidKey, name)
+	}
+	return identityKey, nil
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.Dir, name+bundleSuffix)
+}
+
+func (s *Store) keyPath(name string) string {
+	return filepath.Join(s.Dir, name+keySuffix)
+}
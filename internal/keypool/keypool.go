@@ -0,0 +1,179 @@
+// Package keypool keeps ephemeral key pairs generated ahead of time, so receive
+// doesn't have to wait for RSA key generation. Each key is stored encrypted under
+// a pool key kept in another directory (see localkey), which only obscures it,
+// and is removed from the pool when it is taken, so it is only ever used once.
+package keypool
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/localkey"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrEmpty is returned when the pool holds no key of the wanted size.
+var ErrEmpty = errors.New("key pool is empty")
+
+// DefaultSize is how many keys a refill keeps when no size is configured.
+const DefaultSize = 3
+
+// KeyFileName is the name of the pool key file in the config directory.
+const KeyFileName = "keypool.key"
+
+// File name suffixes of pooled keys, and of keys being written or taken.
+const (
+	keySuffix     = ".key"
+	partialSuffix = ".partial"
+	takenSuffix   = ".taken"
+)
+
+// Pool is a directory of encrypted key pairs, each kept as <bits>-<random>.key.
+type Pool struct {
+	Dir string
+	// KeyFile holds the AES key the pooled keys are encrypted with. It is created
+	// with the first key put in the pool.
+	KeyFile string
+}
+
+// New returns the pool kept in dir, encrypted with the key in keyFile.
+func New(dir, keyFile string) *Pool {
+	return &Pool{Dir: dir, KeyFile: keyFile}
+}
+
+// Take removes a key pair of the given size from the pool and returns it. It
+// returns ErrEmpty if the pool holds none. A key is never returned twice, even
+// to processes taking keys at the same time.
+func (p *Pool) Take(bits int) (*rsa.PrivateKey, error) {
+	names, err := p.entries(bits)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		path := filepath.Join(p.Dir, name)
+		// Renaming claims the key: only one process can rename it away
+		claimed := path + takenSuffix
+		if err := os.Rename(path, claimed); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(claimed)
+		_ = os.Remove(claimed)
+		if err != nil {
+			return nil, fmt.Errorf("could not read pooled key: %w", err)
+		}
+		return p.decrypt(data)
+	}
+	return nil, fmt.Errorf("%w: no %d-bit keys", ErrEmpty, bits)
+}
+
+// Count returns how many key pairs of the given size the pool holds.
+func (p *Pool) Count(bits int) (int, error) {
+	names, err := p.entries(bits)
+	return len(names), err
+}
+
+// Put encrypts the key pair and adds it to the pool.
+func (p *Pool) Put(privateKey *rsa.PrivateKey) error {
+	privPEM, err := crypto.ExportRSAPrivateKeyAsPEM(privateKey)
+	if err != nil {
+		return err
+	}
+	sealed, err := localkey.Seal(p.KeyFile, privPEM)
+	if err != nil {
+		return fmt.Errorf("could not encrypt pooled key: %w", err)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("could not name pooled key: %w", err)
+	}
+	if err := os.MkdirAll(p.Dir, 0700); err != nil {
+		return fmt.Errorf("could not create key pool: %w", err)
+	}
+	// Written under another name first, so Take never sees half a key
+	path := filepath.Join(p.Dir, fmt.Sprintf("%d-%s%s", privateKey.N.BitLen(), hex.EncodeToString(id), keySuffix))
+	if err := os.WriteFile(path+partialSuffix, sealed, 0600); err != nil {
+		return fmt.Errorf("could not write pooled key: %w", err)
+	}
+	if err := os.Rename(path+partialSuffix, path); err != nil {
+		return fmt.Errorf("could not write pooled key: %w", err)
+	}
+	return nil
+}
+
+// Refill generates key pairs of the given size until the pool holds size of
+// them, and returns how many it added.
+func (p *Pool) Refill(bits, size int) (int, error) {
+	count, err := p.Count(bits)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for ; count+added < size; added++ {
+		privateKey, _, err := crypto.GenerateRSAKeyPairBits(bits)
+		if err != nil {
+			return added, err
+		}
+		if err := p.Put(privateKey); err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+// Clear removes every key from the pool, and the pool key with them. It returns
+// how many keys were removed.
+func (p *Pool) Clear() (int, error) {
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("could not read key pool: %w", err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.Contains(entry.Name(), keySuffix) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(p.Dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("could not clear key pool: %w", err)
+		}
+		if strings.HasSuffix(entry.Name(), keySuffix) {
+			removed++
+		}
+	}
+	if err := os.Remove(p.KeyFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return removed, fmt.Errorf("could not remove pool key: %w", err)
+	}
+	return removed, nil
+}
+
+// entries returns the file names of the pooled keys of the given size.
+func (p *Pool) entries(bits int) ([]string, error) {
+	entries, err := os.ReadDir(p.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read key pool: %w", err)
+	}
+	prefix := strconv.Itoa(bits) + "-"
+	var names []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, keySuffix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (p *Pool) decrypt(data []byte) (*rsa.PrivateKey, error) {
+	privPEM, err := localkey.Open(p.KeyFile, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt pooled key: %w", err)
+	}
+	return crypto.DecodeRSAPrivateKey(privPEM)
+}
@@ -0,0 +1,97 @@
+package keypool
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/localkey"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newPool(t *testing.T) *Pool {
+	t.Helper()
+	dir := t.TempDir()
+	return New(filepath.Join(dir, "cache", "keypool"), filepath.Join(dir, "config", KeyFileName))
+}
+
+func TestRefillAndTake(t *testing.T) {
+	p := newPool(t)
+	if _, err := p.Take(crypto.DefaultRSABits); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("Expected ErrEmpty from a new pool, got %v", err)
+	}
+
+	added, err := p.Refill(crypto.DefaultRSABits, 2)
+	if err != nil || added != 2 {
+		t.Fatalf("Expected 2 keys to be added, got %d (%v)", added, err)
+	}
+	if added, err := p.Refill(crypto.DefaultRSABits, 2); err != nil || added != 0 {
+		t.Errorf("Expected a full pool to stay as it is, got %d added (%v)", added, err)
+	}
+	if count, err := p.Count(3072); err != nil || count != 0 {
+		t.Errorf("Expected no 3072-bit keys, got %d (%v)", count, err)
+	}
+
+	// Keys are encrypted at rest
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(p.Dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("PRIVATE KEY")) {
+			t.Errorf("Expected %s to be encrypted", entry.Name())
+		}
+	}
+
+	// Each key is taken once
+	first, err := p.Take(crypto.DefaultRSABits)
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	second, err := p.Take(crypto.DefaultRSABits)
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if first.Equal(second) || first.N.BitLen() != crypto.DefaultRSABits {
+		t.Error("Expected two different keys of the default size")
+	}
+	if _, err := p.Take(crypto.DefaultRSABits); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Expected ErrEmpty once every key was taken, got %v", err)
+	}
+}
+
+func TestPoolKey(t *testing.T) {
+	p := newPool(t)
+	if _, err := p.Refill(crypto.DefaultRSABits, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the pool key the keys are useless, and are consumed anyway
+	other := newPool(t)
+	other.Dir = p.Dir
+	if _, err := localkey.Load(other.KeyFile, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Take(crypto.DefaultRSABits); err == nil || errors.Is(err, ErrEmpty) {
+		t.Errorf("Expected a key encrypted under another pool key to fail, got %v", err)
+	}
+	if count, _ := p.Count(crypto.DefaultRSABits); count != 0 {
+		t.Errorf("Expected the failed key to be gone, %d left", count)
+	}
+
+	if _, err := p.Refill(crypto.DefaultRSABits, 1); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := p.Clear()
+	if err != nil || removed != 1 {
+		t.Errorf("Expected Clear to remove 1 key, got %d (%v)", removed, err)
+	}
+	if _, err := os.Stat(p.KeyFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the pool key to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+// Package keystore keeps named identities: key pairs of your own and public
+// keys of the people you send to.
+package keystore
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/statement"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for names that aren't in the store.
+	ErrNotFound = errors.New("key not found")
+	// ErrExists is returned when a key would be overwritten without force.
+	ErrExists = errors.New("key already exists")
+	// ErrNoPrivateKey is returned when a private key is needed but only the public key is stored.
+	ErrNoPrivateKey = errors.New("no private key stored")
+	// ErrPrivateKeyStored is returned when a public key would replace a key pair,
+	// losing its private key.
+	ErrPrivateKeyStored = errors.New("a private key is stored under this name")
+)
+
+// File name suffixes of the files kept for each identity.
+const (
+	privateSuffix  = ".pem"
+	publicSuffix   = ".pub.pem"
+	metadataSuffix = ".json"
+)
+
+// Identity types.
+const (
+	TypeRSA     = crypto.TypeRSA
+	TypeEd25519 = crypto.TypeEd25519
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Identity describes a stored key.
+type Identity struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Bits        int       `json:"bits"`
+	Fingerprint string    `json:"fingerprint"`
+	Created     time.Time `json:"created"`
+	// Private is set if the private key is stored too, not just the public key.
+	Private bool `json:"private"`
+	// NotBefore and NotAfter are the validity the key signed for itself, if any.
+	NotBefore time.Time `json:"not_before,omitzero"`
+	NotAfter  time.Time `json:"not_after,omitzero"`
+	// Revoked is when the key was revoked, with the reason its owner gave.
+	Revoked          time.Time `json:"revoked,omitzero"`
+	RevocationReason string    `json:"revocation_reason,omitempty"`
+}
+
+// Status returns what the statements stored with the key say about it.
+func (i Identity) Status() statement.Status {
+	status := statement.Status{NotBefore: i.NotBefore, NotAfter: i.NotAfter}
+	if !i.Revoked.IsZero() {
+		status.Revoked = &statement.Statement{Kind: statement.KindRevocation, Key: i.Fingerprint, Issued: i.Revoked, Reason: i.RevocationReason}
+	}
+	return status
+}
+
+// Store is a directory of identities. Each one is kept as <name>.pem (private key,
+// if there is one), <name>.pub.pem (public key) and <name>.json (metadata), so the
+// directory can also be given to receive -k as a directory of private keys.
+type Store struct {
+	Dir string
+}
+
+// New returns the store kept in dir. The directory is created on the first write.
+func New(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// CheckName returns an error if name can't be used for an identity.
+func CheckName(name string) error {
+	if !validName.MatchString(name) || strings.HasSuffix(name, ".pub") {
+		return fmt.Errorf("invalid key name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// List returns the stored identities sorted by name.
+func (s *Store) List() ([]Identity, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read key store: %w", err)
+	}
+
+	var identities []Identity
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), metadataSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		identity, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Name < identities[j].Name })
+	return identities, nil
+}
+
+// Get returns the identity called name.
+func (s *Store) Get(name string) (Identity, error) {
+	if err := CheckName(name); err != nil {
+		return Identity{}, err
+	}
+	data, err := os.ReadFile(s.path(name, metadataSuffix))
+	if errors.Is(err, os.ErrNotExist) {
+		return Identity{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	} else if err != nil {
+		return Identity{}, fmt.Errorf("could not read key %s: %w", name, err)
+	}
+	var identity Identity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return Identity{}, fmt.Errorf("invalid metadata for key %s: %w", name, err)
+	}
+	return identity, nil
+}
+
+// Has reports whether an identity called name is stored.
+func (s *Store) Has(name string) bool {
+	_, err := s.Get(name)
+	return err == nil
+}
+
+// AddPrivate stores a key pair under name. An existing identity is only replaced with force.
+func (s *Store) AddPrivate(name string, privateKey crypto.PrivateKey, force bool) (Identity, error) {
+	privPEM, err := crypto.ExportPrivateKeyAsPEM(privateKey)
+	if err != nil {
+		return Identity{}, err
+	}
+	return s.add(name, crypto.PublicKeyOf(privateKey), privPEM, force)
+}
+
+// AddPublic stores someone else's public key under name. An existing identity is only replaced with force,
+// and never if it is a key pair: its private key would be lost. Delete the key pair first to replace it.
+func (s *Store) AddPublic(name string, publicKey crypto.PublicKey, force bool) (Identity, error) {
+	return s.add(name, publicKey, nil, force)
+}
+
+func (s *Store) add(name string, publicKey crypto.PublicKey, privPEM []byte, force bool) (Identity, error) {
+	if err := CheckName(name); err != nil {
+		return Identity{}, err
+	}
+	if !force && s.Has(name) {
+		return Identity{}, fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	if privPEM == nil {
+		if _, err := os.Stat(s.path(name, privateSuffix)); err == nil {
+			return Identity{}, fmt.Errorf("%w: %s", ErrPrivateKeyStored, name)
+		}
+	}
+
+	pubPEM, err := crypto.ExportPublicKeyAsPEM(publicKey)
+	if err != nil {
+		return Identity{}, err
+	}
+	fingerprint, err := crypto.FingerprintPublicKey(publicKey)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity := Identity{
+		Name:        name,
+		Type:        crypto.KeyType(publicKey),
+		Bits:        crypto.KeyBits(publicKey),
+		Fingerprint: fingerprint,
+		Created:     time.Now().UTC().Truncate(time.Second),
+		Private:     privPEM != nil,
+	}
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return Identity{}, fmt.Errorf("could not create key store: %w", err)
+	}
+	if privPEM != nil {
+		if err := os.WriteFile(s.path(name, privateSuffix), privPEM, 0600); err != nil {
+			return Identity{}, fmt.Errorf("could not write private key: %w", err)
+		}
+	}
+	if err := os.WriteFile(s.path(name, publicSuffix), pubPEM, 0644); err != nil {
+		return Identity{}, fmt.Errorf("could not write public key: %w", err)
+	}
+	if err := s.writeMetadata(identity); err != nil {
+		return Identity{}, err
+	}
+	return identity, nil
+}
+
+// Find returns the identity whose key has the fingerprint.
+func (s *Store) Find(fingerprint string) (Identity, error) {
+	identities, err := s.List()
+	if err != nil {
+		return Identity{}, err
+	}
+	for _, identity := range identities {
+		if identity.Fingerprint == fingerprint {
+			return identity, nil
+		}
+	}
+	return Identity{}, fmt.Errorf("%w: %s", ErrNotFound, fingerprint)
+}
+
+// AddStatement checks that the key stored under name signed the statement, keeps
+// it after the public key and records what it says in the metadata. Statements
+// already stored are not added again.
+func (s *Store) AddStatement(name string, st statement.Statement) (Identity, error) {
+	identity, err := s.Get(name)
+	if err != nil {
+		return Identity{}, err
+	}
+	pubPEM, err := s.PublicKeyPEM(name)
+	if err != nil {
+		return Identity{}, err
+	}
+	publicKey, err := crypto.DecodePublicKey(string(pubPEM))
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid public key %s: %w", name, err)
+	}
+	if err := st.Verify(publicKey); err != nil {
+		return Identity{}, err
+	}
+	statements, err := statement.Parse(string(pubPEM))
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid statements for key %s: %w", name, err)
+	}
+
+	stored := false
+	for _, existing := range statements {
+		stored = stored || existing.Signature == st.Signature
+	}
+	if !stored {
+		block, err := st.Encode()
+		if err != nil {
+			return Identity{}, err
+		}
+		if err := os.WriteFile(s.path(name, publicSuffix), append(pubPEM, block...), 0644); err != nil {
+			return Identity{}, fmt.Errorf("could not write public key: %w", err)
+		}
+		statements = append(statements, st)
+	}
+
+	status, err := statement.StatusOf(publicKey, statements)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity.NotBefore, identity.NotAfter = status.NotBefore, status.NotAfter
+	if status.Revoked != nil {
+		identity.Revoked, identity.RevocationReason = status.Revoked.Issued, status.Revoked.Reason
+	}
+	if err := s.writeMetadata(identity); err != nil {
+		return Identity{}, err
+	}
+	return identity, nil
+}
+
+// PublicKeyPEM returns the PEM encoded public key of name, followed by the
+// statements stored with it.
+func (s *Store) PublicKeyPEM(name string) ([]byte, error) {
+	if _, err := s.Get(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(name, publicSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("could not read public key %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// PrivateKeyPath returns the file holding the private key of name.
+func (s *Store) PrivateKeyPath(name string) (string, error) {
+	identity, err := s.Get(name)
+	if err != nil {
+		return "", err
+	}
+	if !identity.Private {
+		return "", fmt.Errorf("%w: %s", ErrNoPrivateKey, name)
+	}
+	return s.path(name, privateSuffix), nil
+}
+
+// PublicKeyPath returns the file holding the public key of name.
+func (s *Store) PublicKeyPath(name string) (string, error) {
+	if _, err := s.Get(name); err != nil {
+		return "", err
+	}
+	return s.path(name, publicSuffix), nil
+}
+
+// Delete removes the identity called name.
+func (s *Store) Delete(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+	for _, suffix := range []string{privateSuffix, publicSuffix, metadataSuffix} {
+		if err := os.Remove(s.path(name, suffix)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not delete key %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Rename moves the identity called oldName to newName. An existing identity
+// called newName is only replaced with force.
+func (s *Store) Rename(oldName, newName string, force bool) (Identity, error) {
+	identity, err := s.Get(oldName)
+	if err != nil {
+		return Identity{}, err
+	}
+	if err := CheckName(newName); err != nil {
+		return Identity{}, err
+	}
+	if oldName == newName {
+		return identity, nil
+	}
+	if s.Has(newName) {
+		if !force {
+			return Identity{}, fmt.Errorf("%w: %s", ErrExists, newName)
+		}
+		if err := s.Delete(newName); err != nil {
+			return Identity{}, err
+		}
+	}
+
+	for _, suffix := range []string{privateSuffix, publicSuffix} {
+		err := os.Rename(s.path(oldName, suffix), s.path(newName, suffix))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return Identity{}, fmt.Errorf("could not rename key %s: %w", oldName, err)
+		}
+	}
+	identity.Name = newName
+	if err := s.writeMetadata(identity); err != nil {
+		return Identity{}, err
+	}
+	if err := os.Remove(s.path(oldName, metadataSuffix)); err != nil {
+		return Identity{}, fmt.Errorf("could not rename key %s: %w", oldName, err)
+	}
+	return identity, nil
+}
+
+func (s *Store) writeMetadata(identity Identity) error {
+	data, err := json.MarshalIndent(identity, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode key metadata: %w", err)
+	}
+	if err := os.WriteFile(s.path(identity.Name, metadataSuffix), append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("could not write key metadata: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) path(name, suffix string) string {
+	return filepath.Join(s.Dir, name+suffix)
+}
@@ -0,0 +1,308 @@
+package keystore
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/statement"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddAndList(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "keys"))
+	identities, err := store.List()
+	if err != nil || len(identities) != 0 {
+		t.Fatalf("Expected an empty store, got %v (%v)", identities, err)
+	}
+
+	privateKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, alicePub, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mine, err := store.AddPrivate("work", privateKey, false)
+	if err != nil {
+		t.Fatalf("AddPrivate failed: %v", err)
+	}
+	if mine.Type != TypeRSA || mine.Bits != 2048 || !mine.Private || mine.Created.IsZero() {
+		t.Errorf("Unexpected identity %+v", mine)
+	}
+	fingerprint, _ := crypto.FingerprintRSAPublicKey(&privateKey.PublicKey)
+	if mine.Fingerprint != fingerprint {
+		t.Errorf("Expected fingerprint %s, got %s", fingerprint, mine.Fingerprint)
+	}
+	if _, err := store.AddPublic("alice", alicePub, false); err != nil {
+		t.Fatalf("AddPublic failed: %v", err)
+	}
+
+	identities, err = store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 2 || identities[0].Name != "alice" || identities[0].Private || identities[1].Name != "work" {
+		t.Errorf("Unexpected identities %+v", identities)
+	}
+
+	path, err := store.PrivateKeyPath("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private key file only readable by the owner, got %v (%v)", info.Mode(), err)
+	}
+	if _, err := store.PrivateKeyPath("alice"); !errors.Is(err, ErrNoPrivateKey) {
+		t.Errorf("Expected ErrNoPrivateKey, got %v", err)
+	}
+	pubPEM, err := store.PublicKeyPEM("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, err := crypto.DecodeRSAPublicKey(string(pubPEM)); err != nil || decoded.N.Cmp(alicePub.N) != 0 {
+		t.Errorf("Expected alice's public key back, got %v", err)
+	}
+}
+
+func TestEd25519Identity(t *testing.T) {
+	store := New(t.TempDir())
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := store.AddPrivate("ssh", privateKey, false)
+	if err != nil {
+		t.Fatalf("AddPrivate failed: %v", err)
+	}
+	if identity.Type != TypeEd25519 || identity.Bits != 256 {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	path, err := store.PrivateKeyPath("ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := crypto.DecodePrivateKey(pemBytes)
+	if err != nil || !privateKey.Equal(decoded) {
+		t.Errorf("Expected the Ed25519 key back, got %v", err)
+	}
+}
+
+func TestOverwriteNeedsForce(t *testing.T) {
+	store := New(t.TempDir())
+	first, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.AddPrivate("work", first, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddPrivate("work", second, false); !errors.Is(err, ErrExists) {
+		t.Fatalf("Expected ErrExists, got %v", err)
+	}
+	replaced, err := store.AddPrivate("work", second, true)
+	if err != nil {
+		t.Fatalf("AddPrivate with force failed: %v", err)
+	}
+	fingerprint, _ := crypto.FingerprintRSAPublicKey(&second.PublicKey)
+	if replaced.Fingerprint != fingerprint {
+		t.Error("Expected the key to be replaced")
+	}
+}
+
+func TestPublicKeyKeepsKeyPair(t *testing.T) {
+	store := New(t.TempDir())
+	privateKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddPrivate("work", privateKey, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Even with force, the private key must not be lost
+	if _, err := store.AddPublic("work", other, true); !errors.Is(err, ErrPrivateKeyStored) {
+		t.Fatalf("Expected ErrPrivateKeyStored, got %v", err)
+	}
+	identity, err := store.Get("work")
+	if err != nil || !identity.Private {
+		t.Fatalf("Expected the key pair to be kept, got %+v (%v)", identity, err)
+	}
+	if _, err := store.PrivateKeyPath("work"); err != nil {
+		t.Errorf("Expected the private key to be kept: %v", err)
+	}
+
+	if err := store.Delete("work"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddPublic("work", other, false); err != nil {
+		t.Errorf("Expected the public key to be stored once the key pair is deleted: %v", err)
+	}
+}
+
+func TestRenameAndDelete(t *testing.T) {
+	store := New(t.TempDir())
+	privateKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddPrivate("old", privateKey, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddPublic("taken", &privateKey.PublicKey, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Rename("old", "taken", false); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected ErrExists, got %v", err)
+	}
+	renamed, err := store.Rename("old", "new", false)
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if renamed.Name != "new" || store.Has("old") {
+		t.Errorf("Expected old to be renamed to new, got %+v", renamed)
+	}
+	if _, err := store.PrivateKeyPath("new"); err != nil {
+		t.Errorf("Expected the private key to move along: %v", err)
+	}
+
+	if err := store.Delete("new"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get("new"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete("new"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestAddStatement(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "keys"))
+	privateKey, publicKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddPublic("alice", publicKey, false); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	validity, err := statement.Validity(privateKey, now, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := store.AddStatement("alice", validity)
+	if err != nil {
+		t.Fatalf("AddStatement failed: %v", err)
+	}
+	if !identity.NotAfter.Equal(validity.NotAfter) || identity.Status().Check(now) != nil {
+		t.Errorf("Expected the key to be valid until %s, got %+v", validity.NotAfter, identity)
+	}
+
+	// Statements are kept with the public key, once
+	revocation, err := statement.Revocation(privateKey, now, "compromised")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if identity, err = store.AddStatement("alice", revocation); err != nil {
+			t.Fatalf("AddStatement failed: %v", err)
+		}
+	}
+	if err := identity.Status().Check(now); !errors.Is(err, statement.ErrRevoked) || !strings.Contains(err.Error(), "compromised") {
+		t.Errorf("Expected the key to be revoked, got %v", err)
+	}
+	pubPEM, _ := store.PublicKeyPEM("alice")
+	if statements, err := statement.Parse(string(pubPEM)); err != nil || len(statements) != 2 {
+		t.Errorf("Expected two stored statements, got %d (%v)", len(statements), err)
+	}
+	if found, err := store.Find(identity.Fingerprint); err != nil || found.Name != "alice" || found.Revoked.IsZero() {
+		t.Errorf("Expected Find to return the revoked key, got %+v (%v)", found, err)
+	}
+
+	// Only the key itself can make statements about it
+	otherKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := statement.Validity(otherKey, now, 365*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddStatement("alice", forged); !errors.Is(err, statement.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for another key's statement, got %v", err)
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	for _, name := range []string{"work", "alice.laptop", "bob_2", "x-y"} {
+		if err := CheckName(name); err != nil {
+			t.Errorf("Expected %q to be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".hidden", "a/b", "../up", "name.pub", "with space"} {
+		if err := CheckName(name); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestPins(t *testing.T) {
+	pins := &Pins{Path: filepath.Join(t.TempDir(), "config", PinsFile)}
+	if pinned, err := pins.Get("alice"); err != nil || pinned != "" {
+		t.Fatalf("Expected no pin yet, got %q (%v)", pinned, err)
+	}
+
+	if isNew, err := pins.Check("alice", "SHA256:first"); err != nil || !isNew {
+		t.Fatalf("Expected the first key to be pinned, got %v (%v)", isNew, err)
+	}
+	if isNew, err := pins.Check("alice", "SHA256:first"); err != nil || isNew {
+		t.Errorf("Expected the pinned key to pass, got %v (%v)", isNew, err)
+	}
+	_, err := pins.Check("alice", "SHA256:second")
+	if !errors.Is(err, ErrKeyChanged) || !strings.Contains(err.Error(), "SHA256:first") || !strings.Contains(err.Error(), "SHA256:second") {
+		t.Errorf("Expected ErrKeyChanged naming both fingerprints, got %v", err)
+	}
+	if pinned, _ := pins.Get("alice"); pinned != "SHA256:first" {
+		t.Errorf("Expected a changed key to leave the pin alone, got %s", pinned)
+	}
+
+	if err := pins.Set("https://example.com/alice.keys", "SHA256:first"); err != nil {
+		t.Fatal(err)
+	}
+	if name, err := pins.Find("SHA256:first"); err != nil || name != "alice" {
+		t.Errorf("Expected the first name pinned to the key, got %q (%v)", name, err)
+	}
+	if name, err := pins.Find("SHA256:second"); err != nil || name != "" {
+		t.Errorf("Expected no name for a key never pinned, got %q (%v)", name, err)
+	}
+
+	if err := pins.Delete("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if pinned, _ := pins.Get("alice"); pinned != "" {
+		t.Errorf("Expected the pin to be deleted, got %s", pinned)
+	}
+}
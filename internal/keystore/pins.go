@@ -0,0 +1,122 @@
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ErrKeyChanged is returned when a key differs from the one first seen for the same name.
+var ErrKeyChanged = errors.New("key does not match the pinned fingerprint")
+
+// PinsFile is the name of the pins file inside the config directory.
+const PinsFile = "pins.json"
+
+// Pins remembers the fingerprint of the key first trusted for each name, so a key
+// that changes later is noticed (trust on first use). Names are stored contacts
+// or the targets of send --to. Receive checks the senders of signed payloads
+// against the same pins. They are kept as a JSON object in one file.
+type Pins struct {
+	Path string
+}
+
+// Get returns the fingerprint pinned for name, or "" if there is none.
+func (p *Pins) Get(name string) (string, error) {
+	pins, err := p.load()
+	if err != nil {
+		return "", err
+	}
+	return pins[name], nil
+}
+
+// Set pins fingerprint for name.
+func (p *Pins) Set(name, fingerprint string) error {
+	pins, err := p.load()
+	if err != nil {
+		return err
+	}
+	pins[name] = fingerprint
+	return p.save(pins)
+}
+
+// Delete forgets the fingerprint pinned for name.
+func (p *Pins) Delete(name string) error {
+	pins, err := p.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := pins[name]; !ok {
+		return nil
+	}
+	delete(pins, name)
+	return p.save(pins)
+}
+
+// Find returns the name fingerprint is pinned for, the first in sorted order if
+// there are several, or "" if it isn't pinned at all.
+func (p *Pins) Find(fingerprint string) (string, error) {
+	pins, err := p.load()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(pins))
+	for name, pinned := range pins {
+		if pinned == fingerprint {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	slices.Sort(names)
+	return names[0], nil
+}
+
+// Check compares fingerprint with the one pinned for name. A name seen for the
+// first time is pinned and reported as new; a different fingerprint is refused
+// with ErrKeyChanged, naming both the old and the new one.
+func (p *Pins) Check(name, fingerprint string) (bool, error) {
+	pinned, err := p.Get(name)
+	if err != nil {
+		return false, err
+	}
+	switch pinned {
+	case fingerprint:
+		return false, nil
+	case "":
+		return true, p.Set(name, fingerprint)
+	default:
+		return false, fmt.Errorf("%w: %s was pinned to %s, but is now %s", ErrKeyChanged, name, pinned, fingerprint)
+	}
+}
+
+func (p *Pins) load() (map[string]string, error) {
+	pins := map[string]string{}
+	data, err := os.ReadFile(p.Path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return pins, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read pins: %w", err)
+	}
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("invalid pins file %s: %w", p.Path, err)
+	}
+	return pins, nil
+}
+
+func (p *Pins) save(pins map[string]string) error {
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode pins: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+	if err := os.WriteFile(p.Path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("could not write pins: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Package localkey encrypts secrets AirBridge keeps on disk, such as pooled and
+// session keys, with AES-GCM under a random key stored in a file of its own. The
+// key file is kept in another directory than what it encrypts, so a copy of one
+// of them alone gives nothing away. This only obscures the secrets: the key file
+// is not protected by a passphrase, so anyone who can read both directories,
+// such as any program running as the same user, can decrypt them.
+package localkey
+
+import (
+	"AirBridge/internal/crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nonceSize is the size of the AES-GCM nonce sealed data starts with.
+const nonceSize = 12
+
+// Seal encrypts data with the key in keyFile, creating the key if there is none yet.
+func Seal(keyFile string, data []byte) ([]byte, error) {
+	key, err := Load(keyFile, true)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := crypto.GenerateIV()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := crypto.EncryptDataAES(key, nonce, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt: %w", err)
+	}
+	return append(nonce, encrypted...), nil
+}
+
+// Open decrypts data sealed with the key in keyFile.
+func Open(keyFile string, sealed []byte) ([]byte, error) {
+	key, err := Load(keyFile, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("%w: sealed data is truncated", crypto.ErrInvalidKey)
+	}
+	data, err := crypto.DecryptDataAES(key, sealed[:nonceSize], sealed[nonceSize:])
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt: %w", err)
+	}
+	return data, nil
+}
+
+// Load reads the key in keyFile, creating it first if create is set.
+func Load(keyFile string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(keyFile)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%w: invalid key in %s", crypto.ErrInvalidKey, keyFile)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) || !create {
+		return nil, fmt.Errorf("could not read %s: %w", filepath.Base(keyFile), err)
+	}
+
+	key, err := crypto.GenerateAESKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return nil, fmt.Errorf("could not create %s: %w", filepath.Base(keyFile), err)
+	}
+	// Linked into place, so it fails if another process created the key first
+	partial := fmt.Sprintf("%s.%d.partial", keyFile, os.Getpid())
+	if err := os.WriteFile(partial, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("could not write %s: %w", filepath.Base(keyFile), err)
+	}
+	defer func() { _ = os.Remove(partial) }()
+	if err := os.Link(partial, keyFile); errors.Is(err, os.ErrExist) {
+		return Load(keyFile, false)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not write %s: %w", filepath.Base(keyFile), err)
+	}
+	return key, nil
+}
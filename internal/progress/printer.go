@@ -0,0 +1,86 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/mattn/go-isatty"
+)
+
+// Printer writes progress as a single line that is redrawn in place on
+// terminals, and as plain lines at a slower pace everywhere else.
+type Printer struct {
+	out      io.Writer
+	terminal bool
+	interval time.Duration
+
+	mu    sync.Mutex
+	last  time.Time
+	stage string
+}
+
+// NewPrinter returns a printer writing to out.
+func NewPrinter(out io.Writer) *Printer {
+	terminal := false
+	if f, ok := out.(*os.File); ok {
+		terminal = isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+	}
+	interval := 250 * time.Millisecond
+	if !terminal {
+		interval = 2 * time.Second
+	}
+	return &Printer{out: out, terminal: terminal, interval: interval}
+}
+
+// NewLinePrinter returns a printer writing plain lines to out, even on a
+// terminal, for screen readers and other line-based output.
+func NewLinePrinter(out io.Writer) *Printer {
+	return &Printer{out: out, interval: 2 * time.Second}
+}
+
+// Func returns the Func to hand to an operation.
+func (p *Printer) Func() Func {
+	return p.Print
+}
+
+// Print writes the update if enough time has passed since the last one.
+func (p *Printer) Print(u Update) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if !u.Finished && u.Stage == p.stage && now.Sub(p.last) < p.interval {
+		return
+	}
+	p.last = now
+	p.stage = u.Stage
+
+	line := Format(u)
+	if p.terminal {
+		_, _ = fmt.Fprintf(p.out, "\r\033[K%s", line)
+		if u.Finished {
+			_, _ = fmt.Fprintln(p.out)
+		}
+		return
+	}
+	_, _ = fmt.Fprintln(p.out, line)
+}
+
+// Format renders an update as "Stage  45% 12 MB / 27 MB  8.1 MB/s  ETA 2s".
+func Format(u Update) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %3.0f%% %s / %s", u.Stage, u.Percent()*100,
+		humanize.Bytes(uint64(u.Done)), humanize.Bytes(uint64(u.Total)))
+	if u.Rate > 0 {
+		fmt.Fprintf(&b, "  %s/s", humanize.Bytes(uint64(u.Rate)))
+	}
+	if !u.Finished && u.ETA > 0 {
+		fmt.Fprintf(&b, "  ETA %s", u.ETA.Round(time.Second))
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package progress
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Func receives progress updates. A nil Func ignores them.
+type Func func(Update)
+
+// Update describes how far an operation has come.
+type Update struct {
+	Stage string
+	Done  int64
+	Total int64
+	// Rate is the throughput in bytes per second.
+	Rate float64
+	ETA  time.Duration
+	// Finished is set on the last update of a stage.
+	Finished bool
+}
+
+// Percent returns the completed fraction between 0 and 1.
+func (u Update) Percent() float64 {
+	if u.Total <= 0 {
+		return 0
+	}
+	p := float64(u.Done) / float64(u.Total)
+	if p > 1 {
+		p = 1
+	}
+	return p
+}
+
+// DefaultInterval is the minimum time between two reported updates.
+const DefaultInterval = 100 * time.Millisecond
+
+// Tracker counts bytes of one stage and reports them to a Func at most once per Interval.
+type Tracker struct {
+	Interval time.Duration
+
+	mu       sync.Mutex
+	stage    string
+	total    int64
+	done     int64
+	start    time.Time
+	reported time.Time
+	fn       Func
+}
+
+// NewTracker returns a tracker for a stage of total bytes.
+func NewTracker(stage string, total int64, fn Func) *Tracker {
+	return &Tracker{
+		Interval: DefaultInterval,
+		stage:    stage,
+		total:    total,
+		start:    time.Now(),
+		fn:       fn,
+	}
+}
+
+// Add records n more bytes as done.
+func (t *Tracker) Add(n int64) {
+	if t == nil || t.fn == nil {
+		return
+	}
+	t.mu.Lock()
+	t.done += n
+	now := time.Now()
+	if now.Sub(t.reported) < t.Interval {
+		t.mu.Unlock()
+		return
+	}
+	t.reported = now
+	update := t.snapshot(now, false)
+	t.mu.Unlock()
+
+	t.fn(update)
+}
+
+// Finish reports the stage as complete.
+func (t *Tracker) Finish() {
+	if t == nil || t.fn == nil {
+		return
+	}
+	t.mu.Lock()
+	if t.total > 0 {
+		t.done = t.total
+	}
+	update := t.snapshot(time.Now(), true)
+	t.mu.Unlock()
+
+	t.fn(update)
+}
+
+func (t *Tracker) snapshot(now time.Time, finished bool) Update {
+	update := Update{
+		Stage:    t.stage,
+		Done:     t.done,
+		Total:    t.total,
+		Finished: finished,
+	}
+	elapsed := now.Sub(t.start).Seconds()
+	if elapsed > 0 {
+		update.Rate = float64(t.done) / elapsed
+	}
+	if update.Rate > 0 && t.total > t.done {
+		update.ETA = time.Duration(float64(t.total-t.done) / update.Rate * float64(time.Second))
+	}
+	return update
+}
+
+// Reader wraps r so every read is counted.
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	return &reader{r: r, t: t}
+}
+
+// Writer wraps w so every write is counted.
+func (t *Tracker) Writer(w io.Writer) io.Writer {
+	return &writer{w: w, t: t}
+}
+
+type reader struct {
+	r io.Reader
+	t *Tracker
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.t.Add(int64(n))
+	return n, err
+}
+
+type writer struct {
+	w io.Writer
+	t *Tracker
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.t.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,86 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTrackerReportsProgress(t *testing.T) {
+	var updates []Update
+	tracker := NewTracker("Hashing", 10, func(u Update) { updates = append(updates, u) })
+	tracker.Interval = 0
+
+	if _, err := io.Copy(io.Discard, tracker.Reader(strings.NewReader("0123456789"))); err != nil {
+		t.Fatal(err)
+	}
+	tracker.Finish()
+
+	if len(updates) < 2 {
+		t.Fatalf("Expected at least 2 updates, got %d", len(updates))
+	}
+	last := updates[len(updates)-1]
+	if !last.Finished || last.Done != 10 || last.Percent() != 1 {
+		t.Errorf("Unexpected final update %+v", last)
+	}
+	if last.Stage != "Hashing" {
+		t.Errorf("Expected stage Hashing, got %s", last.Stage)
+	}
+}
+
+func TestTrackerWithoutFunc(t *testing.T) {
+	tracker := NewTracker("Hashing", 10, nil)
+	var out bytes.Buffer
+	if _, err := tracker.Writer(&out).Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	tracker.Finish()
+	if out.String() != "data" {
+		t.Errorf("Expected writes to pass through, got %q", out.String())
+	}
+}
+
+func TestPercent(t *testing.T) {
+	if p := (Update{Done: 5, Total: 0}).Percent(); p != 0 {
+		t.Errorf("Expected 0 for unknown total, got %v", p)
+	}
+	if p := (Update{Done: 20, Total: 10}).Percent(); p != 1 {
+		t.Errorf("Expected percent capped at 1, got %v", p)
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	var out bytes.Buffer
+	printer := NewPrinter(&out)
+
+	printer.Print(Update{Stage: "Encrypting", Done: 512, Total: 1024, Rate: 2048})
+	// Throttled: too soon after the previous line
+	printer.Print(Update{Stage: "Encrypting", Done: 600, Total: 1024})
+	printer.Print(Update{Stage: "Encrypting", Done: 1024, Total: 1024, Finished: true})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], "50%") || !strings.Contains(lines[0], "2.0 kB/s") {
+		t.Errorf("Unexpected progress line %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "100%") {
+		t.Errorf("Unexpected final line %q", lines[1])
+	}
+}
+
+func TestLinePrinter(t *testing.T) {
+	var out bytes.Buffer
+	printer := NewLinePrinter(&out)
+	printer.Print(Update{Stage: "Decrypting", Done: 512, Total: 1024})
+	printer.Print(Update{Stage: "Decrypting", Done: 1024, Total: 1024, Finished: true})
+
+	if strings.ContainsAny(out.String(), "\r\x1b") {
+		t.Errorf("Expected no cursor movement, got %q", out.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 {
+		t.Errorf("Expected 2 lines, got %q", out.String())
+	}
+}
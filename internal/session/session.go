@@ -0,0 +1,256 @@
+// Package session keeps the temporary key of a receive, so it can be resumed after
+// AirBridge was closed: the sender's payload can still be decrypted when it
+// arrives later. A session is encrypted at rest (see localkey), expires after a
+// while, and is deleted once it has decrypted a payload.
+package session
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/localkey"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for session IDs that don't exist, or were deleted.
+	ErrNotFound = errors.New("no such receive session")
+	// ErrExpired is returned for sessions whose time ran out. They are deleted.
+	ErrExpired = errors.New("receive session has expired")
+)
+
+// KeyFileName is the name of the file in the config directory that holds the key
+// sessions are encrypted with.
+const KeyFileName = "sessions.key"
+
+// idBytes is the number of random bytes in a session ID.
+const idBytes = 4
+
+// createAttempts is how many IDs Create tries before giving up, should the
+// ones it picks already be taken.
+const createAttempts = 5
+
+// partialSuffix is added to the file name of a session being written.
+const partialSuffix = ".partial"
+
+// errInvalid is returned for session files that can't be decoded.
+var errInvalid = errors.New("invalid session")
+
+// Session is a receive waiting for its payload.
+type Session struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+	// Fingerprint is the fingerprint of the public key handed to the sender.
+	Fingerprint string `json:"fingerprint"`
+	// Key is the private key as PEM, encrypted with the store's key file.
+	Key []byte `json:"key"`
+}
+
+// Store is a directory of sessions, each kept as <id>.json.
+type Store struct {
+	Dir string
+	// KeyFile holds the key the session keys are encrypted with.
+	KeyFile string
+}
+
+// New returns the store of sessions kept in dir, encrypted with the key in keyFile.
+func New(dir, keyFile string) *Store {
+	return &Store{Dir: dir, KeyFile: keyFile}
+}
+
+// Create saves privateKey as a new session that expires after ttl.
+func (s *Store) Create(privateKey crypto.PrivateKey, ttl time.Duration) (Session, error) {
+	fingerprint, err := crypto.FingerprintPublicKey(crypto.PublicKeyOf(privateKey))
+	if err != nil {
+		return Session{}, err
+	}
+	privPEM, err := crypto.ExportPrivateKeyAsPEM(privateKey)
+	if err != nil {
+		return Session{}, err
+	}
+	sealed, err := localkey.Seal(s.KeyFile, privPEM)
+	if err != nil {
+		return Session{}, fmt.Errorf("could not encrypt session key: %w", err)
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return Session{}, fmt.Errorf("could not create sessions directory: %w", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for range createAttempts {
+		id := make([]byte, idBytes)
+		if _, err := rand.Read(id); err != nil {
+			return Session{}, fmt.Errorf("could not create session ID: %w", err)
+		}
+		session := Session{
+			ID:          hex.EncodeToString(id),
+			Created:     now,
+			Expires:     now.Add(ttl),
+			Fingerprint: fingerprint,
+			Key:         sealed,
+		}
+		data, err := json.MarshalIndent(session, "", "  ")
+		if err != nil {
+			return Session{}, fmt.Errorf("could not encode session: %w", err)
+		}
+		if err := s.write(session.ID, data); errors.Is(err, os.ErrExist) {
+			// The ID is taken, try another
+			continue
+		} else if err != nil {
+			return Session{}, fmt.Errorf("could not save session: %w", err)
+		}
+		return session, nil
+	}
+	return Session{}, fmt.Errorf("could not save session: no free session ID after %d tries", createAttempts)
+}
+
+// write saves a session's data as <id>.json. It is written under another name
+// first, so a session is never read half written, then linked into place rather
+// than renamed, so a session that already has the ID is never replaced: that
+// returns an error matching os.ErrExist.
+func (s *Store) write(id string, data []byte) error {
+	partial := s.path(id) + partialSuffix
+	file, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Link(partial, s.path(id))
+}
+
+// Open returns the session with the given ID and its private key. An expired
+// session is deleted, and ErrExpired returned.
+func (s *Store) Open(id string) (Session, crypto.PrivateKey, error) {
+	session, err := s.read(id)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	if !time.Now().Before(session.Expires) {
+		_ = s.Delete(id)
+		return Session{}, nil, fmt.Errorf("%w: %s expired on %s", ErrExpired, id, session.Expires.Local().Format("2006-01-02 15:04"))
+	}
+	privPEM, err := localkey.Open(s.KeyFile, session.Key)
+	if err != nil {
+		return Session{}, nil, fmt.Errorf("could not decrypt session key: %w", err)
+	}
+	privateKey, err := crypto.DecodePrivateKey(privPEM)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	return session, privateKey, nil
+}
+
+// List returns the sessions that haven't expired, oldest first, and deletes the
+// ones that have.
+func (s *Store) List() ([]Session, error) {
+	sessions, _, err := s.sweep()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(sessions, func(a, b Session) int { return a.Created.Compare(b.Created) })
+	return sessions, nil
+}
+
+// Purge deletes the sessions that have expired or are corrupt, and returns how
+// many it deleted.
+func (s *Store) Purge() (int, error) {
+	_, purged, err := s.sweep()
+	return purged, err
+}
+
+// sweep deletes the expired sessions, returning the live ones and the number
+// deleted. Sessions that can't be decoded are deleted too, as they can never be
+// opened, and ones that can't be read are skipped.
+func (s *Store) sweep() ([]Session, int, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, fmt.Errorf("could not read sessions directory: %w", err)
+	}
+
+	now := time.Now()
+	var sessions []Session
+	purged := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		session, err := s.read(id)
+		if errors.Is(err, errInvalid) {
+			if err := s.Delete(id); err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, purged, err
+			}
+			purged++
+			continue
+		} else if err != nil {
+			continue
+		}
+		if !now.Before(session.Expires) {
+			if err := s.Delete(id); err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, purged, err
+			}
+			purged++
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, purged, nil
+}
+
+// Delete removes a session, destroying its key.
+func (s *Store) Delete(id string) error {
+	if !validID(id) {
+		return fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	if err := os.Remove(s.path(id)); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	} else if err != nil {
+		return fmt.Errorf("could not delete session: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) read(id string) (Session, error) {
+	if !validID(id) {
+		return Session{}, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Session{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	} else if err != nil {
+		return Session{}, fmt.Errorf("could not read session: %w", err)
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return Session{}, fmt.Errorf("%w %s: %w", errInvalid, id, err)
+	}
+	return session, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+// validID reports whether id looks like a session ID, so it can't name a file
+// outside the store.
+func validID(id string) bool {
+	decoded, err := hex.DecodeString(id)
+	return err == nil && len(decoded) == idBytes
+}
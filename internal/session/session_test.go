@@ -0,0 +1,169 @@
+package session
+
+import (
+	"AirBridge/internal/crypto"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newStore(t *testing.T) *Store {
+	t.Helper()
+	dir := t.TempDir()
+	return New(filepath.Join(dir, "cache", "sessions"), filepath.Join(dir, "config", KeyFileName))
+}
+
+func TestCreateAndOpen(t *testing.T) {
+	s := newStore(t)
+	privateKey, publicKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := s.Create(privateKey, time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// The key is encrypted at rest
+	data, err := os.ReadFile(s.path(created.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("PRIVATE KEY")) {
+		t.Error("Expected the session key to be encrypted")
+	}
+
+	session, opened, err := s.Open(created.ID)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if !publicKey.Equal(crypto.PublicKeyOf(opened)) || session.Fingerprint != created.Fingerprint {
+		t.Error("Expected the session's key back")
+	}
+
+	sessions, err := s.List()
+	if err != nil || len(sessions) != 1 || sessions[0].ID != created.ID {
+		t.Errorf("Expected the session to be listed, got %v (%v)", sessions, err)
+	}
+
+	if err := s.Delete(created.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, _, err := s.Open(created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+	}
+	if _, _, err := s.Open("../../config/" + KeyFileName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a path, got %v", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	s := newStore(t)
+	privateKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.Create(privateKey, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, err := s.Create(privateKey, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := s.List()
+	if err != nil || len(sessions) != 1 || sessions[0].ID != live.ID {
+		t.Errorf("Expected only the live session to be listed, got %v (%v)", sessions, err)
+	}
+	if _, err := os.Stat(s.path(expired.ID)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the expired session to be deleted, got %v", err)
+	}
+
+	other, err := s.Create(privateKey, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Open(other.ID); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+	if _, _, err := s.Open(other.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the expired session to be gone, got %v", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	s := newStore(t)
+	if purged, err := s.Purge(); err != nil || purged != 0 {
+		t.Errorf("Expected nothing to purge without a sessions directory, got %d (%v)", purged, err)
+	}
+	privateKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := s.Create(privateKey, -time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	live, err := s.Create(privateKey, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if purged, err := s.Purge(); err != nil || purged != 2 {
+		t.Errorf("Expected the 2 expired sessions to be purged, got %d (%v)", purged, err)
+	}
+	if _, _, err := s.Open(live.ID); err != nil {
+		t.Errorf("Expected the live session to be kept: %v", err)
+	}
+}
+
+func TestCorruptSessionIsPurged(t *testing.T) {
+	s := newStore(t)
+	privateKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, err := s.Create(privateKey, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cut off, like a session whose write was interrupted
+	if err := os.WriteFile(s.path("0badc0de"), []byte(`{"id": "0bad`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := s.List()
+	if err != nil || len(sessions) != 1 || sessions[0].ID != live.ID {
+		t.Errorf("Expected the corrupt session to be left out, got %v (%v)", sessions, err)
+	}
+	if _, err := os.Stat(s.path("0badc0de")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the corrupt session to be deleted, got %v", err)
+	}
+}
+
+func TestWriteKeepsExistingSession(t *testing.T) {
+	s := newStore(t)
+	privateKey, _, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, err := s.Create(privateKey, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.write(live.ID, []byte("{}")); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected a taken ID to be refused, got %v", err)
+	}
+	if _, _, err := s.Open(live.ID); err != nil {
+		t.Errorf("Expected the session to be kept: %v", err)
+	}
+	if entries, _ := os.ReadDir(s.Dir); len(entries) != 1 {
+		t.Errorf("Expected no partial file to be left behind, got %v", entries)
+	}
+}
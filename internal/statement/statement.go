@@ -0,0 +1,233 @@
+// Package statement lets a key sign what is known about it: the time it is valid
+// for, and that it was revoked. Statements are PEM blocks kept after the public
+// key they are about, so they travel with it and anyone holding the key can check
+// them.
+package statement
+
+import (
+	"AirBridge/internal/crypto"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRevoked is returned for keys their owner revoked.
+	ErrRevoked = errors.New("key has been revoked")
+	// ErrExpired is returned for keys whose validity has ended.
+	ErrExpired = errors.New("key has expired")
+	// ErrNotYetValid is returned for keys whose validity hasn't started.
+	ErrNotYetValid = errors.New("key is not valid yet")
+	// ErrInvalid is returned for statements that can't be read, or weren't signed by their key.
+	ErrInvalid = errors.New("invalid key statement")
+)
+
+// BlockType is the PEM block type of a statement.
+const BlockType = "AIRBRIDGE KEY STATEMENT"
+
+// Version is the version of the statement format written by this release.
+const Version = 1
+
+// Kinds of statements.
+const (
+	KindValidity   = "validity"
+	KindRevocation = "revocation"
+)
+
+// signaturePrefix starts the message signed for a statement, so the signature
+// can't be mistaken for one over anything else.
+const signaturePrefix = "AirBridge key statement v1"
+
+// Statement is a key's signature over its validity, or over its revocation.
+type Statement struct {
+	Version int    `json:"version"`
+	Kind    string `json:"kind"`
+	// Key is the fingerprint of the key the statement is about, which also signed it.
+	Key string `json:"key"`
+	// Issued is when the statement was made; for a revocation, when the key was revoked.
+	Issued    time.Time `json:"issued"`
+	NotBefore time.Time `json:"not_before,omitzero"`
+	NotAfter  time.Time `json:"not_after,omitzero"`
+	Reason    string    `json:"reason,omitempty"`
+	Signature string    `json:"signature"`
+}
+
+// Status is what the statements about a key say about it. Zero times are no limit.
+type Status struct {
+	NotBefore time.Time
+	NotAfter  time.Time
+	// Revoked is the revocation of the key, if it was revoked.
+	Revoked *Statement
+}
+
+// Validity returns a statement, signed by privateKey, that its key is valid from
+// notBefore for validity.
+func Validity(privateKey crypto.PrivateKey, notBefore time.Time, validity time.Duration) (Statement, error) {
+	notBefore = notBefore.UTC().Truncate(time.Second)
+	return sign(privateKey, Statement{
+		Kind:      KindValidity,
+		Issued:    notBefore,
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(validity),
+	})
+}
+
+// Revocation returns a statement, signed by privateKey, that its key is revoked
+// as of at.
+func Revocation(privateKey crypto.PrivateKey, at time.Time, reason string) (Statement, error) {
+	return sign(privateKey, Statement{
+		Kind:   KindRevocation,
+		Issued: at.UTC().Truncate(time.Second),
+		Reason: reason,
+	})
+}
+
+func sign(privateKey crypto.PrivateKey, s Statement) (Statement, error) {
+	fingerprint, err := crypto.FingerprintPublicKey(crypto.PublicKeyOf(privateKey))
+	if err != nil {
+		return Statement{}, err
+	}
+	s.Version = Version
+	s.Key = fingerprint
+	signature, err := crypto.Sign(privateKey, s.signedMessage())
+	if err != nil {
+		return Statement{}, err
+	}
+	s.Signature = base64.StdEncoding.EncodeToString(signature)
+	return s, nil
+}
+
+// Verify checks that the statement is about publicKey and was signed by it.
+func (s Statement) Verify(publicKey crypto.PublicKey) error {
+	fingerprint, err := crypto.FingerprintPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	if s.Key != fingerprint {
+		return fmt.Errorf("%w: the statement is about key %s, not %s", ErrInvalid, s.Key, fingerprint)
+	}
+	signature, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %s statement: %w", ErrInvalid, s.Kind, crypto.ErrBadSignature)
+	}
+	if err := crypto.VerifySignature(publicKey, s.signedMessage(), signature); err != nil {
+		return fmt.Errorf("%w: %s statement: %w", ErrInvalid, s.Kind, err)
+	}
+	return nil
+}
+
+// Encode returns the statement as a PEM block.
+func (s Statement) Encode() ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode key statement: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: BlockType, Bytes: data}), nil
+}
+
+// Parse returns the statements in text, which may be a public key followed by its
+// statements, in PEM or base64 encoded PEM as crypto.DecodePublicKey accepts it.
+// Signatures aren't checked, see Verify and StatusOf.
+func Parse(text string) ([]Statement, error) {
+	data := []byte(text)
+	if decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), "")); err == nil {
+		data = decoded
+	}
+
+	var statements []Statement
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != BlockType {
+			continue
+		}
+		var s Statement
+		if err := json.Unmarshal(block.Bytes, &s); err != nil {
+			return nil, fmt.Errorf("%w: invalid json: %w", ErrInvalid, err)
+		}
+		if s.Version > Version || (s.Kind != KindValidity && s.Kind != KindRevocation) {
+			return nil, fmt.Errorf("%w: unsupported %q statement, version %d", ErrInvalid, s.Kind, s.Version)
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
+
+// StatusOf returns what the statements about publicKey say about it: the validity
+// issued last, and the first revocation. Statements about other keys are ignored,
+// but one about this key that it didn't sign is an error.
+func StatusOf(publicKey crypto.PublicKey, statements []Statement) (Status, error) {
+	fingerprint, err := crypto.FingerprintPublicKey(publicKey)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var status Status
+	var issued time.Time
+	for _, s := range statements {
+		if s.Key != fingerprint {
+			continue
+		}
+		if err := s.Verify(publicKey); err != nil {
+			return Status{}, err
+		}
+		switch {
+		case s.Kind == KindRevocation && status.Revoked == nil:
+			revoked := s
+			status.Revoked = &revoked
+		case s.Kind == KindValidity && !s.Issued.Before(issued):
+			issued = s.Issued
+			status.NotBefore, status.NotAfter = s.NotBefore, s.NotAfter
+		}
+	}
+	return status, nil
+}
+
+// Check returns ErrRevoked, ErrExpired or ErrNotYetValid if the key may not be
+// used at now.
+func (st Status) Check(now time.Time) error {
+	switch {
+	case st.Revoked != nil:
+		if st.Revoked.Reason != "" {
+			return fmt.Errorf("%w on %s: %s", ErrRevoked, formatDate(st.Revoked.Issued), st.Revoked.Reason)
+		}
+		return fmt.Errorf("%w on %s", ErrRevoked, formatDate(st.Revoked.Issued))
+	case !st.NotAfter.IsZero() && !now.Before(st.NotAfter):
+		return fmt.Errorf("%w on %s", ErrExpired, formatDate(st.NotAfter))
+	case !st.NotBefore.IsZero() && now.Before(st.NotBefore):
+		return fmt.Errorf("%w until %s", ErrNotYetValid, formatDate(st.NotBefore))
+	}
+	return nil
+}
+
+// signedMessage returns what the key signs for the statement.
+func (s Statement) signedMessage() []byte {
+	return []byte(strings.Join([]string{
+		signaturePrefix,
+		s.Kind,
+		s.Key,
+		formatTime(s.Issued),
+		formatTime(s.NotBefore),
+		formatTime(s.NotAfter),
+		s.Reason,
+	}, "\n"))
+}
+
+// formatTime formats t for signing; the zero time, an unset limit, is empty.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatDate(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
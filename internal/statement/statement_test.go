@@ -0,0 +1,115 @@
+package statement
+
+import (
+	"AirBridge/internal/crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidity(t *testing.T) {
+	privateKey, publicKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s, err := Validity(privateKey, now.Add(-time.Hour), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Validity failed: %v", err)
+	}
+
+	// The statement travels after the public key, also in the base64 form
+	pubPEM, _ := crypto.ExportPublicKeyAsPEM(publicKey)
+	block, err := s.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{string(pubPEM) + string(block), base64.StdEncoding.EncodeToString(append(pubPEM, block...))} {
+		statements, err := Parse(text)
+		if err != nil || len(statements) != 1 {
+			t.Fatalf("Expected one statement, got %d (%v)", len(statements), err)
+		}
+		if _, err := crypto.DecodePublicKey(text); err != nil {
+			t.Errorf("Expected the key to still decode, got %v", err)
+		}
+		status, err := StatusOf(publicKey, statements)
+		if err != nil {
+			t.Fatalf("StatusOf failed: %v", err)
+		}
+		if err := status.Check(now); err != nil {
+			t.Errorf("Expected the key to be valid now, got %v", err)
+		}
+		if err := status.Check(now.Add(48 * time.Hour)); !errors.Is(err, ErrExpired) {
+			t.Errorf("Expected ErrExpired, got %v", err)
+		}
+		if err := status.Check(now.Add(-2 * time.Hour)); !errors.Is(err, ErrNotYetValid) {
+			t.Errorf("Expected ErrNotYetValid, got %v", err)
+		}
+	}
+
+	// A later validity replaces an earlier one
+	extended, err := Validity(privateKey, now, 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := StatusOf(publicKey, []Statement{extended, s})
+	if err != nil || status.Check(now.Add(48*time.Hour)) != nil {
+		t.Errorf("Expected the extended validity to be used, got %+v (%v)", status, err)
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Revocation(privateKey, time.Now(), "laptop stolen")
+	if err != nil {
+		t.Fatalf("Revocation failed: %v", err)
+	}
+	other, err := Revocation(otherKey, time.Now(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Statements about other keys are ignored
+	status, err := StatusOf(publicKey, []Statement{other, s})
+	if err != nil {
+		t.Fatalf("StatusOf failed: %v", err)
+	}
+	if err := status.Check(time.Now()); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+	if err := other.Verify(publicKey); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a statement about another key to fail, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(s *Statement)
+	}{
+		{"Changed reason", func(s *Statement) { s.Reason = "" }},
+		{"Changed kind", func(s *Statement) { s.Kind = KindValidity }},
+		{"Bad signature", func(s *Statement) { s.Signature = "AAAA" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := s
+			tt.tamper(&tampered)
+			if _, err := StatusOf(publicKey, []Statement{tampered}); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+
+	if _, err := Parse("-----BEGIN " + BlockType + "-----\neyJraW5kIjoibGVhc2UifQ==\n-----END " + BlockType + "-----\n"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected an unknown kind to fail, got %v", err)
+	}
+}
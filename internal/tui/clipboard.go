@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"AirBridge/internal/clipboard"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ClipboardClearMsg fires when copied secret text is due to be cleared.
+type ClipboardClearMsg struct{}
+
+// Copy writes text to the clipboard. For secrets it also returns a command
+// that fires ClipboardClearMsg once the clear timeout has passed.
+func Copy(text string, secret bool) (tea.Cmd, error) {
+	if err := clipboard.Default.Copy(text, secret); err != nil {
+		return nil, err
+	}
+	remaining, ok := clipboard.Default.Pending()
+	if !ok {
+		return nil, nil
+	}
+	return tea.Tick(remaining, func(time.Time) tea.Msg { return ClipboardClearMsg{} }), nil
+}
+
+// CopiedText describes a successful copy, including when it will be cleared.
+func CopiedText(what string) string {
+	text := fmt.Sprintf("%s copied to clipboard.", what)
+	if remaining, ok := clipboard.Default.Pending(); ok {
+		text += fmt.Sprintf(" It will be cleared in %s.", remaining.Round(time.Second))
+	}
+	return SuccessStyle.Render(text)
+}
+
+// ExpireClipboard clears the clipboard if a copied secret is due and
+// returns a status text describing what happened.
+func ExpireClipboard() string {
+	cleared, err := clipboard.Default.Expire()
+	if err != nil {
+		return WarningStyle.Render(fmt.Sprintf("Could not clear clipboard: %v", err))
+	}
+	if cleared {
+		return SubtleStyle.Render("Clipboard cleared.")
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// BackMsg is sent by an embedded flow when the user leaves it,
+// so the parent model can take over again.
+type BackMsg struct{}
+
+// TransferDoneMsg is sent by a flow once a file has been sent or received.
+type TransferDoneMsg struct {
+	Direction string
+	Name      string
+	Size      int64
+	Detail    string
+}
+
+// ExitCmd quits the program, or hands control back to the parent
+// model when the flow is embedded in another one.
+func ExitCmd(embedded bool) tea.Cmd {
+	if embedded {
+		return func() tea.Msg { return BackMsg{} }
+	}
+	return tea.Quit
+}
+
+// ExitKey returns the quit binding, described as going back when the
+// flow is embedded in another one.
+func ExitKey(embedded bool) key.Binding {
+	exit := Keys.Quit
+	if embedded {
+		exit.SetHelp(exit.Help().Key, "back")
+	}
+	return exit
+}
+
+// TransferDoneCmd reports a finished transfer to the parent model.
+func TransferDoneCmd(done TransferDoneMsg) tea.Cmd {
+	return func() tea.Msg { return done }
+}
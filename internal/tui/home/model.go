@@ -0,0 +1,94 @@
+package home
+
+import (
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/tui"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type Screen int
+
+const (
+	ScreenMenu Screen = iota
+	ScreenFlow
+	ScreenKeys
+	ScreenHistory
+)
+
+type Action int
+
+const (
+	ActionSend Action = iota
+	ActionReceive
+	ActionKeys
+	ActionHistory
+	ActionInbox
+)
+
+type menuItem struct {
+	action      Action
+	title       string
+	description string
+}
+
+type historyEntry struct {
+	tui.TransferDoneMsg
+	at time.Time
+}
+
+type Model struct {
+	tui.Window
+	screen Screen
+
+	items  []menuItem
+	cursor int
+
+	// active is the send or receive flow currently shown.
+	active tea.Model
+
+	history []historyEntry
+
+	// store holds the keys listed on the Keys screen. It may be nil.
+	store      *keystore.Store
+	identities []keystore.Identity
+
+	err error
+}
+
+// InitialModel initializes the home menu model. The Keys screen lists the keys in store.
+func InitialModel(store *keystore.Store) *Model {
+	return &Model{
+		screen: ScreenMenu,
+		store:  store,
+		items: []menuItem{
+			{action: ActionSend, title: "Send", description: "Encrypt a file for someone"},
+			{action: ActionReceive, title: "Receive", description: "Share a public key and decrypt a payload"},
+			{action: ActionInbox, title: "Inbox", description: "Share one public key and receive several payloads"},
+			{action: ActionKeys, title: "Keys/Contacts", description: "Manage your keys and known recipients"},
+			{action: ActionHistory, title: "History", description: "Transfers made in this session"},
+		},
+	}
+}
+
+func (m *Model) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor < 0 {
+		m.cursor = len(m.items) - 1
+	} else if m.cursor >= len(m.items) {
+		m.cursor = 0
+	}
+}
+
+func (m *Model) recordTransfer(done tui.TransferDoneMsg) {
+	m.history = append(m.history, historyEntry{TransferDoneMsg: done, at: time.Now()})
+}
+
+// Output returns what the active flow asked to print once the program exits.
+func (m *Model) Output() string {
+	if out, ok := m.active.(interface{ Output() string }); ok {
+		return out.Output()
+	}
+	return ""
+}
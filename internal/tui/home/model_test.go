@@ -0,0 +1,92 @@
+package home
+
+import (
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/keystore"
+	"AirBridge/internal/tui"
+	"AirBridge/internal/tui/send"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestMenuNavigation(t *testing.T) {
+	m := InitialModel(nil)
+	if m.screen != ScreenMenu {
+		t.Fatalf("Expected ScreenMenu, got %v", m.screen)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if m.cursor != len(m.items)-1 {
+		t.Errorf("Expected cursor to wrap to %d, got %d", len(m.items)-1, m.cursor)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if m.cursor != 0 {
+		t.Errorf("Expected cursor to wrap to 0, got %d", m.cursor)
+	}
+}
+
+func TestOpenFlowAndGoBack(t *testing.T) {
+	m := InitialModel(nil)
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	// First entry is Send
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.screen != ScreenFlow {
+		t.Fatalf("Expected ScreenFlow, got %v", m.screen)
+	}
+	if _, ok := m.active.(*send.Model); !ok {
+		t.Fatalf("Expected active send model, got %T", m.active)
+	}
+
+	// Esc inside the flow asks to go back instead of quitting
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("Expected a command from Esc")
+	}
+	msg := cmd()
+	if _, ok := msg.(tui.BackMsg); !ok {
+		t.Fatalf("Expected tui.BackMsg, got %T", msg)
+	}
+
+	m.Update(msg)
+	if m.screen != ScreenMenu || m.active != nil {
+		t.Error("Expected to be back on the menu")
+	}
+}
+
+func TestTransferDoneIsRecorded(t *testing.T) {
+	m := InitialModel(nil)
+	m.Update(tui.TransferDoneMsg{Direction: "sent", Name: "secret.txt", Size: 42})
+
+	if len(m.history) != 1 {
+		t.Fatalf("Expected 1 history entry, got %d", len(m.history))
+	}
+	if m.history[0].Name != "secret.txt" {
+		t.Errorf("Expected secret.txt, got %s", m.history[0].Name)
+	}
+}
+
+func TestKeysScreenListsStore(t *testing.T) {
+	store := keystore.New(t.TempDir())
+	_, publicKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := store.AddPublic("alice", publicKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := InitialModel(store)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m.open(ActionKeys)
+	if m.screen != ScreenKeys {
+		t.Fatalf("Expected ScreenKeys, got %v", m.screen)
+	}
+	view := m.View()
+	if !strings.Contains(view, "alice") || !strings.Contains(view, identity.Fingerprint) {
+		t.Errorf("Expected alice and her fingerprint on the Keys screen:\n%s", view)
+	}
+}
@@ -0,0 +1,120 @@
+package home
+
+import (
+	"AirBridge/internal/tui"
+	"AirBridge/internal/tui/receive"
+	"AirBridge/internal/tui/send"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tui.BackMsg:
+		m.active = nil
+		m.screen = ScreenMenu
+		return m, nil
+
+	case tui.TransferDoneMsg:
+		m.recordTransfer(msg)
+		return m, nil
+
+	case tui.ClipboardClearMsg:
+		if m.active == nil {
+			tui.ExpireClipboard()
+			return m, nil
+		}
+
+	case tea.WindowSizeMsg:
+		m.Resize(msg.Width, msg.Height)
+		if m.active != nil {
+			var cmd tea.Cmd
+			m.active, cmd = m.active.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			// Let the flow clean up, like a receive ending its session
+			if m.active != nil {
+				m.active.Update(msg)
+			}
+			return m, tea.Quit
+		}
+	}
+
+	switch m.screen {
+	case ScreenFlow:
+		if m.active == nil {
+			m.screen = ScreenMenu
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.active, cmd = m.active.Update(msg)
+		return m, cmd
+
+	case ScreenKeys, ScreenHistory:
+		if msg, ok := msg.(tea.KeyMsg); ok && key.Matches(msg, tui.Keys.Back) {
+			m.screen = ScreenMenu
+		}
+		return m, nil
+	}
+
+	// ScreenMenu
+	msgKey, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch {
+	case key.Matches(msgKey, tui.Keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msgKey, tui.Keys.Up):
+		m.moveCursor(-1)
+	case key.Matches(msgKey, tui.Keys.Down):
+		m.moveCursor(1)
+	case key.Matches(msgKey, tui.Keys.Select):
+		return m, m.open(m.items[m.cursor].action)
+	}
+	return m, nil
+}
+
+// open switches to the screen behind the given menu action.
+func (m *Model) open(action Action) tea.Cmd {
+	m.err = nil
+	switch action {
+	case ActionSend:
+		return m.startFlow(send.InitialModel("", "", "").Embed())
+	case ActionReceive:
+		return m.startFlow(receive.InitialModel(nil, "", "", false).Embed())
+	case ActionInbox:
+		return m.startFlow(receive.InitialModel(nil, "", "", false).Embed().Inbox())
+	case ActionKeys:
+		m.screen = ScreenKeys
+		if m.store != nil {
+			m.identities, m.err = m.store.List()
+		}
+	case ActionHistory:
+		m.screen = ScreenHistory
+	}
+	return nil
+}
+
+// startFlow hands the screen over to a send or receive model.
+func (m *Model) startFlow(flow tea.Model) tea.Cmd {
+	m.active = flow
+	m.screen = ScreenFlow
+
+	cmd := m.active.Init()
+	if m.Width > 0 {
+		var sizeCmd tea.Cmd
+		m.active, sizeCmd = m.active.Update(tea.WindowSizeMsg{Width: m.Width, Height: m.Height})
+		cmd = tea.Batch(cmd, sizeCmd)
+	}
+	return cmd
+}
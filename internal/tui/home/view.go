@@ -0,0 +1,110 @@
+package home
+
+import (
+	"AirBridge/internal/tui"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+)
+
+func (m *Model) View() string {
+	switch m.screen {
+	case ScreenFlow:
+		if m.active != nil {
+			return m.active.View()
+		}
+		return tui.View(m.Window, m.err, m.helpKeys(), "")
+	case ScreenKeys:
+		return tui.View(m.Window, m.err, m.helpKeys(), tui.MainStyle(m.Window).Render(m.keysView()))
+	case ScreenHistory:
+		return tui.View(m.Window, m.err, m.helpKeys(), tui.MainStyle(m.Window).Render(m.historyView()))
+	default:
+		return tui.View(m.Window, m.err, m.helpKeys(), tui.MainStyle(m.Window).Render(m.menuView()))
+	}
+}
+
+// helpKeys returns the bindings shown in the help bar for the current screen.
+func (m *Model) helpKeys() []key.Binding {
+	switch m.screen {
+	case ScreenKeys, ScreenHistory:
+		return []key.Binding{tui.Keys.Back}
+	default:
+		return []key.Binding{tui.Keys.Up, tui.Keys.Down, tui.Keys.Select, tui.Keys.Quit}
+	}
+}
+
+func (m *Model) menuView() string {
+	var rows []string
+	rows = append(rows, "What would you like to do?", "")
+	for i, item := range m.items {
+		cursor := "  "
+		title := item.title
+		if i == m.cursor {
+			cursor = tui.SuccessStyle.Render("> ")
+			title = tui.AccentStyle().Render(title)
+		}
+		rows = append(rows, cursor+title+"  "+tui.SubtleStyle.Render(item.description))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (m *Model) keysView() string {
+	lines := []string{"Keys/Contacts", ""}
+	if len(m.identities) == 0 {
+		lines = append(lines,
+			"The receive flow creates a fresh key pair for every session,",
+			"so you don't need keys of your own to get started.",
+			"",
+			"To keep a reusable key pair, run:",
+			tui.InfoStyle.Render("  airbridge keygen --name <name>"),
+		)
+		return strings.Join(lines, "\n")
+	}
+
+	for _, identity := range m.identities {
+		kind := "contact"
+		if identity.Private {
+			kind = "key pair"
+		}
+		line := fmt.Sprintf("%-16s %s  %s",
+			identity.Name,
+			tui.SubtleStyle.Render(fmt.Sprintf("%-8s %s-%d", kind, strings.ToUpper(identity.Type), identity.Bits)),
+			identity.Fingerprint,
+		)
+		if err := identity.Status().Check(time.Now()); err != nil {
+			line += "  " + tui.WarningStyle.Render(err.Error())
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines,
+		"",
+		"Use a key by name, e.g. send -k <name>. To manage keys, run:",
+		tui.InfoStyle.Render("  airbridge key list|show|export|import|delete|rename|trust|revoke"),
+	)
+	return strings.Join(lines, "\n")
+}
+
+func (m *Model) historyView() string {
+	lines := []string{"History", ""}
+	if len(m.history) == 0 {
+		lines = append(lines, tui.SubtleStyle.Render("No transfers yet in this session."))
+	}
+	for i := len(m.history) - 1; i >= 0; i-- {
+		entry := m.history[i]
+		line := fmt.Sprintf("%s  %-8s %s (%s)",
+			entry.at.Format("15:04:05"),
+			entry.Direction,
+			entry.Name,
+			humanize.Bytes(uint64(entry.Size)),
+		)
+		if entry.Detail != "" {
+			line += tui.SubtleStyle.Render("  " + entry.Detail)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
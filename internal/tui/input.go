@@ -0,0 +1,240 @@
+package tui
+
+import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/clipboard"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/bubbles/filepicker"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+)
+
+// LargePasteThreshold is the size above which pasted text is kept out of the
+// textarea, because rendering it there gets slow.
+const LargePasteThreshold = 4096
+
+// PasteInput is a textarea for keys and payloads that can also read the
+// clipboard (Ctrl+V) or a file (Ctrl+O), and checks what was entered.
+type PasteInput struct {
+	Textarea textarea.Model
+	Picker   filepicker.Model
+
+	expect  cli.ContentKind
+	kind    cli.ContentKind
+	picking bool
+
+	// held is large content kept out of the textarea.
+	held   string
+	source string
+
+	err error
+}
+
+// NewPasteInput returns an input expecting the given kind of content.
+// The file picker only offers files with the given extensions.
+func NewPasteInput(placeholder string, expect cli.ContentKind, extensions ...string) PasteInput {
+	ta := textarea.New()
+	ta.Placeholder = placeholder
+	ta.ShowLineNumbers = false
+	ta.Focus()
+
+	fp := filepicker.New()
+	fp.AllowedTypes = extensions
+	fp.AutoHeight = false
+
+	return PasteInput{
+		Textarea: ta,
+		Picker:   fp,
+		expect:   expect,
+	}
+}
+
+// Value returns the entered, pasted or loaded content.
+func (p PasteInput) Value() string {
+	if p.held != "" {
+		return p.held
+	}
+	return p.Textarea.Value()
+}
+
+// SetValue replaces the content.
+func (p *PasteInput) SetValue(text string) {
+	p.setContent(text, "")
+}
+
+// Reset clears the content.
+func (p *PasteInput) Reset() {
+	p.held = ""
+	p.source = ""
+	p.kind = cli.ContentEmpty
+	p.err = nil
+	p.Textarea.Reset()
+}
+
+// Kind returns what the current content looks like.
+func (p PasteInput) Kind() cli.ContentKind {
+	return p.kind
+}
+
+// Mismatch reports whether the content is recognised as something other than expected,
+// for example a public key pasted where a payload belongs.
+func (p PasteInput) Mismatch() bool {
+	switch p.kind {
+	case cli.ContentEmpty, cli.ContentUnknown:
+		return false
+	default:
+		return p.kind != p.expect
+	}
+}
+
+// Busy reports whether the file picker is open and handles keys such as Esc and Enter itself.
+func (p PasteInput) Busy() bool {
+	return p.picking
+}
+
+// HelpKeys returns the bindings the input handles in its current state.
+func (p PasteInput) HelpKeys() []key.Binding {
+	if p.picking {
+		return []key.Binding{Keys.Up, Keys.Down, Keys.Select, Keys.Cancel}
+	}
+	return []key.Binding{Keys.Paste, Keys.Open}
+}
+
+// SetSize sets the size of the textarea and the file picker.
+func (p *PasteInput) SetSize(width, height int) {
+	if height < 3 {
+		height = 3
+	}
+	p.Textarea.SetWidth(width)
+	p.Textarea.SetHeight(height)
+	p.Picker.SetHeight(height)
+}
+
+func (p *PasteInput) setContent(text string, source string) {
+	p.err = nil
+	p.source = source
+	if len(text) > LargePasteThreshold {
+		p.held = text
+		p.Textarea.Reset()
+	} else {
+		p.held = ""
+		p.Textarea.SetValue(text)
+	}
+	p.kind = cli.DetectContent(text)
+}
+
+func (p PasteInput) Update(msg tea.Msg) (PasteInput, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if p.picking {
+		if msg, ok := msg.(tea.KeyMsg); ok && key.Matches(msg, Keys.Cancel) {
+			p.picking = false
+			return p, nil
+		}
+		p.Picker, cmd = p.Picker.Update(msg)
+		if didSelect, path := p.Picker.DidSelectFile(msg); didSelect {
+			p.picking = false
+			content, err := os.ReadFile(path)
+			if err != nil {
+				p.err = fmt.Errorf("could not read %s: %w", path, err)
+				return p, nil
+			}
+			p.setContent(string(content), path)
+		}
+		return p, cmd
+	}
+
+	msgKey, ok := msg.(tea.KeyMsg)
+	if !ok {
+		p.Textarea, cmd = p.Textarea.Update(msg)
+		return p, cmd
+	}
+
+	switch {
+	case key.Matches(msgKey, Keys.Paste):
+		text, err := clipboard.Default.Read()
+		if errors.Is(err, clipboard.ErrReadUnsupported) {
+			p.err = fmt.Errorf("can't read the clipboard over %s, use your terminal's paste shortcut instead", clipboard.Default.Backend.Name())
+			return p, nil
+		} else if err != nil {
+			p.err = fmt.Errorf("could not read clipboard: %w", err)
+			return p, nil
+		}
+		p.setContent(text, "clipboard")
+		return p, nil
+
+	case key.Matches(msgKey, Keys.Open):
+		p.picking = true
+		p.err = nil
+		return p, p.Picker.Init()
+
+	case msgKey.Paste && len(msgKey.Runes) > LargePasteThreshold:
+		// Bracketed paste of a big payload: keep it out of the textarea
+		p.setContent(string(msgKey.Runes), "paste")
+		return p, nil
+
+	case p.held != "":
+		if key.Matches(msgKey, Keys.Discard) {
+			p.Reset()
+		}
+		return p, nil
+	}
+
+	p.Textarea, cmd = p.Textarea.Update(msg)
+	p.kind = cli.DetectContent(p.Textarea.Value())
+	p.err = nil
+	return p, cmd
+}
+
+// Hint describes whether the content looks like what is expected.
+func (p PasteInput) Hint() string {
+	switch {
+	case p.err != nil:
+		return ErrorStyle.Render(p.err.Error())
+	case p.kind == cli.ContentEmpty:
+		return ""
+	case p.kind == p.expect:
+		return SuccessStyle.Render(fmt.Sprintf("✓ Looks like %s", p.kind))
+	case p.Mismatch():
+		return WarningStyle.Render(fmt.Sprintf("This looks like %s, not %s", p.kind, p.expect))
+	default:
+		return SubtleStyle.Render(fmt.Sprintf("Doesn't look like %s yet", p.expect))
+	}
+}
+
+func (p PasteInput) View() string {
+	if p.picking {
+		return lipgloss.JoinVertical(lipgloss.Left,
+			"Select a file:",
+			p.Picker.View(),
+		)
+	}
+
+	var input string
+	if p.held != "" {
+		source := p.source
+		if source == "" || source == "paste" {
+			source = "pasted"
+		} else {
+			source = "from " + source
+		}
+		input = lipgloss.NewStyle().
+			Padding(0, 1).
+			Border(lipgloss.RoundedBorder()).
+			Render(fmt.Sprintf("%s %s (hidden to keep things fast)\n%s",
+				humanize.Bytes(uint64(len(p.held))),
+				source,
+				SubtleStyle.Render(fmt.Sprintf("Press '%s' to discard", Keys.Discard.Help().Key)),
+			))
+	} else {
+		input = p.Textarea.View()
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, input, p.Hint())
+}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"AirBridge/internal/cli"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPasteInputLargePasteIsHeld(t *testing.T) {
+	p := NewPasteInput("", cli.ContentPayload)
+	big := strings.Repeat("A", LargePasteThreshold+1)
+
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(big), Paste: true})
+
+	if p.Value() != big {
+		t.Error("Expected the pasted content as value")
+	}
+	if p.Textarea.Value() != "" {
+		t.Error("Expected large paste to be kept out of the textarea")
+	}
+
+	// Backspace discards the held content
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if p.Value() != "" {
+		t.Errorf("Expected empty value after discarding, got %d chars", len(p.Value()))
+	}
+}
+
+func TestPasteInputMismatch(t *testing.T) {
+	p := NewPasteInput("", cli.ContentPayload)
+	p.SetValue("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n")
+
+	if p.Kind() != cli.ContentPublicKey {
+		t.Errorf("Expected public key, got %v", p.Kind())
+	}
+	if !p.Mismatch() {
+		t.Error("Expected a public key to mismatch a payload input")
+	}
+	if !strings.Contains(p.Hint(), "not a payload") {
+		t.Errorf("Unexpected hint %q", p.Hint())
+	}
+
+	payload := base64.StdEncoding.EncodeToString([]byte(`{"key":"00","data":"00"}`))
+	p.SetValue(payload)
+	if p.Mismatch() {
+		t.Error("Expected payload to match")
+	}
+
+	p.Reset()
+	if p.Kind() != cli.ContentEmpty || p.Value() != "" {
+		t.Error("Expected empty input after reset")
+	}
+}
+
+func TestPasteInputFilePickerIsBusy(t *testing.T) {
+	p := NewPasteInput("", cli.ContentPayload, ".abp")
+
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if !p.Busy() {
+		t.Fatal("Expected file picker to be open")
+	}
+
+	p, _ = p.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if p.Busy() {
+		t.Error("Expected Esc to close the file picker")
+	}
+}
@@ -0,0 +1,206 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyMap holds the key bindings of all screens, so the help bar always
+// shows the keys that are actually handled.
+type KeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Select key.Binding
+	Back   key.Binding
+	Quit   key.Binding
+
+	Submit  key.Binding
+	Copy    key.Binding
+	Paste   key.Binding
+	Open    key.Binding
+	Discard key.Binding
+	Cancel  key.Binding
+	Forget  key.Binding
+
+	View   key.Binding
+	Save   key.Binding
+	Print  key.Binding
+	Scroll key.Binding
+	Close  key.Binding
+}
+
+// Keys is the key map used by all screens.
+var Keys = DefaultKeyMap()
+
+// DefaultKeyMap returns the built-in key bindings.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k", "shift+tab"),
+			key.WithHelp("↑/k", "up"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j", "tab"),
+			key.WithHelp("↓/j", "down"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("enter", " "),
+			key.WithHelp("enter", "select"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc", "q", "backspace"),
+			key.WithHelp("esc", "back"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "quit"),
+		),
+		Submit: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "submit"),
+		),
+		Copy: key.NewBinding(
+			key.WithKeys("ctrl+k"),
+			key.WithHelp("ctrl+k", "copy"),
+		),
+		Paste: key.NewBinding(
+			key.WithKeys("ctrl+v"),
+			key.WithHelp("ctrl+v", "paste"),
+		),
+		Open: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "open file"),
+		),
+		Discard: key.NewBinding(
+			key.WithKeys("backspace", "delete", "ctrl+u"),
+			key.WithHelp("backspace", "discard"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+		Forget: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "quit and delete session"),
+		),
+		View: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "view payload"),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "save"),
+		),
+		Print: key.NewBinding(
+			key.WithKeys("ctrl+p"),
+			key.WithHelp("ctrl+p", "print and exit"),
+		),
+		Scroll: key.NewBinding(
+			key.WithKeys("up", "down", "pgup", "pgdown"),
+			key.WithHelp("↑/↓", "scroll"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "close"),
+		),
+	}
+}
+
+// bindings maps the action names used in the config file to the bindings.
+func (k *KeyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":      &k.Up,
+		"down":    &k.Down,
+		"select":  &k.Select,
+		"back":    &k.Back,
+		"quit":    &k.Quit,
+		"submit":  &k.Submit,
+		"copy":    &k.Copy,
+		"paste":   &k.Paste,
+		"open":    &k.Open,
+		"discard": &k.Discard,
+		"cancel":  &k.Cancel,
+		"forget":  &k.Forget,
+		"view":    &k.View,
+		"save":    &k.Save,
+		"print":   &k.Print,
+		"scroll":  &k.Scroll,
+		"close":   &k.Close,
+	}
+}
+
+// keyScreens lists the actions each screen handles. Actions that are never
+// handled on the same screen, like quit and close, may share keys.
+var keyScreens = []struct {
+	name    string
+	actions []string
+}{
+	{"home menu", []string{"up", "down", "select", "quit"}},
+	{"payload and key input", []string{"submit", "copy", "paste", "open", "discard", "forget", "quit"}},
+	{"file picker", []string{"up", "down", "select", "cancel"}},
+	{"payload ready", []string{"copy", "view", "save", "print", "quit"}},
+	{"payload viewer", []string{"copy", "save", "print", "scroll", "close"}},
+	{"save prompt", []string{"submit", "cancel"}},
+}
+
+// conflicts returns an error naming two actions of the same screen that share a key.
+func (k *KeyMap) conflicts() error {
+	bindings := k.bindings()
+	for _, screen := range keyScreens {
+		owner := make(map[string]string)
+		for _, name := range screen.actions {
+			for _, keyName := range bindings[name].Keys() {
+				if other, ok := owner[keyName]; ok {
+					return fmt.Errorf("%q is bound to both %q and %q on the %s screen", keyName, other, name, screen.name)
+				}
+				owner[keyName] = name
+			}
+		}
+	}
+	return nil
+}
+
+// KeyActions returns the action names that can be rebound, sorted.
+func KeyActions() []string {
+	var k KeyMap
+	var names []string
+	for name := range k.bindings() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply replaces the keys of the named actions, e.g. {"copy": {"ctrl+y"}}.
+// The key map is left unchanged if two actions of one screen would share a key.
+func (k *KeyMap) Apply(overrides map[string][]string) error {
+	updated := *k
+	bindings := updated.bindings()
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		binding, ok := bindings[name]
+		if !ok {
+			return fmt.Errorf("unknown action %q (expected one of %s)", name, strings.Join(KeyActions(), ", "))
+		}
+		keys := overrides[name]
+		if len(keys) == 0 {
+			return fmt.Errorf("no keys given for %q", name)
+		}
+		binding.SetKeys(keys...)
+		binding.SetHelp(strings.Join(keys, "/"), binding.Help().Desc)
+	}
+	if err := updated.conflicts(); err != nil {
+		return err
+	}
+	*k = updated
+	return nil
+}
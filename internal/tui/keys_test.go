@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeyMapApply(t *testing.T) {
+	keys := DefaultKeyMap()
+	err := keys.Apply(map[string][]string{
+		"copy":   {"ctrl+y"},
+		"submit": {"ctrl+d", "alt+enter"},
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyCtrlY}, keys.Copy) {
+		t.Error("Expected ctrl+y to copy")
+	}
+	if key.Matches(tea.KeyMsg{Type: tea.KeyCtrlK}, keys.Copy) {
+		t.Error("Expected ctrl+k to no longer copy")
+	}
+	if key.Matches(tea.KeyMsg{Type: tea.KeyEnter}, keys.Submit) {
+		t.Error("Expected enter to no longer submit")
+	}
+	if got := keys.Submit.Help().Key; got != "ctrl+d/alt+enter" {
+		t.Errorf("Expected help to show the new keys, got %q", got)
+	}
+	if got := keys.Submit.Help().Desc; got != "submit" {
+		t.Errorf("Expected the description to stay, got %q", got)
+	}
+}
+
+func TestKeyMapApplyErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+	}{
+		{"Unknown action", map[string][]string{"launch": {"ctrl+l"}}},
+		{"No keys", map[string][]string{"copy": {}}},
+		{"Shadowed by a default", map[string][]string{"view": {"ctrl+k"}}},
+		{"Two overrides on one key", map[string][]string{"save": {"ctrl+x"}, "print": {"ctrl+x"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := DefaultKeyMap()
+			if err := keys.Apply(tt.overrides); err == nil {
+				t.Error("Expected error")
+			}
+			if keys.Copy.Help().Key != "ctrl+k" || keys.View.Help().Key != "v" || keys.Save.Help().Key != "ctrl+s" {
+				t.Error("Expected the key map to be left unchanged")
+			}
+		})
+	}
+}
+
+func TestKeyMapConflictNamesActions(t *testing.T) {
+	keys := DefaultKeyMap()
+	err := keys.Apply(map[string][]string{"view": {"ctrl+k"}})
+	if err == nil || !strings.Contains(err.Error(), `"copy"`) || !strings.Contains(err.Error(), `"view"`) {
+		t.Errorf("Expected the error to name copy and view, got %v", err)
+	}
+
+	// Actions on different screens may share a key
+	if err := keys.Apply(map[string][]string{"close": {"q"}, "back": {"q"}}); err != nil {
+		t.Errorf("Expected no conflict, got %v", err)
+	}
+}
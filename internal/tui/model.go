@@ -1,8 +1,39 @@
 package tui
 
+import "github.com/charmbracelet/lipgloss"
+
 type Window struct {
 	Width           int
 	Height          int
 	AvailableHeight int
 	AvailableWidth  int
 }
+
+// Compact reports whether the terminal is too small for the full banner.
+func (w Window) Compact() bool {
+	if w.Width == 0 && w.Height == 0 {
+		return false
+	}
+	return w.Height < CompactHeight || w.Width < lipgloss.Width(AirBridgeBanner())
+}
+
+// Resize sets the terminal size and the space left for the screen content
+// between the header and the footer.
+func (w *Window) Resize(width, height int) {
+	w.Width = width
+	w.Height = height
+
+	headerH := lipgloss.Height(Header(*w))
+	footerH := lipgloss.Height(Footer(*w, nil, nil))
+
+	w.AvailableHeight = height - (headerH + footerH)
+	if w.AvailableHeight < 3 {
+		w.AvailableHeight = 3
+	}
+
+	// MainStyle pads the content by 2 columns on each side
+	w.AvailableWidth = width - 4
+	if w.AvailableWidth < 10 {
+		w.AvailableWidth = 10
+	}
+}
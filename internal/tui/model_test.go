@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		name    string
+		width   int
+		height  int
+		compact bool
+	}{
+		{"Split pane", 80, 24, true},
+		{"Narrow", 40, 60, true},
+		{"Full screen", 120, 50, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w Window
+			w.Resize(tt.width, tt.height)
+
+			if w.Compact() != tt.compact {
+				t.Errorf("Compact() = %v, want %v", w.Compact(), tt.compact)
+			}
+			if w.AvailableWidth != tt.width-4 {
+				t.Errorf("Expected AvailableWidth %d, got %d", tt.width-4, w.AvailableWidth)
+			}
+
+			view := View(w, nil, []key.Binding{Keys.Copy}, MainStyle(w).Render("content"))
+			if h := lipgloss.Height(view); h > tt.height {
+				t.Errorf("View is %d lines high, terminal has %d", h, tt.height)
+			}
+			if !strings.Contains(view, "ctrl+k") {
+				t.Error("Expected the help bar to list ctrl+k")
+			}
+		})
+	}
+}
+
+func TestCompactHeader(t *testing.T) {
+	var w Window
+	w.Resize(80, 24)
+	if h := lipgloss.Height(Header(w)); h > 2 {
+		t.Errorf("Expected a one-line title, got %d lines", h)
+	}
+	if w.AvailableHeight < 15 {
+		t.Errorf("Expected at least 15 rows for the content, got %d", w.AvailableHeight)
+	}
+}
+
+func TestFooterDescribesError(t *testing.T) {
+	var w Window
+	w.Resize(120, 50)
+	if footer := Footer(w, errors.New("boom"), nil); !strings.Contains(footer, "boom") {
+		t.Errorf("Expected the error message, got %q", footer)
+	}
+
+	describe := DescribeError
+	t.Cleanup(func() { DescribeError = describe })
+	DescribeError = func(err error) string { return "hint for " + err.Error() }
+	if footer := Footer(w, errors.New("boom"), nil); !strings.Contains(footer, "hint for boom") {
+		t.Errorf("Expected the described error, got %q", footer)
+	}
+}
+
+func TestExitKey(t *testing.T) {
+	if desc := ExitKey(false).Help().Desc; desc != "quit" {
+		t.Errorf("Expected quit, got %q", desc)
+	}
+	if desc := ExitKey(true).Help().Desc; desc != "back" {
+		t.Errorf("Expected back, got %q", desc)
+	}
+	if Keys.Quit.Help().Desc != "quit" {
+		t.Error("ExitKey must not change the shared key map")
+	}
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"AirBridge/internal/progress"
+	"fmt"
+	"time"
+
+	bar "github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+	"github.com/muesli/termenv"
+)
+
+// ProgressMsg carries a progress update from a running operation.
+type ProgressMsg struct {
+	progress.Update
+	ch chan progress.Update
+}
+
+// Progress shows the progress of the operation started with Run.
+type Progress struct {
+	Bar bar.Model
+
+	update progress.Update
+	ch     chan progress.Update
+}
+
+// NewProgress returns an empty progress display drawn in the active theme.
+func NewProgress() Progress {
+	if ActiveTheme.Accent == "" {
+		return Progress{Bar: bar.New(bar.WithFillCharacters('#', '-'), bar.WithColorProfile(termenv.Ascii))}
+	}
+	return Progress{Bar: bar.New(bar.WithSolidFill(ActiveTheme.Accent))}
+}
+
+// Run wraps the command built by start so the progress it reports reaches
+// the model as ProgressMsg.
+func (p *Progress) Run(start func(onProgress progress.Func) tea.Cmd) tea.Cmd {
+	ch := make(chan progress.Update, 1)
+	p.ch = ch
+	p.update = progress.Update{}
+
+	report := func(u progress.Update) {
+		// Keep only the latest update if the UI falls behind
+		select {
+		case ch <- u:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- u:
+			default:
+			}
+		}
+	}
+
+	cmd := start(report)
+	run := func() tea.Msg {
+		defer close(ch)
+		return cmd()
+	}
+	return tea.Batch(run, waitForProgress(ch))
+}
+
+func waitForProgress(ch chan progress.Update) tea.Cmd {
+	return func() tea.Msg {
+		u, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return ProgressMsg{Update: u, ch: ch}
+	}
+}
+
+// Update records a ProgressMsg and keeps listening for the next one.
+func (p Progress) Update(msg ProgressMsg) (Progress, tea.Cmd) {
+	if msg.ch != p.ch {
+		// Left over from an earlier operation
+		return p, nil
+	}
+	p.update = msg.Update
+	return p, waitForProgress(p.ch)
+}
+
+// Active reports whether there is progress to show.
+func (p Progress) Active() bool {
+	return p.update.Total > 0
+}
+
+// View renders the bar with bytes done, throughput and time left.
+func (p Progress) View(width int) string {
+	if !p.Active() {
+		return ""
+	}
+	if width > 0 {
+		p.Bar.Width = width
+	}
+
+	u := p.update
+	details := fmt.Sprintf("%s: %s / %s", u.Stage, humanize.Bytes(uint64(u.Done)), humanize.Bytes(uint64(u.Total)))
+	if u.Rate > 0 {
+		details += fmt.Sprintf(" • %s/s", humanize.Bytes(uint64(u.Rate)))
+	}
+	if !u.Finished && u.ETA > 0 {
+		details += fmt.Sprintf(" • %s left", u.ETA.Round(time.Second))
+	}
+	return p.Bar.ViewAs(u.Percent()) + "\n" + SubtleStyle.Render(details)
+}
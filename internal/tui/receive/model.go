@@ -1,14 +1,16 @@
 package receive
 
 import (
+	"AirBridge/internal/cli"
 	"AirBridge/internal/crypto"
+	"AirBridge/internal/progress"
 	"AirBridge/internal/tui"
-	"crypto/rsa"
+	"AirBridge/pkg"
 	"fmt"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textarea"
-	"github.com/charmbracelet/lipgloss"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 type Step int
@@ -21,20 +23,42 @@ const (
 	StepSuccess
 )
 
+// inboxItem is a payload handled in inbox mode.
+type inboxItem struct {
+	// path is where the file was saved, and metadata what the sender said about it.
+	path     string
+	metadata pkg.FileMetadata
+	// err is why the payload couldn't be decrypted, if it couldn't.
+	err error
+	at  time.Time
+}
+
 type Model struct {
 	tui.Window
 	step Step
 
 	spinner  spinner.Model
-	textarea textarea.Model
+	progress tui.Progress
+	input    tui.PasteInput
 
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
 	encodedKey string
+	// sessionID is the saved session of the key, which ends once a payload is
+	// decrypted or the user forgets it.
+	sessionID string
+	// keys are the private keys to pick from by each payload's recipient, when
+	// receiving with a directory of keys.
+	keys []cli.KeyFile
 
 	payload     string
 	payloadPath string
 	deleteFile  bool
+	embedded    bool
+
+	// inbox keeps the model accepting payloads after each one, with the same key.
+	inbox    bool
+	received []inboxItem
 
 	statusText string
 	err        error
@@ -44,27 +68,24 @@ type Model struct {
 func InitialModel(initialPrivKeyPEM []byte, initialPayload string, payloadPath string, deleteFile bool) *Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	s.Style = tui.SpinnerStyle
 
-	ta := textarea.New()
-	ta.Placeholder = "Paste Base64 encoded payload here ..."
-	ta.ShowLineNumbers = false
-	ta.Focus()
+	input := tui.NewPasteInput("Paste Base64 encoded payload here ...", cli.ContentPayload, ".abp", ".txt")
 
 	window := tui.Window{}
 
-	var privateKey *rsa.PrivateKey
-	var publicKey *rsa.PublicKey
+	var privateKey crypto.PrivateKey
+	var publicKey crypto.PublicKey
 	var encodedKey string
 	var step = StepGeneratingKey
 	var statusText string
 	var err error
 
 	if len(initialPrivKeyPEM) > 0 {
-		privateKey, err = crypto.DecodeRSAPrivateKey(initialPrivKeyPEM)
+		privateKey, err = crypto.DecodePrivateKey(initialPrivKeyPEM)
 		if err == nil {
-			publicKey = &privateKey.PublicKey
-			encodedKey, err = crypto.EncodeRSAPublicKey(publicKey)
+			publicKey = crypto.PublicKeyOf(privateKey)
+			encodedKey, err = crypto.EncodePublicKey(publicKey)
 			if err == nil {
 				step = StepAwaitingPayload
 			}
@@ -78,7 +99,7 @@ func InitialModel(initialPrivKeyPEM []byte, initialPayload string, payloadPath s
 	}
 
 	if initialPayload != "" {
-		ta.SetValue(initialPayload)
+		input.SetValue(initialPayload)
 		// If we already have the key, we can proceed
 		if step == StepAwaitingPayload {
 			statusText = "Decrypting..."
@@ -92,7 +113,8 @@ func InitialModel(initialPrivKeyPEM []byte, initialPayload string, payloadPath s
 		Window:      window,
 		step:        step,
 		spinner:     s,
-		textarea:    ta,
+		progress:    tui.NewProgress(),
+		input:       input,
 		privateKey:  privateKey,
 		publicKey:   publicKey,
 		encodedKey:  encodedKey,
@@ -104,6 +126,72 @@ func InitialModel(initialPrivKeyPEM []byte, initialPayload string, payloadPath s
 	}
 }
 
+// Embed marks the model as running inside another model, so leaving it
+// returns to the parent instead of quitting the program.
+func (m *Model) Embed() *Model {
+	m.embedded = true
+	return m
+}
+
+// Resume marks the model as resuming the receive session with the given ID, so
+// the session ends once its payload is decrypted.
+func (m *Model) Resume(sessionID string) *Model {
+	m.sessionID = sessionID
+	return m
+}
+
+// Keys makes the model decrypt with whichever of the keys each payload is for,
+// picked once the payload is known. The first key stands in until then.
+func (m *Model) Keys(keys []cli.KeyFile) *Model {
+	if len(keys) == 0 {
+		return m
+	}
+	m.keys = keys
+	m.privateKey = keys[0].Key
+	m.publicKey = crypto.PublicKeyOf(keys[0].Key)
+	m.encodedKey = ""
+	if len(keys) == 1 {
+		m.encodedKey, _ = crypto.EncodePublicKey(m.publicKey)
+	}
+	m.step = StepAwaitingPayload
+	return m
+}
+
+// Inbox keeps the model receiving: after each payload it goes back to waiting
+// for the next one, decrypted with the same key, until the user quits.
+func (m *Model) Inbox() *Model {
+	m.inbox = true
+	return m
+}
+
+// leave ends the session of an inbox that received something, as its key was
+// only kept to receive more. A receive that got nothing can still be resumed.
+func (m *Model) leave() {
+	if !m.inbox {
+		return
+	}
+	for _, item := range m.received {
+		if item.err == nil {
+			m.forget()
+			return
+		}
+	}
+}
+
+// forget ends the session, so the receive can't be resumed.
+func (m *Model) forget() {
+	_ = cli.EndSession(m.sessionID)
+	m.sessionID = ""
+}
+
+// decrypt starts decrypting and saving the payload.
+func (m *Model) decrypt() tea.Cmd {
+	payload, privateKey, keys := m.payload, m.privateKey, m.keys
+	return m.progress.Run(func(onProgress progress.Func) tea.Cmd {
+		return decryptAndSaveCmd(payload, privateKey, keys, onProgress)
+	})
+}
+
 func (m *Model) nextStep() {
 	if m.privateKey == nil {
 		m.step = StepGeneratingKey
@@ -1,9 +1,17 @@
 package receive
 
 import (
+	"AirBridge/internal/cli"
 	"AirBridge/internal/crypto"
+	"AirBridge/internal/session"
+	"crypto/rsa"
+	"errors"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 func TestInitialModel(t *testing.T) {
@@ -59,3 +67,183 @@ func TestInitialModel(t *testing.T) {
 		t.Errorf("Expected warning in status text, got %q", model3.statusText)
 	}
 }
+
+func TestSubmitRejectsPublicKey(t *testing.T) {
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privKeyPEM, _ := crypto.ExportRSAPrivateKeyAsPEM(privKey)
+	encodedKey, _ := crypto.EncodeRSAPublicKey(pubKey)
+
+	model := InitialModel(privKeyPEM, "", "", false)
+	model.input.SetValue(encodedKey)
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Error("Expected no decryption to start")
+	}
+	if model.step != StepAwaitingPayload {
+		t.Errorf("Expected to stay on StepAwaitingPayload, got %v", model.step)
+	}
+	if model.err == nil || !strings.Contains(model.err.Error(), "not a payload") {
+		t.Errorf("Expected a 'not a payload' error, got %v", model.err)
+	}
+}
+
+func TestSessionEndsAfterDecryption(t *testing.T) {
+	dir := t.TempDir()
+	sessions := session.New(dir, filepath.Join(dir, session.KeyFileName))
+	previous := cli.Default
+	t.Cleanup(func() { cli.Default = previous })
+	cli.Default.Sessions, cli.Default.SessionTTL = sessions, time.Hour
+
+	msg, ok := generateKeyCmd()().(keyGeneratedMsg)
+	if !ok || msg.sessionID == "" || msg.sessionErr != nil {
+		t.Fatalf("Expected the key to be saved in a session, got %+v", msg)
+	}
+	model := InitialModel(nil, "", "", false)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Update(msg)
+	if !strings.Contains(model.View(), "--resume "+msg.sessionID) {
+		t.Error("Expected the session ID to be shown with the public key")
+	}
+
+	model.Update(fileDecryptedMsg{filename: filepath.Join(dir, "missing.txt")})
+	if _, _, err := sessions.Open(msg.sessionID); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Expected the session to end after decryption, got %v", err)
+	}
+}
+
+func TestSessionKeptOnQuit(t *testing.T) {
+	dir := t.TempDir()
+	sessions := session.New(dir, filepath.Join(dir, session.KeyFileName))
+	previous := cli.Default
+	t.Cleanup(func() { cli.Default = previous })
+	cli.Default.Sessions, cli.Default.SessionTTL = sessions, time.Hour
+
+	receiving := func() (*Model, string) {
+		t.Helper()
+		msg, ok := generateKeyCmd()().(keyGeneratedMsg)
+		if !ok || msg.sessionID == "" {
+			t.Fatalf("Expected the key to be saved in a session, got %+v", msg)
+		}
+		model := InitialModel(nil, "", "", false)
+		model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+		model.Update(msg)
+		return model, msg.sessionID
+	}
+
+	// Quitting keeps the session, so the receive can be resumed
+	for _, quit := range []tea.KeyMsg{{Type: tea.KeyEsc}, {Type: tea.KeyCtrlC}} {
+		model, id := receiving()
+		model.Update(quit)
+		if _, _, err := sessions.Open(id); err != nil {
+			t.Errorf("Expected %s to keep the session, got %v", quit, err)
+		}
+	}
+
+	// The forget key quits and deletes it
+	model, id := receiving()
+	if !strings.Contains(model.View(), "delete session") {
+		t.Error("Expected the forget key in the help bar")
+	}
+	if _, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlX}); cmd == nil {
+		t.Error("Expected the forget key to quit")
+	}
+	if _, _, err := sessions.Open(id); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Expected the forget key to delete the session, got %v", err)
+	}
+}
+
+func TestInboxKeepsReceiving(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir := t.TempDir()
+	sessions := session.New(dir, filepath.Join(dir, session.KeyFileName))
+	previous := cli.Default
+	t.Cleanup(func() { cli.Default = previous })
+	cli.Default.Sessions, cli.Default.SessionTTL = sessions, time.Hour
+
+	privKey, pubKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, strangerKey, err := crypto.GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privKeyPEM, _ := crypto.ExportRSAPrivateKeyAsPEM(privKey)
+	created, err := sessions.Create(privKey, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := InitialModel(privKeyPEM, "", "", false).Resume(created.ID).Inbox()
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Init()
+
+	// One payload for the key, then one for another key
+	for _, payload := range []string{encryptForTest(t, []byte("first"), pubKey), encryptForTest(t, []byte("second"), strangerKey)} {
+		model.input.SetValue(payload)
+		model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if model.step != StepDecrypting {
+			t.Fatalf("Expected StepDecrypting, got %v", model.step)
+		}
+		model.Update(decryptAndSaveCmd(model.payload, model.privateKey, nil, nil)())
+		if model.step != StepAwaitingPayload || model.input.Value() != "" {
+			t.Fatalf("Expected to wait for the next payload, got step %v", model.step)
+		}
+	}
+
+	if len(model.received) != 2 || model.received[0].err != nil || model.received[1].err == nil {
+		t.Fatalf("Expected one received and one failed payload, got %+v", model.received)
+	}
+	if model.received[0].metadata.Name != "test_decrypted.txt" {
+		t.Errorf("Expected the sender's file name, got %q", model.received[0].metadata.Name)
+	}
+	if !strings.Contains(model.View(), "Received 1 of 2 payloads") {
+		t.Error("Expected the received payloads to be listed")
+	}
+
+	// The session stays open for more until the inbox is left
+	if _, _, err := sessions.Open(created.ID); err != nil {
+		t.Fatalf("Expected the session to stay open, got %v", err)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if _, _, err := sessions.Open(created.ID); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Expected the session to end when leaving the inbox, got %v", err)
+	}
+}
+
+func TestKeyDirectoryPicksKey(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var keys []cli.KeyFile
+	var lastPublic *rsa.PublicKey
+	for _, name := range []string{"a.pem", "b.pem"} {
+		key, publicKey, err := crypto.GenerateRSAKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprint, _ := crypto.FingerprintPublicKey(publicKey)
+		keys = append(keys, cli.KeyFile{Path: name, Key: key, Fingerprint: fingerprint})
+		lastPublic = publicKey
+	}
+
+	model := InitialModel(nil, "", "", false).Keys(keys)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Init()
+	if model.step != StepAwaitingPayload {
+		t.Fatalf("Expected to wait for the payload, got step %v", model.step)
+	}
+	if view := model.View(); !strings.Contains(view, "2 private keys") || strings.Contains(view, "copy public key") {
+		t.Error("Expected the keys to be listed instead of a public key to copy")
+	}
+
+	// The payload is for the second key, which is picked once it is pasted
+	model.input.SetValue(encryptForTest(t, []byte("picked"), lastPublic))
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(decryptAndSaveCmd(model.payload, model.privateKey, model.keys, nil)())
+	if model.err != nil || model.step != StepSuccess {
+		t.Errorf("Expected the payload to be decrypted, got step %v (%v)", model.step, model.err)
+	}
+}
@@ -3,47 +3,68 @@ package receive
 import (
 	"AirBridge/internal/cli"
 	"AirBridge/internal/crypto"
-	"crypto/rsa"
+	"AirBridge/internal/progress"
+	"AirBridge/pkg"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 type keyGeneratedMsg struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
 	encodedKey string
+	// sessionID is the session the key was saved in to resume the receive, if any.
+	sessionID  string
+	sessionErr error
 }
 
-type fileDecryptedMsg struct{}
+type fileDecryptedMsg struct {
+	filename string
+	metadata pkg.FileMetadata
+}
 
 type errMsg struct{ error }
 
 func generateKeyCmd() tea.Cmd {
 	return func() tea.Msg {
-		privateKey, publicKey, err := crypto.GenerateRSAKeyPair()
+		privateKey, publicKey, err := cli.GenerateTemporaryKey()
 		if err != nil {
 			return errMsg{err}
 		}
 
-		encodedKey, err := crypto.EncodeRSAPublicKey(publicKey)
+		encodedKey, err := crypto.EncodePublicKey(publicKey)
 		if err != nil {
 			return errMsg{err}
 		}
 
+		// Without a session the receive still works, it just can't be resumed
+		sessionID, sessionErr := cli.StartSession(privateKey)
+
 		return keyGeneratedMsg{
 			privateKey: privateKey,
 			publicKey:  publicKey,
 			encodedKey: encodedKey,
+			sessionID:  sessionID,
+			sessionErr: sessionErr,
 		}
 	}
 }
 
-func decryptAndSaveCmd(payloadStr string, privateKey *rsa.PrivateKey) tea.Cmd {
+// decryptAndSaveCmd decrypts the payload with privateKey, or with the one of
+// keys it is for if there are any
+func decryptAndSaveCmd(payloadStr string, privateKey crypto.PrivateKey, keys []cli.KeyFile, onProgress progress.Func) tea.Cmd {
 	return func() tea.Msg {
-		_, err := cli.ProcessPayload(payloadStr, privateKey)
+		if len(keys) > 0 {
+			key, err := cli.SelectKey(payloadStr, keys)
+			if err != nil {
+				return errMsg{err}
+			}
+			privateKey = key.Key
+		}
+		filename, metadata, err := cli.DecryptPayload(payloadStr, privateKey, onProgress)
 		if err != nil {
 			return errMsg{err}
 		}
-		return fileDecryptedMsg{}
+		return fileDecryptedMsg{filename: filename, metadata: metadata}
 	}
 }
@@ -81,7 +81,7 @@ func TestDecryptAndSaveCmd(t *testing.T) {
 	payloadStr := encryptForTest(t, originalData, pubKey)
 
 	// 3. Run the command
-	cmd := decryptAndSaveCmd(payloadStr, privKey)
+	cmd := decryptAndSaveCmd(payloadStr, privKey, nil, nil)
 	msg := cmd()
 
 	// 4. Check result
@@ -111,7 +111,7 @@ func TestDecryptAndSaveCmd(t *testing.T) {
 
 func TestDecryptAndSaveCmd_InvalidPayload(t *testing.T) {
 	privKey, _, _ := crypto.GenerateRSAKeyPair()
-	cmd := decryptAndSaveCmd("invalid_base64", privKey)
+	cmd := decryptAndSaveCmd("invalid_base64", privKey, nil, nil)
 	msg := cmd()
 
 	if _, ok := msg.(errMsg); !ok {
@@ -168,7 +168,7 @@ func TestDecryptAndSaveCmd_PathTraversal(t *testing.T) {
 	payloadStr := base64.StdEncoding.EncodeToString(jsonPayload)
 
 	// 3. Run the command
-	cmd := decryptAndSaveCmd(payloadStr, privKey)
+	cmd := decryptAndSaveCmd(payloadStr, privKey, nil, nil)
 	msg := cmd()
 
 	// 4. Check result
@@ -1,14 +1,15 @@
 package receive
 
 import (
+	"AirBridge/internal/cli"
 	"AirBridge/internal/tui"
 	"fmt"
 	"os"
+	"time"
 
-	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
 func (m *Model) Init() tea.Cmd {
@@ -19,7 +20,7 @@ func (m *Model) Init() tea.Cmd {
 			m.statusText = "Decrypting..."
 			m.step = StepDecrypting // Force step update for consistency
 			return tea.Batch(
-				decryptAndSaveCmd(m.payload, m.privateKey),
+				m.decrypt(),
 				m.spinner.Tick,
 			)
 		}
@@ -44,11 +45,24 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.privateKey = msg.privateKey
 		m.publicKey = msg.publicKey
 		m.encodedKey = msg.encodedKey
+		m.sessionID = msg.sessionID
+		if msg.sessionErr != nil {
+			m.statusText = tui.WarningStyle.Render(fmt.Sprintf("⚠️ This receive can't be resumed: %v", msg.sessionErr))
+		}
 		m.nextStep()
 		return m, nil
 
 	case fileDecryptedMsg:
+		done := tui.TransferDoneMsg{
+			Direction: "received",
+			Name:      msg.filename,
+			Size:      fileSize(msg.filename),
+			Detail:    m.payloadPath,
+		}
 		m.statusText = "File saved successfully!"
+		if m.inbox {
+			m.statusText = tui.SuccessStyle.Render("Saved " + msg.filename)
+		}
 		// Handle file deletion if requested
 		if m.deleteFile && m.payloadPath != "" {
 			err := os.Remove(m.payloadPath)
@@ -58,7 +72,28 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.statusText += " (Payload deleted)"
 			}
 		}
+		if m.inbox {
+			// Back to waiting with the same key; the session ends when the inbox is left
+			m.received = append(m.received, inboxItem{path: msg.filename, metadata: msg.metadata, at: time.Now()})
+			m.payload, m.payloadPath = "", ""
+			m.input.Reset()
+		} else {
+			if err := cli.EndSession(m.sessionID); err != nil {
+				m.statusText += fmt.Sprintf(" (Failed to end session: %v)", err)
+			}
+			m.sessionID = ""
+		}
 		m.nextStep()
+		return m, tui.TransferDoneCmd(done)
+
+	case tui.ProgressMsg:
+		m.progress, cmd = m.progress.Update(msg)
+		return m, cmd
+
+	case tui.ClipboardClearMsg:
+		if text := tui.ExpireClipboard(); text != "" {
+			m.statusText = text
+		}
 		return m, nil
 
 	case errMsg:
@@ -66,8 +101,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.statusText = ""
 		switch m.step {
 		case StepDecrypting:
+			if m.inbox {
+				m.received = append(m.received, inboxItem{err: msg.error, at: time.Now()})
+				m.payloadPath = ""
+			}
 			m.payload = ""
-			m.textarea.Reset()
+			m.input.Reset()
 		case StepGeneratingKey:
 			m.privateKey = nil
 			m.publicKey = nil
@@ -77,59 +116,69 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.WindowSizeMsg:
-		m.Width = msg.Width
-		m.Height = msg.Height
-
-		headerH := lipgloss.Height(tui.Header())
-		footerH := lipgloss.Height(tui.Footer(m.err))
-		headerW := lipgloss.Width(tui.Header())
-		spacer := 0
-
-		availableHeight := m.Height - (headerH + footerH + spacer)
-		if availableHeight < 3 {
-			availableHeight = 3
-		}
-		m.AvailableHeight = availableHeight
-
-		availableWidth := headerW
-		m.AvailableWidth = availableWidth
+		m.Resize(msg.Width, msg.Height)
 		return m, nil
 
 	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyCtrlC, tea.KeyEsc:
+		if msg.Type == tea.KeyCtrlC {
+			m.leave()
 			return m, tea.Quit
 		}
+		// The file picker handles Esc itself
+		if key.Matches(msg, tui.Keys.Quit) && !m.input.Busy() {
+			m.leave()
+			return m, tui.ExitCmd(m.embedded)
+		}
+		if key.Matches(msg, tui.Keys.Forget) && m.sessionID != "" && m.step == StepAwaitingPayload && !m.input.Busy() {
+			m.forget()
+			return m, tui.ExitCmd(m.embedded)
+		}
 
 		switch m.step {
 		case StepGeneratingKey:
 			// Wait for key generation
 			return m, nil
 		case StepAwaitingPayload:
-			// Handle Copy Key
-			if msg.Type == tea.KeyCtrlK {
-				err := clipboard.WriteAll(m.encodedKey)
+			if m.input.Busy() {
+				m.input, cmd = m.input.Update(msg)
+				return m, cmd
+			}
+
+			// Handle Copy Key (there is none to copy with several keys)
+			if key.Matches(msg, tui.Keys.Copy) {
+				if m.encodedKey == "" {
+					return m, nil
+				}
+				clearCmd, err := tui.Copy(m.encodedKey, false)
 				if err != nil {
 					m.err = err
 				} else {
 					m.statusText = tui.SuccessStyle.Render("Public key copied to clipboard!")
 				}
-				return m, nil
+				return m, clearCmd
 			}
 
-			// Handle Textarea input
-			m.textarea, cmd = m.textarea.Update(msg)
-
-			if msg.Type == tea.KeyEnter {
-				m.payload = m.textarea.Value()
+			if key.Matches(msg, tui.Keys.Submit) {
+				if m.input.Kind() == cli.ContentEmpty {
+					m.err = tui.ErrEmptyInput
+					return m, nil
+				}
+				if m.input.Mismatch() {
+					m.err = fmt.Errorf("this looks like %s, not a payload", m.input.Kind())
+					return m, nil
+				}
+				m.err = nil
+				m.payload = m.input.Value()
 				m.statusText = "Decrypting..."
 				m.nextStep()
 				return m, tea.Batch(
-					decryptAndSaveCmd(m.payload, m.privateKey),
+					m.decrypt(),
 					m.spinner.Tick,
 				)
 			}
 
+			// Handle input (typing, pasting, Ctrl+V and Ctrl+O)
+			m.input, cmd = m.input.Update(msg)
 			return m, cmd
 		}
 	}
@@ -138,7 +187,19 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case StepGeneratingKey, StepDecrypting:
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
+	case StepAwaitingPayload:
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
 	}
 
 	return m, cmd
 }
+
+// fileSize returns the size of a saved file, or 0 if it cannot be read.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -3,58 +3,152 @@ package receive
 import (
 	"AirBridge/internal/strutil"
 	"AirBridge/internal/tui"
+	"fmt"
+	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
 )
 
 func (m *Model) View() string {
 	switch m.step {
 	case StepUndefined:
-		return tui.View(m.err, "")
+		return tui.View(m.Window, m.err, m.helpKeys(), "")
 	case StepGeneratingKey:
 		input := m.spinner.View() + " Generating RSA Key Pair..."
 		view := tui.MainStyle(m.Window).Render(input)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	case StepAwaitingPayload:
 		encodedText := strutil.TruncateMiddle(m.encodedKey, 15)
 		// Public Key Section
-		keyView := lipgloss.NewStyle().
+		keyStyle := lipgloss.NewStyle().
 			Padding(1).
-			Border(lipgloss.RoundedBorder()).
-			Render(encodedText)
+			Border(lipgloss.RoundedBorder())
+		if m.Compact() {
+			keyStyle = keyStyle.Padding(0, 1)
+		}
+		keyView := keyStyle.Render(encodedText)
+		keyTitle := "Your Public Key:"
+		if len(m.keys) > 1 {
+			keyTitle = "Your Keys:"
+			keyView = keyStyle.Render(fmt.Sprintf("%d private keys, each payload is decrypted with the one it is for", len(m.keys)))
+		}
+		if m.sessionID != "" {
+			keyView = lipgloss.JoinVertical(lipgloss.Left, keyView,
+				tui.SubtleStyle.Render("Closed too early? Resume with 'airbridge receive --resume "+m.sessionID+"'"))
+		}
 
-		keyHelp := tui.SubtleStyle.Render("Press 'Ctrl+K' to copy public key")
+		title := "Incoming Payload:"
+		var inbox string
+		if m.inbox {
+			title = "Incoming Payload (inbox: stays open for more until you quit):"
+			if len(m.received) > 0 {
+				inbox = m.inboxView()
+			}
+		}
 
 		// Payload Input Section
-		m.textarea.SetWidth(m.AvailableWidth - 4)
-		m.textarea.SetHeight(10)
-		input := m.textarea.View()
+		// The texts, the key box, the hint, the status and the inbox take the rest of the height
+		inputHeight := m.AvailableHeight - 6 - lipgloss.Height(keyView)
+		if inbox != "" {
+			inputHeight -= lipgloss.Height(inbox) + 1
+		}
+		if inputHeight > 10 {
+			inputHeight = 10
+		}
+		m.input.SetSize(m.AvailableWidth, inputHeight)
+		input := m.input.View()
 
-		inputHelp := tui.SubtleStyle.Render("Paste payload above and press 'Enter' to decrypt and save")
-
-		view := lipgloss.JoinVertical(lipgloss.Left,
-			"Your Public Key:",
+		rows := []string{
+			keyTitle,
 			keyView,
-			keyHelp,
 			"",
-			"Incoming Payload:",
+			title,
 			input,
-			inputHelp,
 			"",
 			m.statusText,
-		)
+		}
+		if inbox != "" {
+			rows = append(rows, "", inbox)
+		}
+		view := lipgloss.JoinVertical(lipgloss.Left, rows...)
 
 		view = tui.MainStyle(m.Window).Render(view)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	case StepDecrypting:
-		input := m.spinner.View() + " Decrypting and Saving..."
+		input := lipgloss.JoinVertical(lipgloss.Left,
+			m.spinner.View()+" Decrypting and Saving...",
+			"",
+			m.progress.View(m.AvailableWidth),
+		)
 		view := tui.MainStyle(m.Window).Render(input)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	case StepSuccess:
 		text := tui.SuccessStyle.Render("File received and saved successfully!")
 		view := tui.MainStyle(m.Window).Render(text)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	default:
-		return tui.View(m.err, "Unknown Step")
+		return tui.View(m.Window, m.err, m.helpKeys(), "Unknown Step")
+	}
+}
+
+// inboxView lists the payloads received in inbox mode, the latest last. Only the
+// last few are shown, to leave room for the input.
+func (m *Model) inboxView() string {
+	const shown = 5
+	items := m.received
+	if len(items) > shown {
+		items = items[len(items)-shown:]
+	}
+
+	saved := 0
+	for _, item := range m.received {
+		if item.err == nil {
+			saved++
+		}
+	}
+	lines := []string{fmt.Sprintf("Received %d of %d payloads:", saved, len(m.received))}
+	for _, item := range items {
+		at := tui.SubtleStyle.Render(item.at.Format("15:04"))
+		if item.err != nil {
+			lines = append(lines, fmt.Sprintf("%s %s %s", tui.ErrorStyle.Render("✗"), at, strutil.TruncateMiddle(item.err.Error(), max(m.AvailableWidth/2-8, 10))))
+			continue
+		}
+		hash := item.metadata.Hash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s  %s  %s",
+			tui.SuccessStyle.Render("✓"),
+			at,
+			item.path,
+			humanize.Bytes(uint64(item.metadata.Size)),
+			tui.SubtleStyle.Render("sha256:"+hash),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// helpKeys returns the bindings shown in the help bar for the current step.
+func (m *Model) helpKeys() []key.Binding {
+	exit := tui.ExitKey(m.embedded)
+	if m.step != StepAwaitingPayload {
+		return []key.Binding{exit}
+	}
+	if m.input.Busy() {
+		return m.input.HelpKeys()
+	}
+	submitKey := tui.Keys.Submit
+	submitKey.SetHelp(submitKey.Help().Key, "decrypt")
+	keys := []key.Binding{submitKey}
+	if m.encodedKey != "" {
+		copyKey := tui.Keys.Copy
+		copyKey.SetHelp(copyKey.Help().Key, "copy public key")
+		keys = append(keys, copyKey)
+	}
+	if m.sessionID != "" {
+		keys = append(keys, tui.Keys.Forget)
 	}
+	return append(keys, append(m.input.HelpKeys(), exit)...)
 }
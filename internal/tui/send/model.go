@@ -1,15 +1,19 @@
 package send
 
 import (
+	"AirBridge/internal/cli"
+	"AirBridge/internal/crypto"
+	"AirBridge/internal/progress"
+	"AirBridge/internal/strutil"
 	"AirBridge/internal/tui"
 	"AirBridge/pkg"
-	"crypto/rsa"
 	"os"
 
 	"github.com/charmbracelet/bubbles/filepicker"
 	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textarea"
-	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 type Step int
@@ -29,19 +33,32 @@ type Model struct {
 
 	filepicker filepicker.Model
 	spinner    spinner.Model
-	textarea   textarea.Model
+	progress   tui.Progress
+	input      tui.PasteInput
 
 	selectedFile string
 	file         *os.File
 	fileMetadata pkg.FileMetadata
 
 	rawPublicKey string
-	publicKey    *rsa.PublicKey
+	publicKey    crypto.PublicKey
 
 	filePayload string
 
+	// viewer shows the full payload when viewing is set.
+	viewer  viewport.Model
+	viewing bool
+
+	// pathInput asks for the destination when saving is set.
+	pathInput textinput.Model
+	saving    bool
+
+	// printPayload makes the payload available through Output once the program exits.
+	printPayload bool
+
 	statusText     string
 	outputFilePath string
+	embedded       bool
 	err            error
 }
 
@@ -55,19 +72,23 @@ func InitialModel(initialFile string, initialPubKey string, outputFilePath strin
 
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	s.Style = tui.SpinnerStyle
 
-	ta := textarea.New()
-	ta.Placeholder = "Paste Base64 encoded public key here ..."
-	ta.ShowLineNumbers = false
-	ta.Focus()
+	input := tui.NewPasteInput("Paste Base64 encoded public key here ...", cli.ContentPublicKey, ".pem", ".pub", ".key", ".txt")
+
+	ti := textinput.New()
+	ti.Prompt = "Save to: "
+	ti.Placeholder = "payload.abp"
 
 	return &Model{
 		Window:         window,
 		step:           StepUndefined,
 		spinner:        s,
+		progress:       tui.NewProgress(),
 		filepicker:     fp,
-		textarea:       ta,
+		input:          input,
+		viewer:         viewport.New(0, 0),
+		pathInput:      ti,
 		selectedFile:   initialFile,
 		rawPublicKey:   initialPubKey,
 		outputFilePath: outputFilePath,
@@ -90,6 +111,69 @@ func (m *Model) nextStep() {
 	}
 }
 
+// Embed marks the model as running inside another model, so leaving it
+// returns to the parent instead of quitting the program.
+func (m *Model) Embed() *Model {
+	m.embedded = true
+	return m
+}
+
+// Output returns the payload if the user asked to print it on exit.
+func (m *Model) Output() string {
+	if !m.printPayload {
+		return ""
+	}
+	return m.filePayload
+}
+
+// openViewer shows the full payload wrapped to the terminal width.
+func (m *Model) openViewer() {
+	m.viewing = true
+	m.resizeViewer()
+	m.viewer.GotoTop()
+}
+
+func (m *Model) resizeViewer() {
+	width := m.Width
+	if width < 1 {
+		width = 80
+	}
+	height := m.Height - 2 // header and help lines
+	if height < 1 {
+		height = 1
+	}
+	m.viewer.Width = width
+	m.viewer.Height = height
+	m.viewer.SetContent(strutil.HardWrap(m.filePayload, width))
+}
+
+// startSaving asks for the path the payload should be written to.
+func (m *Model) startSaving() tea.Cmd {
+	m.saving = true
+	path := m.outputFilePath
+	if path == "" {
+		path = "payload.abp"
+	}
+	m.pathInput.SetValue(path)
+	m.pathInput.CursorEnd()
+	return m.pathInput.Focus()
+}
+
+// extractMetadata starts hashing the opened file.
+func (m *Model) extractMetadata() tea.Cmd {
+	return m.progress.Run(func(onProgress progress.Func) tea.Cmd {
+		return extractMetadataCmd(m.file, onProgress)
+	})
+}
+
+// processPublicKey starts encrypting the file for the entered public key.
+func (m *Model) processPublicKey() tea.Cmd {
+	rawPublicKey, file, metadata := m.rawPublicKey, m.file, m.fileMetadata
+	return m.progress.Run(func(onProgress progress.Func) tea.Cmd {
+		return processPublicKeyCmd(rawPublicKey, file, metadata, onProgress)
+	})
+}
+
 func (m *Model) resetError() {
 	m.err = nil
 }
@@ -1,7 +1,13 @@
 package send
 
 import (
+	"AirBridge/internal/tui"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 func TestInitialModel(t *testing.T) {
@@ -26,3 +32,131 @@ func TestInitialModel(t *testing.T) {
 		t.Errorf("Expected rawPublicKey %q, got %q", initialKey, model2.rawPublicKey)
 	}
 }
+
+func readyModel(payload string) *Model {
+	m := InitialModel("", "", "")
+	m.filePayload = payload
+	m.step = StepReadyToSend
+	m.Update(tea.WindowSizeMsg{Width: 20, Height: 10})
+	return m
+}
+
+func TestPayloadViewer(t *testing.T) {
+	payload := strings.Repeat("A", 100)
+	m := readyModel(payload)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v")})
+	if !m.viewing {
+		t.Fatal("Expected viewer to be open")
+	}
+	if m.viewer.TotalLineCount() != 5 {
+		t.Errorf("Expected payload wrapped to 5 lines, got %d", m.viewer.TotalLineCount())
+	}
+
+	// Esc closes the viewer without leaving the flow
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.viewing {
+		t.Error("Expected viewer to be closed")
+	}
+	if cmd != nil {
+		t.Error("Expected no command when closing the viewer")
+	}
+}
+
+func TestSavePayload(t *testing.T) {
+	m := readyModel("payload-content")
+	path := filepath.Join(t.TempDir(), "out.abp")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if !m.saving {
+		t.Fatal("Expected save prompt to be open")
+	}
+	m.pathInput.SetValue(path)
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if m.saving {
+		t.Error("Expected save prompt to be closed")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read saved payload: %v", err)
+	}
+	if string(content) != "payload-content" {
+		t.Errorf("Expected payload-content, got %q", content)
+	}
+
+	// An existing file is never overwritten
+	m.filePayload = "other-content"
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	m.pathInput.SetValue(path)
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.err == nil {
+		t.Error("Expected an error when saving over an existing file")
+	}
+	if content, _ := os.ReadFile(path); string(content) != "payload-content" {
+		t.Errorf("Expected the existing file to be kept, got %q", content)
+	}
+}
+
+func TestOutputFileOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.abp")
+	if err := os.WriteFile(path, []byte("old-content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// -o replaces the file with a warning, like headless and plain mode
+	m := InitialModel("", "", path)
+	_, cmd := m.Update(smallFilePayloadMsg{payload: "payload-content"})
+	if content, _ := os.ReadFile(path); string(content) != "payload-content" {
+		t.Errorf("Expected the payload to replace the file, got %q", content)
+	}
+	if !strings.Contains(m.statusText, "Overwriting") {
+		t.Errorf("Expected a warning about the overwritten file, got %q", m.statusText)
+	}
+	if _, ok := cmd().(tui.TransferDoneMsg); !ok {
+		t.Error("Expected the transfer to be recorded once saved")
+	}
+
+	// A payload that couldn't be saved isn't recorded as sent
+	m = InitialModel("", "", filepath.Join(dir, "missing", "out.abp"))
+	if _, cmd := m.Update(smallFilePayloadMsg{payload: "payload-content"}); cmd != nil || m.err == nil {
+		t.Errorf("Expected the failed save to be reported without recording the transfer, got %v", m.err)
+	}
+}
+
+func TestPrintPayloadOnExit(t *testing.T) {
+	m := readyModel("payload-content")
+	if m.Output() != "" {
+		t.Error("Expected no output before printing was requested")
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	if m.Output() != "payload-content" {
+		t.Errorf("Expected payload as output, got %q", m.Output())
+	}
+}
+
+func TestRemappedSubmitKeepsEnterForNewlines(t *testing.T) {
+	defaultKeys := tui.Keys
+	t.Cleanup(func() { tui.Keys = defaultKeys })
+	if err := tui.Keys.Apply(map[string][]string{"submit": {"ctrl+d"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := InitialModel("", "", "")
+	m.step = StepAwaitingPublicKey
+	m.input.SetValue("line1")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.rawPublicKey != "" {
+		t.Fatal("Expected enter not to submit")
+	}
+	if m.input.Value() != "line1\n" {
+		t.Errorf("Expected enter to add a newline, got %q", m.input.Value())
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+	if m.rawPublicKey == "" {
+		t.Error("Expected ctrl+d to submit")
+	}
+}
@@ -3,20 +3,45 @@ package send
 import (
 	"AirBridge/internal/cli"
 	"AirBridge/internal/crypto"
+	"AirBridge/internal/progress"
 	"AirBridge/pkg"
-	"crypto/rsa"
+	"errors"
 	"fmt"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-func getMetadata(file *os.File) (pkg.FileMetadata, error) {
-	return cli.GetFileMetadata(file)
+func getMetadata(file *os.File, onProgress progress.Func) (pkg.FileMetadata, error) {
+	return cli.GetFileMetadataWithProgress(file, onProgress)
 }
 
-func encryptFile(file *os.File, metadata pkg.FileMetadata, publicKey *rsa.PublicKey) (string, error) {
-	return cli.EncryptFile(file, metadata, publicKey)
+func encryptFile(file *os.File, metadata pkg.FileMetadata, publicKeys []crypto.PublicKey, onProgress progress.Func) (string, error) {
+	return cli.EncryptFileForKeys(file, metadata, publicKeys, onProgress)
+}
+
+// savePayload writes the payload to the path typed at the save prompt. Existing
+// files are never overwritten there; -o is saved like the other modes do, see
+// cli.SavePayload.
+func savePayload(path string, payload string) error {
+	if path == "" {
+		return fmt.Errorf("empty output path")
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, choose another path", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save payload: %w", err)
+	}
+	if _, err := file.WriteString(payload); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save payload: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to save payload: %w", err)
+	}
+	return nil
 }
 
 // message types for async workflow (split steps)
@@ -41,12 +66,12 @@ func openFileCmd(path string) tea.Cmd {
 }
 
 // extractMetadataCmd extracts metadata asynchronously using an already opened file
-func extractMetadataCmd(file *os.File) tea.Cmd {
+func extractMetadataCmd(file *os.File, onProgress progress.Func) tea.Cmd {
 	return func() tea.Msg {
 		if file == nil {
 			return errMsg{fmt.Errorf("nil file")}
 		}
-		metadata, err := getMetadata(file)
+		metadata, err := getMetadata(file, onProgress)
 		if err != nil {
 			return errMsg{err}
 		}
@@ -54,14 +79,14 @@ func extractMetadataCmd(file *os.File) tea.Cmd {
 	}
 }
 
-func processPublicKeyCmd(rawPublicKey string, file *os.File, metadata pkg.FileMetadata) tea.Cmd {
+func processPublicKeyCmd(rawPublicKey string, file *os.File, metadata pkg.FileMetadata, onProgress progress.Func) tea.Cmd {
 	return func() tea.Msg {
-		pubKey, err := crypto.DecodeRSAPublicKey(rawPublicKey)
+		pubKeys, err := cli.DecodeRecipients(rawPublicKey)
 		if err != nil {
 			return errMsg{err}
 		}
 
-		encryptedPayload, err := encryptFile(file, metadata, pubKey)
+		encryptedPayload, err := encryptFile(file, metadata, pubKeys, onProgress)
 		if err != nil {
 			return errMsg{err}
 		}
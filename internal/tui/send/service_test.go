@@ -25,7 +25,7 @@ func TestGetMetadata(t *testing.T) {
 		t.Fatalf("Failed to seek temp file: %v", err)
 	}
 
-	metadata, err := getMetadata(tmpFile)
+	metadata, err := getMetadata(tmpFile, nil)
 	if err != nil {
 		t.Fatalf("getMetadata failed: %v", err)
 	}
@@ -72,7 +72,7 @@ func TestEncryptFile(t *testing.T) {
 	}
 
 	// 3. Encrypt the file
-	payloadStr, err := encryptFile(tmpFile, metadata, pubKey)
+	payloadStr, err := encryptFile(tmpFile, metadata, []crypto.PublicKey{pubKey}, nil)
 	if err != nil {
 		t.Fatalf("encryptFile failed: %v", err)
 	}
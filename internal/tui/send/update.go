@@ -1,15 +1,14 @@
 package send
 
 import (
+	"AirBridge/internal/cli"
 	"AirBridge/internal/tui"
 	"AirBridge/pkg"
 	"fmt"
-	"os"
 
-	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
 func (m *Model) Init() tea.Cmd {
@@ -34,7 +33,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.file = msg.file
 		m.statusText = "Extracting metadata"
 		return m, tea.Batch(
-			extractMetadataCmd(m.file),
+			m.extractMetadata(),
 			m.spinner.Tick,
 		)
 
@@ -46,7 +45,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.step == StepReadyingPublicKey {
 			m.statusText = "Processing public key"
 			return m, tea.Batch(
-				processPublicKeyCmd(m.rawPublicKey, m.file, m.fileMetadata),
+				m.processPublicKey(),
 				m.spinner.Tick,
 			)
 		}
@@ -58,16 +57,35 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.err = nil
 
 		if m.outputFilePath != "" {
-			err := os.WriteFile(m.outputFilePath, []byte(m.filePayload), 0644)
+			warning, err := cli.SavePayload(m.outputFilePath, m.filePayload)
 			if err != nil {
-				m.err = fmt.Errorf("failed to save payload: %w", err)
-			} else {
-				// We can stay on the same step or move to ready
-				m.statusText = tui.SuccessStyle.Render(fmt.Sprintf("Payload saved to %s", m.outputFilePath))
+				m.err = err
+				m.nextStep()
+				return m, nil
+			}
+			// We can stay on the same step or move to ready
+			m.statusText = tui.SuccessStyle.Render(fmt.Sprintf("Payload saved to %s", m.outputFilePath))
+			if warning != "" {
+				m.statusText += "\n" + tui.WarningStyle.Render(warning)
 			}
 		}
 
 		m.nextStep()
+		return m, tui.TransferDoneCmd(tui.TransferDoneMsg{
+			Direction: "sent",
+			Name:      m.fileMetadata.Name,
+			Size:      m.fileMetadata.Size,
+			Detail:    m.outputFilePath,
+		})
+
+	case tui.ProgressMsg:
+		m.progress, cmd = m.progress.Update(msg)
+		return m, cmd
+
+	case tui.ClipboardClearMsg:
+		if text := tui.ExpireClipboard(); text != "" {
+			m.statusText = text
+		}
 		return m, nil
 
 	case errMsg:
@@ -87,29 +105,19 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.WindowSizeMsg:
-		m.Width = msg.Width
-		m.Height = msg.Height
-
-		headerH := lipgloss.Height(tui.Header())
-		footerH := lipgloss.Height(tui.Footer(m.err))
-		headerW := lipgloss.Width(tui.Header())
-		spacer := 0
-
-		availableHeight := m.Height - (headerH + footerH + spacer)
-		if availableHeight < 3 {
-			availableHeight = 3
+		m.Resize(msg.Width, msg.Height)
+		if m.viewing {
+			m.resizeViewer()
 		}
-		m.AvailableHeight = availableHeight
-
-		availableWidth := headerW
-		m.AvailableWidth = availableWidth
 		return m, nil
 
 	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyCtrlC, tea.KeyEsc:
+		if msg.Type == tea.KeyCtrlC {
 			return m, tea.Quit
-		default:
+		}
+		// The payload viewer, save prompt and file picker handle Esc themselves
+		if key.Matches(msg, tui.Keys.Quit) && !m.viewing && !m.saving && !m.input.Busy() {
+			return m, tui.ExitCmd(m.embedded)
 		}
 	}
 
@@ -131,19 +139,27 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.resetError()
 		return m, cmd
 	case StepAwaitingPublicKey:
-		m.textarea, cmd = m.textarea.Update(msg)
-		if msg, ok := msg.(tea.KeyMsg); ok && msg.Type == tea.KeyEnter {
-			rawPublicKey := m.textarea.Value()
+		if msg, ok := msg.(tea.KeyMsg); ok && key.Matches(msg, tui.Keys.Submit) && !m.input.Busy() {
+			if m.input.Kind() == cli.ContentEmpty {
+				m.err = tui.ErrEmptyInput
+				return m, nil
+			}
+			if m.input.Mismatch() {
+				m.err = fmt.Errorf("this looks like %s, not a public key", m.input.Kind())
+				return m, nil
+			}
+			rawPublicKey := m.input.Value()
 			m.rawPublicKey = rawPublicKey
-			m.textarea.Reset()
+			m.input.Reset()
 			m.statusText = "Processing public key"
 			m.resetError()
 			m.nextStep()
 			return m, tea.Batch(
-				processPublicKeyCmd(m.rawPublicKey, m.file, m.fileMetadata),
+				m.processPublicKey(),
 				m.spinner.Tick,
 			)
 		}
+		m.input, cmd = m.input.Update(msg)
 		return m, cmd
 	case StepReadyingPublicKey:
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -151,19 +167,78 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case StepReadyToSend:
-		m.resetError()
-		if msg, ok := msg.(tea.KeyMsg); ok && msg.Type == tea.KeyCtrlK {
-			err := clipboard.WriteAll(m.filePayload)
-			if err != nil {
-				// TODO: toggle payload visibility
-				m.err = err
+		return m.updateReadyToSend(msg)
+	default:
+		// No default action
+	}
+	return m, cmd
+}
+
+func (m *Model) updateReadyToSend(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if m.saving {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch {
+			case key.Matches(msg, tui.Keys.Cancel):
+				m.saving = false
+				m.pathInput.Blur()
+				return m, nil
+			case key.Matches(msg, tui.Keys.Submit):
+				path := m.pathInput.Value()
+				m.saving = false
+				m.pathInput.Blur()
+				if err := savePayload(path, m.filePayload); err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.resetError()
+				m.statusText = tui.SuccessStyle.Render(fmt.Sprintf("Payload saved to %s", path))
 				return m, nil
 			}
-			m.statusText = tui.SuccessStyle.Render("File payload copied to clipboard.")
+		}
+		m.pathInput, cmd = m.pathInput.Update(msg)
+		return m, cmd
+	}
+
+	msgKey, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if m.viewing {
+			m.viewer, cmd = m.viewer.Update(msg)
+		}
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(msgKey, tui.Keys.Copy):
+		m.resetError()
+		clearCmd, err := tui.Copy(m.filePayload, true)
+		if err != nil {
+			m.err = fmt.Errorf("%w (press '%s' to view the payload or '%s' to save it)",
+				err, tui.Keys.View.Help().Key, tui.Keys.Save.Help().Key)
 			return m, nil
 		}
-	default:
-		// No default action
+		m.statusText = tui.CopiedText("File payload")
+		return m, clearCmd
+	case key.Matches(msgKey, tui.Keys.Save):
+		m.resetError()
+		return m, m.startSaving()
+	case key.Matches(msgKey, tui.Keys.Print):
+		m.printPayload = true
+		return m, tea.Quit
+	case key.Matches(msgKey, tui.Keys.Close):
+		m.viewing = false
+		return m, nil
 	}
-	return m, cmd
+
+	if m.viewing {
+		m.viewer, cmd = m.viewer.Update(msg)
+		return m, cmd
+	}
+
+	if key.Matches(msgKey, tui.Keys.View) {
+		m.resetError()
+		m.openViewer()
+	}
+	return m, nil
 }
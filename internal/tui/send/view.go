@@ -3,47 +3,100 @@ package send
 import (
 	"AirBridge/internal/strutil"
 	"AirBridge/internal/tui"
+	"fmt"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
 )
 
 func (m *Model) View() string {
 	switch m.step {
 	case StepUndefined:
-		return tui.View(m.err, "")
+		return tui.View(m.Window, m.err, m.helpKeys(), "")
 	case StepAwaitingFile:
 		text := "Please select a file to send:"
 		m.filepicker.SetHeight(m.AvailableHeight - 3) // -3 for the text and spacing
 		input := m.filepicker.View()
 		view := lipgloss.JoinVertical(lipgloss.Left, text, "", input)
 		view = tui.MainStyle(m.Window).Render(view)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	case StepReadyingFile:
-		input := m.spinner.View() + m.statusText
+		input := lipgloss.JoinVertical(lipgloss.Left,
+			m.spinner.View()+m.statusText,
+			"",
+			m.progress.View(m.AvailableWidth),
+		)
 		view := tui.MainStyle(m.Window).Render(input)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	case StepAwaitingPublicKey:
-		text := "Please paste the recipient's public key and press 'Enter':"
-		m.textarea.SetWidth(m.AvailableWidth - 2) // -2 for the spacing
-		input := m.textarea.View()
+		text := fmt.Sprintf("Please paste the recipient's public key and press '%s':", tui.Keys.Submit.Help().Key)
+		m.input.SetSize(m.AvailableWidth, m.AvailableHeight-3) // -3 for the text, the spacing and the hint
+		input := m.input.View()
 		view := lipgloss.JoinVertical(lipgloss.Left, text, "", input)
 		view = tui.MainStyle(m.Window).Render(view)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	case StepReadyingPublicKey:
-		input := m.spinner.View() + m.statusText
+		input := lipgloss.JoinVertical(lipgloss.Left,
+			m.spinner.View()+m.statusText,
+			"",
+			m.progress.View(m.AvailableWidth),
+		)
 		view := tui.MainStyle(m.Window).Render(input)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	case StepReadyToSend:
+		if m.viewing {
+			return m.viewerView()
+		}
 		text := m.statusText
 		if text == "" {
-			text = "Press 'Ctrl+K' to copy payload to clipboard."
+			text = fmt.Sprintf("Press '%s' to copy payload to clipboard.", tui.Keys.Copy.Help().Key)
 		}
 		payloadText := strutil.TruncateMiddle(m.filePayload, 15)
 		input := text + "\n\nPayload: " + payloadText
+		if m.saving {
+			input += "\n\n" + m.pathInput.View()
+		}
 		view := tui.MainStyle(m.Window).Render(input)
-		return tui.View(m.err, view)
+		return tui.View(m.Window, m.err, m.helpKeys(), view)
 	default:
 		panic("unhandled default case")
 	}
 
 }
+
+// viewerView renders the payload full screen without borders or padding,
+// so it can be selected and copied with the mouse.
+func (m *Model) viewerView() string {
+	header := fmt.Sprintf("Payload (%d characters) %3.f%%", len(m.filePayload), m.viewer.ScrollPercent()*100)
+	help := tui.HelpView(m.Width, m.helpKeys()...)
+	if m.saving {
+		help = m.pathInput.View()
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, header, m.viewer.View(), help)
+}
+
+// helpKeys returns the bindings shown in the help bar for the current step.
+func (m *Model) helpKeys() []key.Binding {
+	exit := tui.ExitKey(m.embedded)
+	switch m.step {
+	case StepAwaitingFile:
+		return []key.Binding{tui.Keys.Up, tui.Keys.Down, tui.Keys.Select, exit}
+	case StepAwaitingPublicKey:
+		if m.input.Busy() {
+			return m.input.HelpKeys()
+		}
+		return append([]key.Binding{tui.Keys.Submit}, append(m.input.HelpKeys(), exit)...)
+	case StepReadyToSend:
+		if m.saving {
+			return []key.Binding{tui.Keys.Submit, tui.Keys.Cancel}
+		}
+		copyKey := tui.Keys.Copy
+		copyKey.SetHelp(copyKey.Help().Key, "copy payload")
+		if m.viewing {
+			return []key.Binding{tui.Keys.Scroll, copyKey, tui.Keys.Save, tui.Keys.Print, tui.Keys.Close}
+		}
+		return []key.Binding{copyKey, tui.Keys.View, tui.Keys.Save, tui.Keys.Print, exit}
+	default:
+		return []key.Binding{exit}
+	}
+}
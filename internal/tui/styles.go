@@ -1,56 +1,115 @@
 package tui
 
 import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// CompactHeight is the terminal height below which the banner collapses
+// into a one-line title.
+const CompactHeight = 30
+
 var (
-	BaseColor    = lipgloss.Color("#E0E0E0") // açık gri
-	AccentColor  = lipgloss.Color("#00C6FF") // mavi-turkuaz ton
-	WarningColor = lipgloss.Color("#FFAD33") // turuncu
-	ErrorColor   = lipgloss.Color("#FF4D4D") // kırmızı
-	SuccessColor = lipgloss.Color("#4DFF88") // yeşil
+	BaseColor    lipgloss.TerminalColor
+	AccentColor  lipgloss.TerminalColor
+	WarningColor lipgloss.TerminalColor
+	ErrorColor   lipgloss.TerminalColor
+	SuccessColor lipgloss.TerminalColor
 )
 
-var FooterStyle = lipgloss.NewStyle().
-	Foreground(BaseColor).
-	Padding(0, 1).
-	Margin(1, 0, 1, 0).
-	Align(lipgloss.Left)
-
-//Border(lipgloss.RoundedBorder()).
-//BorderForeground(AccentColor)
-
-var TitleStyle = lipgloss.NewStyle().
-	Bold(true).
-	Foreground(AccentColor).
-	// Background(lipgloss.Color("#1A1A1A")).
-	Padding(0, 2).
-	Margin(1, 0, 1, 0).
-	Align(lipgloss.Center).
-	Border(lipgloss.RoundedBorder()).
-	BorderForeground(AccentColor)
-
 var (
+	FooterStyle  lipgloss.Style
+	TitleStyle   lipgloss.Style
+	SpinnerStyle lipgloss.Style
+
+	SubtitleStyle lipgloss.Style
+	InfoStyle     lipgloss.Style
+	SuccessStyle  lipgloss.Style
+	ErrorStyle    lipgloss.Style
+	WarningStyle  lipgloss.Style
+	SubtleStyle   lipgloss.Style
+
+	HelpStyles help.Styles
+)
+
+// ActiveTheme is the theme the styles were built from.
+var ActiveTheme Theme
+
+func init() {
+	ApplyTheme(DarkTheme)
+}
+
+// ApplyTheme rebuilds all styles from the given theme.
+func ApplyTheme(theme Theme) {
+	ActiveTheme = theme
+
+	BaseColor = theme.color(theme.Base)
+	AccentColor = theme.color(theme.Accent)
+	WarningColor = theme.color(theme.Warning)
+	ErrorColor = theme.color(theme.Error)
+	SuccessColor = theme.color(theme.Success)
+
+	FooterStyle = lipgloss.NewStyle().
+		Foreground(BaseColor).
+		Padding(0, 1).
+		Margin(1, 0, 1, 0).
+		Align(lipgloss.Left)
+
+	//Border(lipgloss.RoundedBorder()).
+	//BorderForeground(AccentColor)
+
+	TitleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(AccentColor).
+		// Background(lipgloss.Color("#1A1A1A")).
+		Padding(0, 2).
+		Margin(1, 0, 1, 0).
+		Align(lipgloss.Center).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(AccentColor)
+
+	SpinnerStyle = lipgloss.NewStyle().
+		Foreground(theme.color(theme.Spinner))
+
 	SubtitleStyle = lipgloss.NewStyle().
-			Foreground(BaseColor).
-			Italic(true)
+		Foreground(BaseColor).
+		Italic(true)
 
 	InfoStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#B0B0B0"))
+		Foreground(theme.color(theme.Info))
 
 	SuccessStyle = lipgloss.NewStyle().
-			Foreground(SuccessColor)
+		Foreground(SuccessColor)
 
 	ErrorStyle = lipgloss.NewStyle().
-			Foreground(ErrorColor)
+		Foreground(ErrorColor).
+		// Without colors errors still have to stand out
+		Bold(theme.Monochrome())
 
 	WarningStyle = lipgloss.NewStyle().
-			Foreground(WarningColor)
+		Foreground(WarningColor)
 
 	SubtleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241"))
-)
+		Foreground(theme.color(theme.Subtle)).
+		Faint(theme.Monochrome())
+
+	helpKey := lipgloss.NewStyle().Foreground(BaseColor).Bold(true)
+	HelpStyles = help.Styles{
+		Ellipsis:       SubtleStyle,
+		ShortKey:       helpKey,
+		ShortDesc:      SubtleStyle,
+		ShortSeparator: SubtleStyle,
+		FullKey:        helpKey,
+		FullDesc:       SubtleStyle,
+		FullSeparator:  SubtleStyle,
+	}
+}
+
+// AccentStyle highlights the selected item in lists.
+func AccentStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Bold(true).Foreground(AccentColor)
+}
 
 func asciiTitle() string {
 	return `
@@ -80,15 +139,36 @@ func AirBridgeBanner() string {
 	return box
 }
 
+// DescribeError turns an error into the message shown on a screen. It shows
+// the error as is unless the caller sets one that knows what to do about it.
+var DescribeError = func(err error) string { return err.Error() }
+
 func errorView(err error) string {
 	if err != nil {
-		errMsg := "⚠️  " + err.Error()
+		errMsg := "⚠️  " + DescribeError(err)
 		return ErrorStyle.Render(errMsg)
 	}
 	return ""
 }
 
-func Header() string {
+// CompactTitle is the one-line header used when the terminal is too small for the banner.
+func CompactTitle() string {
+	title := lipgloss.NewStyle().
+		Foreground(AccentColor).
+		Bold(true).
+		Render("AirBridge")
+	sub := SubtleStyle.Render(" · Secure, Simple, and Fast File Transfer")
+	return lipgloss.NewStyle().
+		Padding(0, 1).
+		MarginBottom(1).
+		Render(title + sub)
+}
+
+// Header returns the banner, or the compact title on small terminals.
+func Header(window Window) string {
+	if window.Compact() {
+		return CompactTitle()
+	}
 	return AirBridgeBanner()
 }
 
@@ -101,16 +181,28 @@ func MainStyle(window Window) lipgloss.Style {
 	return mainStyle
 }
 
-func Footer(err error) string {
-	// Todo: Change input to Model
-	quitText := "(esc to quit)"
-	view := lipgloss.JoinVertical(lipgloss.Left, errorView(err), quitText)
-	view = FooterStyle.Render(view)
-	return view
+// HelpView renders the given bindings as a single line that fits the width.
+func HelpView(width int, keys ...key.Binding) string {
+	h := help.New()
+	h.Width = width
+	h.Styles = HelpStyles
+	return h.ShortHelpView(keys)
+}
+
+// Footer renders the error line above the help bar for the given bindings.
+func Footer(window Window, err error, keys []key.Binding) string {
+	style := FooterStyle
+	if window.Compact() {
+		style = style.Margin(1, 0, 0, 0)
+	}
+	width := window.Width - style.GetHorizontalFrameSize()
+	view := lipgloss.JoinVertical(lipgloss.Left, errorView(err), HelpView(width, keys...))
+	return style.Render(view)
 }
 
-func View(err error, view string) string {
-	banner := Header()
-	footer := Footer(err)
-	return lipgloss.JoinVertical(lipgloss.Left, banner, view, footer)
+// View lays out the header, the screen content and the footer.
+func View(window Window, err error, keys []key.Binding, view string) string {
+	header := Header(window)
+	footer := Footer(window, err, keys)
+	return lipgloss.JoinVertical(lipgloss.Left, header, view, footer)
 }
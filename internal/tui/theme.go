@@ -0,0 +1,151 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme holds the colors the TUI is drawn with. Colors are hex values ("#00C6FF")
+// or ANSI numbers ("241"); an empty color leaves the terminal default.
+type Theme struct {
+	Name string `toml:"-"`
+	// Inherits names the theme that unset colors are taken from (user themes only).
+	Inherits string `toml:"inherits"`
+
+	Base    string `toml:"base"`
+	Accent  string `toml:"accent"`
+	Warning string `toml:"warning"`
+	Error   string `toml:"error"`
+	Success string `toml:"success"`
+	Info    string `toml:"info"`
+	Subtle  string `toml:"subtle"`
+	Spinner string `toml:"spinner"`
+}
+
+var (
+	DarkTheme = Theme{
+		Name:    "dark",
+		Base:    "#E0E0E0", // açık gri
+		Accent:  "#00C6FF", // mavi-turkuaz ton
+		Warning: "#FFAD33", // turuncu
+		Error:   "#FF4D4D", // kırmızı
+		Success: "#4DFF88", // yeşil
+		Info:    "#B0B0B0",
+		Subtle:  "241",
+		Spinner: "205",
+	}
+
+	LightTheme = Theme{
+		Name:    "light",
+		Base:    "#303030",
+		Accent:  "#005F87",
+		Warning: "#AF5F00",
+		Error:   "#D70000",
+		Success: "#007A33",
+		Info:    "#4E4E4E",
+		Subtle:  "#767676",
+		Spinner: "#AF005F",
+	}
+
+	HighContrastTheme = Theme{
+		Name:    "high-contrast",
+		Base:    "#FFFFFF",
+		Accent:  "#00FFFF",
+		Warning: "#FFFF00",
+		Error:   "#FF5F5F",
+		Success: "#00FF00",
+		Info:    "#FFFFFF",
+		Subtle:  "#D0D0D0",
+		Spinner: "#FFFFFF",
+	}
+
+	// MonochromeTheme draws everything in the terminal's default colors.
+	MonochromeTheme = Theme{Name: "monochrome"}
+)
+
+// BuiltinThemes lists the themes that ship with AirBridge.
+var BuiltinThemes = map[string]Theme{
+	DarkTheme.Name:         DarkTheme,
+	LightTheme.Name:        LightTheme,
+	HighContrastTheme.Name: HighContrastTheme,
+	MonochromeTheme.Name:   MonochromeTheme,
+}
+
+// Monochrome reports whether the theme has no colors at all.
+func (t Theme) Monochrome() bool {
+	return t.Base == "" && t.Accent == "" && t.Warning == "" && t.Error == "" &&
+		t.Success == "" && t.Info == "" && t.Subtle == "" && t.Spinner == ""
+}
+
+func (t Theme) color(c string) lipgloss.TerminalColor {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
+
+// inherit fills unset colors from parent.
+func (t Theme) inherit(parent Theme) Theme {
+	fill := func(c *string, p string) {
+		if *c == "" {
+			*c = p
+		}
+	}
+	fill(&t.Base, parent.Base)
+	fill(&t.Accent, parent.Accent)
+	fill(&t.Warning, parent.Warning)
+	fill(&t.Error, parent.Error)
+	fill(&t.Success, parent.Success)
+	fill(&t.Info, parent.Info)
+	fill(&t.Subtle, parent.Subtle)
+	fill(&t.Spinner, parent.Spinner)
+	return t
+}
+
+// LoadTheme returns the built-in theme called name, or reads <themesDir>/<name>.toml.
+func LoadTheme(name string, themesDir string) (Theme, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if theme, ok := BuiltinThemes[name]; ok {
+		return theme, nil
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return Theme{}, fmt.Errorf("invalid theme name %q", name)
+	}
+
+	path := filepath.Join(themesDir, name+".toml")
+	var theme Theme
+	if _, err := toml.DecodeFile(path, &theme); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Theme{}, fmt.Errorf("unknown theme %q (built-in themes: %s)", name, strings.Join(ThemeNames(), ", "))
+		}
+		return Theme{}, fmt.Errorf("could not read theme %s: %w", path, err)
+	}
+	theme.Name = name
+
+	parentName := theme.Inherits
+	if parentName == "" {
+		parentName = DarkTheme.Name
+	}
+	parent, ok := BuiltinThemes[parentName]
+	if !ok {
+		return Theme{}, fmt.Errorf("theme %q inherits unknown built-in theme %q", name, parentName)
+	}
+	return theme.inherit(parent), nil
+}
+
+// ThemeNames returns the names of the built-in themes.
+func ThemeNames() []string {
+	names := make([]string, 0, len(BuiltinThemes))
+	for name := range BuiltinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
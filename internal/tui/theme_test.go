@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestLoadBuiltinTheme(t *testing.T) {
+	theme, err := LoadTheme("Light", t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadTheme failed: %v", err)
+	}
+	if theme.Accent != LightTheme.Accent {
+		t.Errorf("Expected light accent %s, got %s", LightTheme.Accent, theme.Accent)
+	}
+}
+
+func TestLoadUserTheme(t *testing.T) {
+	dir := t.TempDir()
+	content := "inherits = \"light\"\naccent = \"#123456\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "corporate.toml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := LoadTheme("corporate", dir)
+	if err != nil {
+		t.Fatalf("LoadTheme failed: %v", err)
+	}
+	if theme.Accent != "#123456" {
+		t.Errorf("Expected accent from file, got %s", theme.Accent)
+	}
+	if theme.Error != LightTheme.Error {
+		t.Errorf("Expected error color inherited from light, got %s", theme.Error)
+	}
+}
+
+func TestLoadUnknownTheme(t *testing.T) {
+	if _, err := LoadTheme("neon", t.TempDir()); err == nil {
+		t.Error("Expected error for unknown theme")
+	}
+	if _, err := LoadTheme("../evil", t.TempDir()); err == nil {
+		t.Error("Expected error for theme name with path separators")
+	}
+}
+
+func TestApplyMonochromeTheme(t *testing.T) {
+	defer ApplyTheme(DarkTheme)
+
+	ApplyTheme(MonochromeTheme)
+	if _, ok := ErrorStyle.GetForeground().(lipgloss.NoColor); !ok {
+		t.Errorf("Expected no error color, got %v", ErrorStyle.GetForeground())
+	}
+	if !ErrorStyle.GetBold() {
+		t.Error("Expected errors to be bold without colors")
+	}
+
+	ApplyTheme(DarkTheme)
+	if ErrorStyle.GetForeground() != lipgloss.Color(DarkTheme.Error) {
+		t.Errorf("Expected dark error color, got %v", ErrorStyle.GetForeground())
+	}
+}
@@ -1,11 +1,46 @@
 package pkg
 
+// PayloadVersion is the newest version of the payload format, written for
+// payloads with several recipients. Payloads for one recipient are still written
+// as PayloadVersionSingle so older releases can read them.
+const PayloadVersion = 2
+
+// PayloadVersionSingle is the version of payloads with one recipient. Payloads
+// without a version are version 1 too.
+const PayloadVersionSingle = 1
+
+// KeyTypeX25519 marks payloads whose key is wrapped with X25519 for an Ed25519 key.
+const KeyTypeX25519 = "x25519"
+
 // SmallFilePayload represents a payload for a small file.
 type SmallFilePayload struct {
-	Key      string       `json:"key"`
+	Version int `json:"version,omitempty"`
+	// Recipient is the key ID of the public key the payload is encrypted for.
+	// It is empty for anonymous payloads.
+	Recipient string `json:"recipient,omitempty"`
+	// KeyType is how Key is wrapped: empty for RSA-OAEP, KeyTypeX25519 for Ed25519 recipients.
+	KeyType string `json:"key_type,omitempty"`
+	Key     string `json:"key,omitempty"`
+	// Keys holds the file key wrapped for each recipient of a version 2 payload,
+	// in place of Recipient, KeyType and Key.
+	Keys     []WrappedKey `json:"keys,omitempty"`
 	Data     string       `json:"data"`
 	Nonce    string       `json:"nonce"`
 	Metadata FileMetadata `json:"metadata"`
+	// Sender is the public key of a signed payload's sender, as base64 PEM, and
+	// Signature their base64 signature over the payload's JSON with Signature
+	// left empty. Both are empty for unsigned payloads.
+	Sender    string `json:"sender,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// WrappedKey is the file key of a payload wrapped for one recipient key.
+type WrappedKey struct {
+	// Recipient is the key ID of the recipient key, empty for anonymous payloads.
+	Recipient string `json:"recipient,omitempty"`
+	// KeyType is how Key is wrapped, as in SmallFilePayload.
+	KeyType string `json:"key_type,omitempty"`
+	Key     string `json:"key"`
 }
 
 // LargeFilePayload represents a payload for a large file chunk.
@@ -1,15 +1,28 @@
 package tests
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
 var binaryPath string
@@ -32,8 +45,22 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
+	// Keep the user's config file and AIRBRIDGE_* variables out of the tests
+	configHome, err := os.MkdirTemp("", "airbridge_config_*")
+	if err != nil {
+		fmt.Printf("Failed to create config dir: %v\n", err)
+		os.Exit(1)
+	}
+	_ = os.Setenv("XDG_CONFIG_HOME", configHome)
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, "AIRBRIDGE_") {
+			_ = os.Unsetenv(strings.SplitN(env, "=", 2)[0])
+		}
+	}
+
 	// Run tests
 	code := m.Run()
+	_ = os.RemoveAll(configHome)
 
 	// Cleanup binary
 	_ = os.Remove(binaryPath)
@@ -41,8 +68,14 @@ func TestMain(m *testing.M) {
 }
 
 func runCLI(dir string, args ...string) (string, error) {
+	return runCLIWithEnv(dir, nil, args...)
+}
+
+// runCLIWithEnv runs the binary with extra environment variables such as "AIRBRIDGE_OUTPUT_DIR=out".
+func runCLIWithEnv(dir string, env []string, args ...string) (string, error) {
 	cmd := exec.Command(binaryPath, args...)
 	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
 	output, err := cmd.CombinedOutput()
 	return string(output), err
 }
@@ -168,6 +201,1101 @@ func TestHeadlessErrorCases(t *testing.T) {
 	}
 }
 
+func TestHeadlessProgress(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if _, err := runCLI(tempDir, "keygen", "-o", "."); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "small.txt"), []byte("progress"), 0644); err != nil {
+		t.Fatalf("Failed to write small.txt: %v", err)
+	}
+
+	output, err := runCLI(tempDir, "send", "small.txt", "-k", "public.pem", "-o", "payload.abp", "-H")
+	if err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "Hashing") || !strings.Contains(output, "Encrypting") {
+		t.Errorf("Expected progress lines, got:\n%s", output)
+	}
+
+	output, err = runCLI(tempDir, "receive", "-k", "private.pem", "-i", "payload.abp", "-H", "--quiet")
+	if err != nil {
+		t.Fatalf("Receive failed: %v\nOutput: %s", err, output)
+	}
+	if strings.Contains(output, "Decrypting") {
+		t.Errorf("Expected no progress with --quiet, got:\n%s", output)
+	}
+}
+
+func TestConfigProfileAndEnv(t *testing.T) {
+	tempDir := t.TempDir()
+	env := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "config")}
+
+	if _, err := runCLI(tempDir, "keygen", "-o", "."); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "note.txt"), []byte("profiles"), 0644); err != nil {
+		t.Fatalf("Failed to write note.txt: %v", err)
+	}
+
+	settings := [][]string{
+		{"config", "set", "--profile", "work", "recipient", filepath.Join(tempDir, "public.pem")},
+		{"config", "set", "--profile", "work", "anonymous", "true"},
+		{"config", "set", "private_key", filepath.Join(tempDir, "private.pem")},
+		{"config", "set", "payload_encoding", "armor"},
+	}
+	for _, args := range settings {
+		if output, err := runCLIWithEnv(tempDir, env, args...); err != nil {
+			t.Fatalf("%v failed: %v\nOutput: %s", args, err, output)
+		}
+	}
+
+	// The profile supplies -k
+	output, err := runCLIWithEnv(tempDir, env, "send", "--profile", "work", "note.txt", "-o", "payload.abp", "-H", "-q")
+	if err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+	payload, err := os.ReadFile(filepath.Join(tempDir, "payload.abp"))
+	if err != nil {
+		t.Fatalf("Payload not written: %v", err)
+	}
+	if !strings.HasPrefix(string(payload), "-----BEGIN AIRBRIDGE PAYLOAD-----") {
+		t.Errorf("Expected an armored payload, got %q", payload)
+	}
+
+	// Environment variables beat the config file, flags beat both
+	receiveEnv := append(env, "AIRBRIDGE_OUTPUT_DIR=from-env")
+	output, err = runCLIWithEnv(tempDir, receiveEnv, "receive", "-i", "payload.abp", "-o", "from-flag", "-H", "-q")
+	if err != nil {
+		t.Fatalf("Receive failed: %v\nOutput: %s", err, output)
+	}
+	content, err := os.ReadFile(filepath.Join(tempDir, "from-flag", "note.txt"))
+	if err != nil {
+		t.Fatalf("Received file not found: %v\nOutput: %s", err, output)
+	}
+	if string(content) != "profiles" {
+		t.Errorf("Expected 'profiles', got %q", content)
+	}
+
+	output, err = runCLIWithEnv(tempDir, env, "config", "get", "--profile", "work", "anonymous")
+	if err != nil || strings.TrimSpace(output) != "true" {
+		t.Errorf("Expected anonymous = true in the work profile, got %q (%v)", output, err)
+	}
+	if _, err := runCLIWithEnv(tempDir, env, "config", "set", "conflict_policy", "sometimes"); err == nil {
+		t.Error("Expected an invalid value to be rejected")
+	}
+}
+
+func TestHeadlessJSONOutput(t *testing.T) {
+	tempDir := t.TempDir()
+
+	output, err := runCLI(tempDir, "keygen", "-o", ".", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("Keygen failed: %v\nOutput: %s", err, output)
+	}
+	var keygen struct {
+		OK     bool
+		Result struct{ Fingerprint string }
+	}
+	if err := json.Unmarshal([]byte(output), &keygen); err != nil {
+		t.Fatalf("Keygen output is not JSON: %v\n%s", err, output)
+	}
+	if !keygen.OK || !strings.HasPrefix(keygen.Result.Fingerprint, "SHA256:") {
+		t.Errorf("Unexpected keygen result: %s", output)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// JSON implies headless, progress stays on stderr
+	cmd := exec.Command(binaryPath, "send", "data.txt", "-k", "public.pem", "--output-format", "json")
+	cmd.Dir = tempDir
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	var send struct {
+		OK     bool
+		Result struct {
+			Size                 int64
+			SHA256               string
+			Payload              string
+			RecipientFingerprint string `json:"recipient_fingerprint"`
+		}
+	}
+	if err := json.Unmarshal(stdout, &send); err != nil {
+		t.Fatalf("Send output is not JSON: %v\n%s", err, stdout)
+	}
+	if send.Result.Size != 4 || send.Result.Payload != "payload.abp" || len(send.Result.SHA256) != 64 {
+		t.Errorf("Unexpected send result: %s", stdout)
+	}
+	if send.Result.RecipientFingerprint != keygen.Result.Fingerprint {
+		t.Errorf("Expected recipient fingerprint %s, got %s", keygen.Result.Fingerprint, send.Result.RecipientFingerprint)
+	}
+}
+
+func TestHeadlessExitCodes(t *testing.T) {
+	tempDir := t.TempDir()
+	if _, err := runCLI(tempDir, "keygen", "-o", "."); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, "other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(tempDir, "keygen", "-o", "other"); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("codes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "garbage.abp"), []byte("not a payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := runCLI(tempDir, "send", "data.txt", "-k", "public.pem", "-H", "-q"); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		code  int
+		class string
+	}{
+		{"Missing key", []string{"receive", "-i", "payload.abp", "-H"}, 2, "usage"},
+		{"Inbox without the TUI", []string{"receive", "-k", "private.pem", "--inbox", "-H"}, 2, "usage"},
+		{"Watch with a payload", []string{"receive", "--watch", ".", "-k", "private.pem", "-i", "payload.abp"}, 2, "usage"},
+		{"Missing file", []string{"send", "missing.txt", "-k", "public.pem", "-H"}, 3, "io"},
+		{"Bad key", []string{"send", "data.txt", "-k", "data.txt", "-H"}, 4, "key"},
+		{"Malformed payload", []string{"receive", "-k", "private.pem", "-i", "garbage.abp", "-H"}, 5, "payload"},
+		{"Wrong key", []string{"receive", "-k", "other/private.pem", "-i", "payload.abp", "-H"}, 6, "decryption"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, append(tt.args, "--output-format", "json")...)
+			cmd.Dir = tempDir
+			stdout, err := cmd.Output()
+
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("Expected a failing exit code, got %v", err)
+			}
+			if exitErr.ExitCode() != tt.code {
+				t.Errorf("Expected exit code %d, got %d", tt.code, exitErr.ExitCode())
+			}
+
+			var result struct {
+				OK    bool
+				Error struct{ Class string }
+			}
+			if err := json.Unmarshal(stdout, &result); err != nil {
+				t.Fatalf("Error output is not JSON: %v\n%s", err, stdout)
+			}
+			if result.Error.Class != tt.class {
+				t.Errorf("Expected class %s, got %s", tt.class, result.Error.Class)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	tempDir := t.TempDir()
+	if _, err := runCLI(tempDir, "keygen", "-o", "."); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, "other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(tempDir, "keygen", "-o", "other"); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("inspect"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := runCLI(tempDir, "send", "data.txt", "-k", "public.pem", "-H", "-q", "--encoding", "armor"); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+	if err := os.Remove(filepath.Join(tempDir, "data.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runCLI(tempDir, "inspect", "payload.abp", "-k", "private.pem")
+	if err != nil {
+		t.Fatalf("Inspect failed: %v\nOutput: %s", err, output)
+	}
+	for _, expected := range []string{"version 1", "armor", "Problems:    none", "this key can decrypt it"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, output)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "data.txt")); !os.IsNotExist(err) {
+		t.Error("Inspect must not write the plaintext")
+	}
+
+	output, err = runCLI(tempDir, "inspect", "payload.abp", "-k", "other/private.pem", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("Inspect failed: %v\nOutput: %s", err, output)
+	}
+	var result struct {
+		Result struct {
+			Valid bool
+			Keys  []struct {
+				CanDecrypt bool `json:"can_decrypt"`
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Inspect output is not JSON: %v\n%s", err, output)
+	}
+	if !result.Result.Valid || len(result.Result.Keys) != 1 || result.Result.Keys[0].CanDecrypt {
+		t.Errorf("Expected a valid payload the other key can't decrypt: %s", output)
+	}
+}
+
+func TestReceiveKeyDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a", "b", "stranger", "keys"} {
+		if err := os.Mkdir(filepath.Join(tempDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a", "b", "stranger"} {
+		if _, err := runCLI(tempDir, "keygen", "-o", name); err != nil {
+			t.Fatalf("Keygen failed: %v", err)
+		}
+	}
+	copyFile(t, filepath.Join(tempDir, "a", "private.pem"), filepath.Join(tempDir, "keys", "a.pem"))
+	copyFile(t, filepath.Join(tempDir, "b", "private.pem"), filepath.Join(tempDir, "keys", "b.pem"))
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("which key?"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"Named recipient", []string{"-k", "b/public.pem"}},
+		{"Anonymous", []string{"-k", "a/public.pem", "--anonymous"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"send", "data.txt", "-o", "payload.abp", "-H", "-q"}, tt.args...)
+			if output, err := runCLI(tempDir, args...); err != nil {
+				t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+			}
+			output, err := runCLI(tempDir, "receive", "-k", "keys", "-i", "payload.abp", "-o", "out", "-H", "-q")
+			if err != nil {
+				t.Fatalf("Receive failed: %v\nOutput: %s", err, output)
+			}
+			content, err := os.ReadFile(filepath.Join(tempDir, "out", "data.txt"))
+			if err != nil || string(content) != "which key?" {
+				t.Errorf("Unexpected received content %q (%v)", content, err)
+			}
+		})
+	}
+
+	if output, err := runCLI(tempDir, "send", "data.txt", "-k", "stranger/public.pem", "-o", "payload.abp", "-H", "-q"); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+	output, err := runCLI(tempDir, "receive", "-k", "keys", "-i", "payload.abp", "-H", "-q")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 6 {
+		t.Errorf("Expected exit code 6, got %v", err)
+	}
+	if !strings.Contains(output, "none of your keys match") {
+		t.Errorf("Expected a clear error, got: %s", output)
+	}
+}
+
+func TestKeyCommands(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("stored keys"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := runCLI(tempDir, "keygen", "--name", "work"); err != nil {
+		t.Fatalf("Keygen failed: %v\nOutput: %s", err, output)
+	}
+	_, err := runCLI(tempDir, "keygen", "--name", "work")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 7 {
+		t.Errorf("Expected keygen to refuse to overwrite with exit code 7, got %v", err)
+	}
+
+	// Stored keys are used by name
+	if output, err := runCLI(tempDir, "send", "data.txt", "-k", "work", "-o", "payload.abp", "-H", "-q"); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := runCLI(tempDir, "receive", "-k", "work", "-i", "payload.abp", "-o", "out", "-H", "-q"); err != nil {
+		t.Fatalf("Receive failed: %v\nOutput: %s", err, output)
+	}
+
+	output, err := runCLI(tempDir, "key", "export", "work", "--format", "base64", "-o", "work.pub")
+	if err != nil {
+		t.Fatalf("Export failed: %v\nOutput: %s", err, output)
+	}
+	// An existing file is only replaced with --force
+	if output, err := runCLI(tempDir, "key", "export", "work", "-o", "work.pub"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 7 {
+		t.Errorf("Expected export over an existing file to be refused with exit code 7, got %v\nOutput: %s", err, output)
+	}
+	if output, err := runCLI(tempDir, "key", "export", "work", "--format", "base64", "-o", "work.pub", "--force"); err != nil {
+		t.Fatalf("Export with --force failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := runCLI(tempDir, "key", "import", "alice", "work.pub"); err != nil {
+		t.Fatalf("Import failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := runCLI(tempDir, "key", "rename", "work", "laptop"); err != nil {
+		t.Fatalf("Rename failed: %v\nOutput: %s", err, output)
+	}
+
+	output, err = runCLI(tempDir, "key", "list", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("List failed: %v\nOutput: %s", err, output)
+	}
+	var list struct {
+		Result struct {
+			Keys []struct {
+				Name        string
+				Bits        int
+				Fingerprint string
+				Private     bool
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		t.Fatalf("List output is not JSON: %v\n%s", err, output)
+	}
+	keys := list.Result.Keys
+	if len(keys) != 2 || keys[0].Name != "alice" || keys[0].Private || keys[1].Name != "laptop" || !keys[1].Private {
+		t.Fatalf("Unexpected keys: %s", output)
+	}
+	if keys[0].Fingerprint != keys[1].Fingerprint || keys[1].Bits != 2048 {
+		t.Errorf("Expected the imported key to match the exported one: %s", output)
+	}
+
+	if output, err := runCLI(tempDir, "key", "delete", "alice"); err != nil {
+		t.Fatalf("Delete failed: %v\nOutput: %s", err, output)
+	}
+	if _, err := runCLI(tempDir, "key", "show", "alice"); err == nil {
+		t.Error("Expected show to fail for a deleted key")
+	}
+
+	// keygen into a directory doesn't overwrite either
+	if _, err := runCLI(tempDir, "keygen", "-o", "."); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	if _, err := runCLI(tempDir, "keygen", "-o", "."); err == nil {
+		t.Error("Expected keygen to refuse to overwrite private.pem")
+	}
+	if output, err := runCLI(tempDir, "keygen", "-o", ".", "--force"); err != nil {
+		t.Errorf("Keygen --force failed: %v\nOutput: %s", err, output)
+	}
+}
+
+func TestContactPinning(t *testing.T) {
+	tempDir := t.TempDir()
+	env := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "config")}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("pinned"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"old", "new"} {
+		if err := os.Mkdir(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if output, err := runCLIWithEnv(tempDir, env, "keygen", "-o", dir); err != nil {
+			t.Fatalf("Keygen failed: %v\nOutput: %s", err, output)
+		}
+	}
+	send := func() (string, error) {
+		return runCLIWithEnv(tempDir, env, "send", "data.txt", "-k", "alice", "-o", "payload.abp", "-H", "-q")
+	}
+
+	if output, err := runCLIWithEnv(tempDir, env, "key", "import", "alice", filepath.Join("old", "public.pem")); err != nil {
+		t.Fatalf("Import failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := send(); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+
+	// Replacing alice's key warns, and send refuses it until it is trusted again
+	output, err := runCLIWithEnv(tempDir, env, "key", "import", "alice", filepath.Join("new", "public.pem"), "--force")
+	if err != nil || !strings.Contains(output, "HAS CHANGED") {
+		t.Errorf("Expected a warning when the key changes, got %v\n%s", err, output)
+	}
+	output, err = send()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 || !strings.Contains(output, "Trusted before") {
+		t.Errorf("Expected send to refuse the changed key with exit code 4, got %v\n%s", err, output)
+	}
+
+	output, err = runCLIWithEnv(tempDir, env, "key", "show", "alice", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("Show failed: %v\nOutput: %s", err, output)
+	}
+	var shown struct {
+		Result struct{ Fingerprint string }
+	}
+	if err := json.Unmarshal([]byte(output), &shown); err != nil {
+		t.Fatalf("Show output is not JSON: %v\n%s", err, output)
+	}
+	if _, err := runCLIWithEnv(tempDir, env, "key", "trust", "alice"); err == nil {
+		t.Error("Expected trust to require --fingerprint without a terminal")
+	}
+	if _, err := runCLIWithEnv(tempDir, env, "key", "trust", "alice", "--fingerprint", "SHA256:wrong"); err == nil {
+		t.Error("Expected trust to refuse a fingerprint that doesn't match")
+	}
+	if output, err := runCLIWithEnv(tempDir, env, "key", "trust", "alice", "--fingerprint", shown.Result.Fingerprint); err != nil {
+		t.Fatalf("Trust failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := send(); err != nil {
+		t.Errorf("Send failed after trusting the new key: %v\nOutput: %s", err, output)
+	}
+
+	// The pin follows a renamed contact
+	if output, err := runCLIWithEnv(tempDir, env, "key", "rename", "alice", "alice2"); err != nil {
+		t.Fatalf("Rename failed: %v\nOutput: %s", err, output)
+	}
+	pins, err := os.ReadFile(filepath.Join(tempDir, "config", "airbridge", "pins.json"))
+	if err != nil || !strings.Contains(string(pins), `"alice2": "`+shown.Result.Fingerprint) || strings.Contains(string(pins), `"alice"`) {
+		t.Errorf("Expected the pin to move to the new name, got %v\n%s", err, pins)
+	}
+}
+
+func TestSignedPayloads(t *testing.T) {
+	tempDir := t.TempDir()
+	env := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "config")}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("signed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"me", "alice", "mallory"} {
+		if err := os.Mkdir(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if output, err := runCLIWithEnv(tempDir, env, "keygen", "-o", dir); err != nil {
+			t.Fatalf("Keygen failed: %v\nOutput: %s", err, output)
+		}
+	}
+	sendSigned := func(signer string) {
+		t.Helper()
+		output, err := runCLIWithEnv(tempDir, env, "send", "data.txt", "-k", filepath.Join("me", "public.pem"), "-o", "payload.abp", "-H", "-q", "--sign", filepath.Join(signer, "private.pem"))
+		if err != nil {
+			t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+		}
+	}
+	receive := func(args ...string) (string, error) {
+		return runCLIWithEnv(tempDir, env, append([]string{"receive", "-k", filepath.Join("me", "private.pem"), "-i", "payload.abp", "-H", "-q", "-o", tempDir, "--on-conflict", "rename"}, args...)...)
+	}
+	var exitErr *exec.ExitError
+
+	// A signed payload from someone who isn't a contact is received, with a warning
+	sendSigned("alice")
+	if output, err := receive(); err != nil || !strings.Contains(output, "unverified key") || !strings.Contains(output, "key import") {
+		t.Errorf("Expected an unknown sender to be received as unverified, got %v\n%s", err, output)
+	}
+
+	if output, err := runCLIWithEnv(tempDir, env, "key", "import", "alice", filepath.Join("alice", "public.pem")); err != nil {
+		t.Fatalf("Import failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := receive("--from", "alice"); err != nil || !strings.Contains(output, "Signed by alice") {
+		t.Errorf("Expected the payload signed by alice to be received, got %v\n%s", err, output)
+	}
+
+	// Someone else signing as alice is refused
+	sendSigned("mallory")
+	if output, err := receive("--from", "alice"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 || !strings.Contains(output, "pinned to") {
+		t.Errorf("Expected a payload signed by another key to be refused with exit code 4, got %v\n%s", err, output)
+	}
+}
+
+func TestKeyRevocation(t *testing.T) {
+	tempDir := t.TempDir()
+	alice := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "alice-config")}
+	bob := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "bob-config")}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("before and after"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := runCLIWithEnv(tempDir, alice, "keygen", "--name", "laptop", "--expires", "30"); err != nil || !strings.Contains(output, "Valid until") {
+		t.Fatalf("Keygen failed: %v\nOutput: %s", err, output)
+	}
+	// A bad flag is a usage error, even when the key already exists
+	var exitErr *exec.ExitError
+	if output, err := runCLIWithEnv(tempDir, alice, "keygen", "--name", "laptop", "--expires", "-1"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Errorf("Expected --expires -1 to be refused with exit code 2, got %v\nOutput: %s", err, output)
+	}
+	if output, err := runCLIWithEnv(tempDir, alice, "key", "export", "laptop", "-o", "laptop.pem"); err != nil {
+		t.Fatalf("Export failed: %v\nOutput: %s", err, output)
+	}
+
+	// The signed expiry travels with the exported key
+	output, err := runCLIWithEnv(tempDir, bob, "key", "import", "alice", "laptop.pem", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("Import failed: %v\nOutput: %s", err, output)
+	}
+	var imported struct {
+		Result struct {
+			NotAfter time.Time `json:"not_after"`
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &imported); err != nil || time.Until(imported.Result.NotAfter) < 29*24*time.Hour {
+		t.Errorf("Expected the key to expire in 30 days, got %v (%v)\n%s", imported.Result.NotAfter, err, output)
+	}
+	send := func() (string, error) {
+		return runCLIWithEnv(tempDir, bob, "send", "data.txt", "-k", "alice", "-o", "payload.abp", "-H", "-q")
+	}
+	if output, err := send(); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+
+	// Alice revokes the key; she can still decrypt with it, with a warning. An
+	// existing -o file is refused before anything is revoked
+	if err := os.WriteFile(filepath.Join(tempDir, "revoked.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := runCLIWithEnv(tempDir, alice, "key", "revoke", "laptop", "-o", "revoked.txt"); err == nil || !strings.Contains(output, "already exists") {
+		t.Errorf("Expected revoke over an existing file to be refused, got %v\nOutput: %s", err, output)
+	}
+	if output, err := runCLIWithEnv(tempDir, alice, "key", "revoke", "laptop", "--reason", "laptop stolen", "-o", "revoked.txt", "--force"); err != nil {
+		t.Fatalf("Revoke failed: %v\nOutput: %s", err, output)
+	}
+	output, err = runCLIWithEnv(tempDir, alice, "receive", "-k", "laptop", "-i", "payload.abp", "-o", filepath.Join(tempDir, "out"), "-H", "-q")
+	if err != nil || !strings.Contains(output, "Warning: the laptop key has been revoked") {
+		t.Errorf("Expected receive to warn about the revoked key, got %v\n%s", err, output)
+	}
+
+	// Once Bob imports the revocation, send refuses the key
+	output, err = runCLIWithEnv(tempDir, bob, "key", "import", "alice", "revoked.txt")
+	if err != nil || !strings.Contains(output, "Key alice revoked") || !strings.Contains(output, "laptop stolen") {
+		t.Errorf("Expected the revocation to be imported, got %v\n%s", err, output)
+	}
+	output, err = send()
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 || !strings.Contains(output, "revoked") {
+		t.Errorf("Expected send to refuse the revoked key with exit code 4, got %v\n%s", err, output)
+	}
+	if output, err := runCLIWithEnv(tempDir, bob, "key", "list"); err != nil || !strings.Contains(output, "revoked") {
+		t.Errorf("Expected the key list to show the revocation, got %v\n%s", err, output)
+	}
+
+	// A statement the key didn't sign is refused
+	if output, err := runCLIWithEnv(tempDir, bob, "keygen", "--name", "bob"); err != nil {
+		t.Fatalf("Keygen failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := runCLIWithEnv(tempDir, bob, "key", "import", "bob", "revoked.txt"); err == nil {
+		t.Errorf("Expected a statement about another key to be refused\n%s", output)
+	}
+}
+
+func TestIdentityDevices(t *testing.T) {
+	tempDir := t.TempDir()
+	alice := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "alice")}
+	bob := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "bob")}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("for every device"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(env []string, args ...string) string {
+		t.Helper()
+		output, err := runCLIWithEnv(tempDir, env, args...)
+		if err != nil {
+			t.Fatalf("%s failed: %v\nOutput: %s", strings.Join(args[:2], " "), err, output)
+		}
+		return output
+	}
+
+	// Alice certifies two device keys and hands out her bundle
+	run(alice, "identity", "create", "alice")
+	run(alice, "keygen", "--name", "laptop")
+	run(alice, "keygen", "--name", "bastion")
+	run(alice, "identity", "certify", "alice", "laptop", "laptop")
+	run(alice, "identity", "certify", "alice", "bastion", "bastion", "--days", "7")
+	run(alice, "identity", "export", "alice", "-o", "alice.bundle")
+	if output := run(alice, "key", "list"); strings.Contains(output, "alice") {
+		t.Errorf("Expected the identity key to be kept out of the key store, so receive -k never tries it: %s", output)
+	}
+
+	output := run(bob, "identity", "import", "alice", "alice.bundle", "--output-format", "json")
+	if !strings.Contains(output, `"device": "laptop"`) || !strings.Contains(output, `"own": false`) {
+		t.Errorf("Expected both devices in the imported identity: %s", output)
+	}
+	output = run(bob, "send", "data.txt", "-k", "alice", "-o", "payload.abp", "--output-format", "json")
+	if strings.Count(output, "SHA256:") != 3 || !strings.Contains(output, `"recipient": "alice"`) {
+		t.Errorf("Expected the payload to be encrypted for both devices: %s", output)
+	}
+	output = run(bob, "inspect", "payload.abp", "--output-format", "json")
+	if !strings.Contains(output, `"version": 2`) || !strings.Contains(output, `"wrapped_keys": 2`) {
+		t.Errorf("Expected a version 2 payload for two keys: %s", output)
+	}
+	for _, device := range []string{"laptop", "bastion"} {
+		run(alice, "receive", "-k", device, "-i", "payload.abp", "-o", filepath.Join("out", device), "-H", "-q")
+		if data, err := os.ReadFile(filepath.Join(tempDir, "out", device, "data.txt")); err != nil || string(data) != "for every device" {
+			t.Errorf("Expected %s to decrypt the payload, got %q (%v)", device, data, err)
+		}
+	}
+
+	// A device key swapped into the bundle is refused
+	bundle, err := os.ReadFile(filepath.Join(tempDir, "alice.bundle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(alice, "keygen", "--name", "mallory")
+	mallory := run(alice, "key", "export", "mallory", "--format", "base64")
+	var parsed struct {
+		Devices []struct{ Key string }
+	}
+	if err := json.Unmarshal(bundle, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(bundle), parsed.Devices[0].Key, strings.TrimSpace(mallory), 1)
+	if err := os.WriteFile(filepath.Join(tempDir, "tampered.bundle"), []byte(tampered), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = runCLIWithEnv(tempDir, bob, "identity", "import", "alice", "tampered.bundle")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("Expected a key error (exit code 4) for a tampered bundle, got %v", err)
+	}
+
+	// A different identity under the same name needs its fingerprint
+	impostor := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "impostor")}
+	output = run(impostor, "identity", "create", "alice", "--output-format", "json")
+	var created struct {
+		Result struct{ Fingerprint string }
+	}
+	if err := json.Unmarshal([]byte(output), &created); err != nil {
+		t.Fatalf("Create output is not JSON: %v\n%s", err, output)
+	}
+	run(impostor, "keygen", "--name", "phone")
+	run(impostor, "identity", "certify", "alice", "phone", "phone")
+	run(impostor, "identity", "export", "alice", "-o", "impostor.bundle")
+	output, err = runCLIWithEnv(tempDir, bob, "identity", "import", "alice", "impostor.bundle")
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 || !strings.Contains(output, "HAS CHANGED") {
+		t.Errorf("Expected a changed identity to be refused with a warning, got %v\n%s", err, output)
+	}
+	run(bob, "identity", "import", "alice", "impostor.bundle", "--fingerprint", created.Result.Fingerprint)
+}
+
+func TestKeySizes(t *testing.T) {
+	tempDir := t.TempDir()
+	env := []string{"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "config")}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("bigger keys"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runCLIWithEnv(tempDir, env, "keygen", "--name", "big", "--bits", "3072", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("Keygen failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, `"bits": 3072`) || !strings.Contains(output, `"type": "rsa"`) {
+		t.Errorf("Expected the key size in the keygen result: %s", output)
+	}
+	output, err = runCLIWithEnv(tempDir, env, "key", "show", "big")
+	if err != nil || !strings.Contains(output, "RSA-3072") {
+		t.Errorf("Expected key show to list RSA-3072, got %v\n%s", err, output)
+	}
+	if _, err := runCLIWithEnv(tempDir, env, "keygen", "--name", "odd", "--bits", "1000"); err == nil {
+		t.Error("Expected keygen to refuse an unsupported key size")
+	}
+
+	// The recipient key has to meet the minimum strength
+	_, err = runCLIWithEnv(tempDir, env, "send", "data.txt", "-k", "big", "-o", "payload.abp", "-H", "-q", "--min-bits", "4096")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("Expected a key error (exit code 4) for a weak recipient key, got %v", err)
+	}
+	if output, err := runCLIWithEnv(tempDir, env, "send", "data.txt", "-k", "big", "-o", "payload.abp", "-H", "-q", "--min-bits", "3072"); err != nil {
+		t.Errorf("Send failed: %v\nOutput: %s", err, output)
+	}
+}
+
+func TestSSHKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("ssh keys"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An Ed25519 key pair as written by ssh-keygen
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "me@laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPublic, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "id_ed25519"), pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic))) + " me@laptop\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "id_ed25519.pub"), []byte(authorized), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An RSA key in PKCS#8, as written by openssl genpkey
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "rsa.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "rsa.pub.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pubKey  string
+		privKey string
+	}{
+		{"OpenSSH Ed25519", "id_ed25519.pub", "id_ed25519"},
+		{"PKCS#8 RSA", "rsa.pub.pem", "rsa.pem"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if output, err := runCLI(tempDir, "send", "data.txt", "-k", tt.pubKey, "-o", "payload.abp", "-H", "-q"); err != nil {
+				t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+			}
+			output, err := runCLI(tempDir, "receive", "-k", tt.privKey, "-i", "payload.abp", "-o", "out", "-H", "-q")
+			if err != nil {
+				t.Fatalf("Receive failed: %v\nOutput: %s", err, output)
+			}
+			content, err := os.ReadFile(filepath.Join(tempDir, "out", "data.txt"))
+			if err != nil || string(content) != "ssh keys" {
+				t.Errorf("Unexpected received content %q (%v)", content, err)
+			}
+		})
+	}
+
+	// The wrong kind of key is a wrong recipient, not a crash
+	if output, err := runCLI(tempDir, "send", "data.txt", "-k", "id_ed25519.pub", "-o", "payload.abp", "-H", "-q", "--anonymous"); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+	_, err = runCLI(tempDir, "receive", "-k", "rsa.pem", "-i", "payload.abp", "-o", "out", "-H", "-q")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 6 {
+		t.Errorf("Expected a decryption error (exit code 6), got %v", err)
+	}
+
+	output, err := runCLI(tempDir, "inspect", "payload.abp", "--output-format", "json")
+	if err != nil || !strings.Contains(output, `"recipient_key_type": "ed25519"`) {
+		t.Errorf("Expected inspect to report an Ed25519 recipient, got %v\n%s", err, output)
+	}
+}
+
+func TestSendTo(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("discovered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(tempDir, "keygen", "-o", "."); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	pubPEM, err := os.ReadFile(filepath.Join(tempDir, "public.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	published := string(pubPEM)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/airbridge/alice" {
+			http.NotFound(w, r)
+			return
+		}
+		doc, _ := json.Marshal(map[string][]string{"keys": {published}})
+		_, _ = w.Write(doc)
+	}))
+	defer server.Close()
+
+	// The binary trusts the test server through SSL_CERT_FILE
+	certPath := filepath.Join(tempDir, "server.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{
+		"SSL_CERT_FILE=" + certPath,
+		"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "config"),
+		"XDG_CACHE_HOME=" + filepath.Join(tempDir, "cache"),
+	}
+	target := "alice@" + strings.TrimPrefix(server.URL, "https://")
+	send := func(extra ...string) (string, error) {
+		args := append([]string{"send", "data.txt", "--to", target, "-o", "payload.abp", "-H", "-q", "--refresh"}, extra...)
+		return runCLIWithEnv(tempDir, env, args...)
+	}
+	var exitErr *exec.ExitError
+
+	// Without a terminal to confirm on, a new key needs --fingerprint
+	if _, err := send(); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Fatalf("Expected an untrusted key error (exit code 4), got %v", err)
+	}
+	output, err := runCLIWithEnv(tempDir, env, "key", "import", "alice", "public.pem", "--output-format", "json")
+	if err != nil {
+		t.Fatalf("Import failed: %v\nOutput: %s", err, output)
+	}
+	var imported struct{ Result struct{ Fingerprint string } }
+	if err := json.Unmarshal([]byte(output), &imported); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := send("--fingerprint", imported.Result.Fingerprint, "--output-format", "json"); err != nil || !strings.Contains(output, `"recipient": "`+target+`"`) {
+		t.Fatalf("Send with --fingerprint failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := runCLI(tempDir, "receive", "-k", "private.pem", "-i", "payload.abp", "-o", "out", "-H", "-q"); err != nil {
+		t.Fatalf("Receive failed: %v\nOutput: %s", err, output)
+	}
+
+	// The key is pinned now, and a changed key is refused
+	if output, err := send(); err != nil {
+		t.Fatalf("Send to a pinned key failed: %v\nOutput: %s", err, output)
+	}
+	if _, err := runCLI(tempDir, "keygen", "-o", ".", "--force"); err != nil {
+		t.Fatal(err)
+	}
+	newPEM, err := os.ReadFile(filepath.Join(tempDir, "public.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	published = string(newPEM)
+	output, err = send()
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 || !strings.Contains(output, "pinned") {
+		t.Errorf("Expected a changed key to be refused, got %v\nOutput: %s", err, output)
+	}
+}
+
+func TestKeyPool(t *testing.T) {
+	tempDir := t.TempDir()
+	env := []string{
+		"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "config"),
+		"XDG_CACHE_HOME=" + filepath.Join(tempDir, "cache"),
+	}
+	poolKeys := func() int {
+		t.Helper()
+		output, err := runCLIWithEnv(tempDir, env, "key", "pool", "status", "--output-format", "json")
+		if err != nil {
+			t.Fatalf("Pool status failed: %v\nOutput: %s", err, output)
+		}
+		var status struct{ Result struct{ Keys int } }
+		if err := json.Unmarshal([]byte(output), &status); err != nil {
+			t.Fatalf("Invalid JSON: %v\nOutput: %s", err, output)
+		}
+		return status.Result.Keys
+	}
+
+	output, err := runCLIWithEnv(tempDir, env, "key", "pool", "refill", "--size", "1", "--output-format", "json")
+	if err != nil || !strings.Contains(output, `"added": 1`) {
+		t.Fatalf("Refill failed: %v\nOutput: %s", err, output)
+	}
+	output, err = runCLIWithEnv(tempDir, env, "keys", "refill", "--size", "2", "--output-format", "json")
+	if err != nil || !strings.Contains(output, `"added": 1`) {
+		t.Fatalf("keys refill failed: %v\nOutput: %s", err, output)
+	}
+
+	// A receive without -k takes its key from the pool; it fails on the empty stdin
+	output, _ = runCLIWithEnv(tempDir, env, "receive", "--plain")
+	if !strings.Contains(output, "Your public key") {
+		t.Fatalf("Expected a temporary public key, got: %s", output)
+	}
+	if keys := poolKeys(); keys != 1 {
+		t.Errorf("Expected the pool to hold 1 key, got %d", keys)
+	}
+
+	// With key_pool set, the pool is refilled in the background afterwards
+	_, _ = runCLIWithEnv(tempDir, append(env, "AIRBRIDGE_KEY_POOL=2"), "receive", "--plain")
+	deadline := time.Now().Add(30 * time.Second)
+	for poolKeys() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the pool to be refilled to 2 keys")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	output, err = runCLIWithEnv(tempDir, env, "key", "pool", "clear")
+	if err != nil || !strings.Contains(output, "Removed 2 keys.") {
+		t.Errorf("Clear failed: %v\nOutput: %s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "config", "airbridge", "keypool.key")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the pool key to be removed, got %v", err)
+	}
+}
+
+func TestReceiveSession(t *testing.T) {
+	tempDir := t.TempDir()
+	env := []string{
+		"XDG_CONFIG_HOME=" + filepath.Join(tempDir, "config"),
+		"XDG_CACHE_HOME=" + filepath.Join(tempDir, "cache"),
+	}
+
+	// Sessions are off until session_ttl is set
+	output, _ := runCLIWithEnv(tempDir, env, "receive", "--plain")
+	if !strings.Contains(output, "Your public key") || strings.Contains(output, "--resume") {
+		t.Fatalf("Expected no session by default, got: %s", output)
+	}
+	env = append(env, "AIRBRIDGE_SESSION_TTL=24h")
+
+	// The receive is closed before the payload arrives (stdin is empty)
+	output, _ = runCLIWithEnv(tempDir, env, "receive", "--plain")
+	lines := strings.Split(output, "\n")
+	var publicKey, id string
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Your public key") && i+1 < len(lines) {
+			publicKey = lines[i+1]
+		}
+		if _, after, ok := strings.Cut(line, "--resume "); ok {
+			id = strings.TrimRight(after, "',.")
+		}
+	}
+	if publicKey == "" || id == "" {
+		t.Fatalf("Expected a public key and a session ID, got: %s", output)
+	}
+
+	output, err := runCLIWithEnv(tempDir, env, "session", "list", "--output-format", "json")
+	if err != nil || !strings.Contains(output, `"id": "`+id+`"`) {
+		t.Fatalf("Expected the session to be listed: %v\nOutput: %s", err, output)
+	}
+
+	// A receive quit with Ctrl+C keeps its session too
+	if runtime.GOOS != "windows" {
+		cmd := exec.Command(binaryPath, "receive", "--plain")
+		cmd.Dir = tempDir
+		cmd.Env = append(os.Environ(), env...)
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stdin.Close()
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer cmd.Process.Kill()
+		// Wait for the payload prompt, by then the session is saved
+		for scanner := bufio.NewScanner(stdout); scanner.Scan(); {
+			if strings.HasPrefix(scanner.Text(), "Payload from the sender") {
+				break
+			}
+		}
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			t.Fatal(err)
+		}
+		_ = cmd.Wait()
+		output, err := runCLIWithEnv(tempDir, env, "session", "list", "--output-format", "json")
+		if err != nil || strings.Count(output, `"id"`) != 2 {
+			t.Errorf("Expected the interrupted receive's session to be kept: %v\nOutput: %s", err, output)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "key.txt"), []byte(publicKey), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("later"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := runCLIWithEnv(tempDir, env, "send", "data.txt", "-k", "key.txt", "-o", "payload.abp", "-H", "-q"); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+
+	// The session decrypts the payload once, and is gone after
+	resume := func() (string, error) {
+		return runCLIWithEnv(tempDir, env, "receive", "--resume", id, "-i", "payload.abp", "-o", "out", "-H", "-q")
+	}
+	if output, err := resume(); err != nil {
+		t.Fatalf("Resume failed: %v\nOutput: %s", err, output)
+	}
+	if content, err := os.ReadFile(filepath.Join(tempDir, "out", "data.txt")); err != nil || string(content) != "later" {
+		t.Errorf("Expected the file to be received, got %q (%v)", content, err)
+	}
+	var exitErr *exec.ExitError
+	if output, err := resume(); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 || !strings.Contains(output, "session list") {
+		t.Errorf("Expected a used session to be gone (exit code 4), got %v\nOutput: %s", err, output)
+	}
+}
+
+func TestReceiveWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the watcher is stopped with an interrupt, which Windows can't send")
+	}
+	tempDir := t.TempDir()
+	inbox := filepath.Join(tempDir, "inbox")
+	if err := os.Mkdir(inbox, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(tempDir, "keygen", "-o", "."); err != nil {
+		t.Fatalf("Keygen failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "data.txt"), []byte("dropped"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := runCLI(tempDir, "send", "data.txt", "-k", "public.pem", "-o", "payload.abp", "-H", "-q"); err != nil {
+		t.Fatalf("Send failed: %v\nOutput: %s", err, output)
+	}
+
+	cmd := exec.Command(binaryPath, "receive", "--watch", "inbox", "-k", "private.pem", "-o", "out", "--interval", "100ms", "--output-format", "json", "-q")
+	cmd.Dir = tempDir
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	copyFile(t, filepath.Join(tempDir, "payload.abp"), filepath.Join(inbox, "payload.abp"))
+	if err := os.WriteFile(filepath.Join(inbox, "garbage.abp"), []byte("not a payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := func() bool {
+		_, doneErr := os.Stat(filepath.Join(inbox, "done", "payload.abp"))
+		_, failedErr := os.Stat(filepath.Join(inbox, "failed", "garbage.abp"))
+		return doneErr == nil && failedErr == nil
+	}
+	for deadline := time.Now().Add(10 * time.Second); !handled() && time.Now().Before(deadline); {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !handled() {
+		t.Fatal("Expected the payloads to be moved into done/ and failed/")
+	}
+	if content, err := os.ReadFile(filepath.Join(tempDir, "out", "data.txt")); err != nil || string(content) != "dropped" {
+		t.Errorf("Expected the file to be received, got %q (%v)", content, err)
+	}
+
+	// The watcher keeps running until interrupted, then exits cleanly
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("Expected a clean exit, got %v", err)
+	}
+
+	statuses := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var event struct {
+			Payload string
+			Status  string
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Event is not a line of JSON: %v\n%s", err, line)
+		}
+		statuses[event.Payload] = event.Status
+	}
+	if statuses["payload.abp"] != "done" || statuses["garbage.abp"] != "failed" {
+		t.Errorf("Unexpected events: %s", stdout.String())
+	}
+}
+
 func copyFile(t *testing.T, src, dst string) {
 	data, err := os.ReadFile(src)
 	if err != nil {